@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -85,28 +86,34 @@ type AssessmentResult struct {
 	ModelID      string         `json:"model_id"`
 	Provider     string         `json:"provider"`
 	Timestamp    time.Time      `json:"timestamp"`
+	Capabilities []string       `json:"capabilities"`
 	Tests        []TestResult   `json:"tests"`
 	Summary      ResultSummary  `json:"summary"`
 }
 
 // TestResult holds results for a single test.
 type TestResult struct {
-	Name        string        `json:"name"`
-	Category    string        `json:"category"`
-	Prompt      string        `json:"prompt"`
-	Response    string        `json:"response"`
-	Latency     time.Duration `json:"latency_ms"`
-	TokensUsed  int           `json:"tokens_used"`
-	Score       float64       `json:"score"`
-	Notes       string        `json:"notes,omitempty"`
+	Name         string        `json:"name"`
+	Category     string        `json:"category"`
+	Prompt       string        `json:"prompt"`
+	Response     string        `json:"response"`
+	Latency      time.Duration `json:"latency_ms"`
+	FirstToken   time.Duration `json:"first_token_ms,omitempty"` // Only measured for models that stream
+	TokensUsed   int           `json:"tokens_used"`
+	InputTokens  int           `json:"input_tokens"`
+	OutputTokens int           `json:"output_tokens"`
+	Score        float64       `json:"score"`
+	Notes        string        `json:"notes,omitempty"`
 }
 
 // ResultSummary summarizes assessment results.
 type ResultSummary struct {
-	TotalTests    int           `json:"total_tests"`
-	AverageScore  float64       `json:"average_score"`
+	TotalTests     int           `json:"total_tests"`
+	AverageScore   float64       `json:"average_score"`
 	AverageLatency time.Duration `json:"average_latency_ms"`
-	TotalTokens   int           `json:"total_tokens"`
+	TotalTokens    int           `json:"total_tokens"`
+	InputTokens    int           `json:"input_tokens"`
+	OutputTokens   int           `json:"output_tokens"`
 }
 
 // StandardTests returns the standard assessment test suite.
@@ -183,7 +190,7 @@ func loadConfig() (*config.Config, error) {
 	return nil, fmt.Errorf("no config file found")
 }
 
-func setupProviders(cfg *config.Config) *provider.Registry {
+func setupProviders(cfg *config.Config) (*provider.Registry, error) {
 	registry := provider.NewRegistry()
 
 	registry.Register(provider.NewAnthropicProvider(""))
@@ -197,8 +204,15 @@ func setupProviders(cfg *config.Config) *provider.Registry {
 	registry.Register(provider.NewLMStudioProvider("", ""))
 
 	registry.RegisterModels(cfg.Models)
+	if err := registry.RegisterScriptedModels(cfg.Models); err != nil {
+		return nil, err
+	}
+	// A model left on the shared provider would use the wrong key or network
+	if err := registry.RegisterDedicatedProviders(cfg.Models); err != nil {
+		return nil, err
+	}
 
-	return registry
+	return registry, nil
 }
 
 func runAssessment(cmd *cobra.Command, args []string) error {
@@ -211,7 +225,10 @@ func runAssessment(cmd *cobra.Command, args []string) error {
 		models = cfg.GetCouncilMembers()
 	}
 
-	registry := setupProviders(cfg)
+	registry, err := setupProviders(cfg)
+	if err != nil {
+		return err
+	}
 	ctx := cmd.Context()
 
 	fmt.Println("═══════════════════════════════════════════════════════")
@@ -233,24 +250,31 @@ func runAssessment(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
+		caps, err := registry.Capabilities(modelID)
+		if err != nil {
+			fmt.Printf("  Model unavailable (%v), skipping\n", err)
+			continue
+		}
+		fmt.Printf("  Capabilities: %s\n", strings.Join(caps.Badges(), ", "))
+
 		result := AssessmentResult{
-			ModelID:   modelID,
-			Provider:  modelCfg.Provider,
-			Timestamp: time.Now(),
-			Tests:     []TestResult{},
+			ModelID:      modelID,
+			Provider:     modelCfg.Provider,
+			Capabilities: caps.Badges(),
+			Timestamp:    time.Now(),
+			Tests:        []TestResult{},
 		}
 
 		var totalScore float64
 		var totalLatency time.Duration
 		var totalTokens int
+		var totalInput, totalOutput int
 
 		for _, test := range tests {
 			fmt.Printf("  %s... ", test.Name)
 
 			start := time.Now()
-			resp, err := registry.Invoke(ctx, modelID, provider.Request{
-				Prompt: test.Prompt,
-			})
+			resp, firstToken, err := runTest(ctx, registry, modelID, test.Prompt, caps.Streaming)
 			latency := time.Since(start)
 
 			if err != nil {
@@ -269,13 +293,16 @@ func runAssessment(cmd *cobra.Command, args []string) error {
 			score := scoreResponse(test.Category, resp.Content)
 
 			testResult := TestResult{
-				Name:       test.Name,
-				Category:   test.Category,
-				Prompt:     test.Prompt,
-				Response:   resp.Content,
-				Latency:    latency,
-				TokensUsed: resp.TokensUsed,
-				Score:      score,
+				Name:         test.Name,
+				Category:     test.Category,
+				Prompt:       test.Prompt,
+				Response:     resp.Content,
+				Latency:      latency,
+				FirstToken:   firstToken,
+				TokensUsed:   resp.TokensUsed,
+				InputTokens:  resp.Usage.InputTokens,
+				OutputTokens: resp.Usage.OutputTokens,
+				Score:        score,
 			}
 
 			result.Tests = append(result.Tests, testResult)
@@ -283,8 +310,11 @@ func runAssessment(cmd *cobra.Command, args []string) error {
 			totalScore += score
 			totalLatency += latency
 			totalTokens += resp.TokensUsed
+			totalInput += resp.Usage.InputTokens
+			totalOutput += resp.Usage.OutputTokens
 
-			fmt.Printf("%.1f (%.1fs)\n", score, latency.Seconds())
+			fmt.Printf("%.1f (%.1fs, %d in / %d out tokens)\n", score, latency.Seconds(),
+				resp.Usage.InputTokens, resp.Usage.OutputTokens)
 		}
 
 		result.Summary = ResultSummary{
@@ -292,6 +322,8 @@ func runAssessment(cmd *cobra.Command, args []string) error {
 			AverageScore:   totalScore / float64(len(result.Tests)),
 			AverageLatency: totalLatency / time.Duration(len(result.Tests)),
 			TotalTokens:    totalTokens,
+			InputTokens:    totalInput,
+			OutputTokens:   totalOutput,
 		}
 
 		results = append(results, result)
@@ -388,6 +420,44 @@ func runCompare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runTest sends one test prompt. Models that can stream are streamed, so
+// the time to their first token is measured as well.
+func runTest(ctx context.Context, registry *provider.Registry, modelID, prompt string, stream bool) (*provider.Response, time.Duration, error) {
+	req := provider.Request{Prompt: prompt}
+	if !stream {
+		resp, err := registry.Invoke(ctx, modelID, req)
+		return resp, 0, err
+	}
+
+	start := time.Now()
+	ch, err := registry.Stream(ctx, modelID, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var content strings.Builder
+	var usage provider.Usage
+	var firstToken time.Duration
+	for chunk := range ch {
+		if chunk.Error != nil {
+			return nil, 0, chunk.Error
+		}
+		if chunk.Content != "" && firstToken == 0 {
+			firstToken = time.Since(start)
+		}
+		content.WriteString(chunk.Content)
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+	}
+
+	return &provider.Response{
+		Content:    content.String(),
+		TokensUsed: usage.Total(),
+		Usage:      usage,
+	}, firstToken, nil
+}
+
 // scoreResponse provides a simple scoring heuristic.
 func scoreResponse(category, response string) float64 {
 	// Base score
@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/jxmullins/thekanbansociety/internal/config"
 	"github.com/jxmullins/thekanbansociety/internal/debate"
 	"github.com/jxmullins/thekanbansociety/internal/provider"
+	"github.com/jxmullins/thekanbansociety/internal/redact"
 	"github.com/jxmullins/thekanbansociety/internal/tui"
 )
 
@@ -22,6 +24,15 @@ var (
 	verbose    bool
 	noStream   bool
 	useTUI     bool
+	showCosts  bool
+	noCache    bool
+
+	recordPath     string
+	replayPath     string
+	replayMatch    string
+	replayRealtime bool
+
+	ollamaURL string
 )
 
 func main() {
@@ -65,7 +76,33 @@ Example:
 var modelsCmd = &cobra.Command{
 	Use:   "models",
 	Short: "List available AI models",
-	RunE:  runListModels,
+	Long: `List configured AI models and the models pulled into Ollama.
+
+Local Ollama models are registered automatically as ollama/<name>, so they
+can be used as council or team members without editing the config.`,
+	RunE: runListModels,
+}
+
+var modelsPullCmd = &cobra.Command{
+	Use:   "pull [name]",
+	Short: "Download a model into Ollama",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPullModel,
+}
+
+var modelsShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show the details of a local Ollama model",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runShowModel,
+}
+
+var modelsDeleteCmd = &cobra.Command{
+	Use:     "rm [name]",
+	Aliases: []string{"delete"},
+	Short:   "Delete a local Ollama model",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runDeleteModel,
 }
 
 var versionCmd = &cobra.Command{
@@ -89,6 +126,23 @@ This wizard guides you through:
 	RunE: runSetup,
 }
 
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or clear the response cache",
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show response cache statistics",
+	RunE:  runCacheStats,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete all cached responses",
+	RunE:  runCacheClear,
+}
+
 var manageCmd = &cobra.Command{
 	Use:   "manage",
 	Short: "Interactive model management",
@@ -105,18 +159,33 @@ Features:
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: ./config/config.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "bypass the response cache")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "record all provider calls to a cassette file")
+	rootCmd.PersistentFlags().StringVar(&replayPath, "replay", "", "replay provider calls from a cassette file instead of calling models")
+	rootCmd.PersistentFlags().StringVar(&replayMatch, "replay-match", "strict", "cassette matching: strict, lenient")
+	rootCmd.PersistentFlags().BoolVar(&replayRealtime, "replay-realtime", false, "replay stream chunks with their recorded timing")
 
 	debateCmd.Flags().IntVarP(&rounds, "rounds", "r", 0, "number of debate rounds (default from config)")
 	debateCmd.Flags().StringVarP(&mode, "mode", "m", "collaborative", "debate mode: collaborative, adversarial, socratic")
 	debateCmd.Flags().StringSliceVar(&members, "members", nil, "council members (default from config)")
 	debateCmd.Flags().BoolVar(&noStream, "no-stream", false, "disable streaming output")
 	debateCmd.Flags().BoolVar(&useTUI, "tui", false, "use interactive TUI mode")
+	debateCmd.Flags().BoolVar(&showCosts, "show-costs", false, "display estimated token costs")
+
+	modelsCmd.PersistentFlags().StringVar(&ollamaURL, "ollama-url", "", "Ollama server (default: the configured ollama endpoint)")
+	modelsCmd.AddCommand(modelsPullCmd)
+	modelsCmd.AddCommand(modelsShowCmd)
+	modelsCmd.AddCommand(modelsDeleteCmd)
 
 	rootCmd.AddCommand(debateCmd)
 	rootCmd.AddCommand(modelsCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(setupCmd)
 	rootCmd.AddCommand(manageCmd)
+
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
 }
 
 func loadConfig() (*config.Config, error) {
@@ -140,8 +209,13 @@ func loadConfig() (*config.Config, error) {
 	return nil, fmt.Errorf("no config file found, tried: %v", locations)
 }
 
-func setupProviders(cfg *config.Config) *provider.Registry {
+func setupProviders(cfg *config.Config) (*provider.Registry, error) {
 	registry := provider.NewRegistry()
+	registry.SetRetryConfig(provider.RetryConfigFromExecution(cfg.Execution))
+	registry.SetRateLimits(cfg.RateLimits)
+	if cfg.Cache.Enabled && !noCache {
+		registry.SetCache(provider.NewResponseCacheFromConfig(cfg.Cache))
+	}
 
 	// Register all providers
 	registry.Register(provider.NewAnthropicProvider(""))
@@ -156,8 +230,46 @@ func setupProviders(cfg *config.Config) *provider.Registry {
 
 	// Register models from config
 	registry.RegisterModels(cfg.Models)
+	if err := registry.RegisterScriptedModels(cfg.Models); err != nil {
+		return nil, err
+	}
+	// A model left on the shared provider would use the wrong key or network
+	if err := registry.RegisterDedicatedProviders(cfg.Models); err != nil {
+		return nil, err
+	}
+	// Replays never reach Ollama, so don't wait on it. Ollama not running is
+	// the usual case, so failures are only reported with --verbose
+	if replayPath == "" {
+		added, err := registry.DiscoverOllamaModels(cfg.Models)
+		if verbose {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Ollama models not discovered: %v\n", err)
+			} else if len(added) > 0 {
+				fmt.Fprintf(os.Stderr, "Discovered Ollama models: %v\n", added)
+			}
+		}
+	}
+
+	return registry, nil
+}
 
-	return registry
+// attachCassette installs the --record or --replay cassette, if requested,
+// redacting recordings with cfg's patterns.
+func attachCassette(registry *provider.Registry, cfg *config.Config) error {
+	switch {
+	case recordPath != "" && replayPath != "":
+		return fmt.Errorf("--record and --replay cannot be used together")
+	case recordPath != "":
+		redactor, _ := redact.New(cfg.Redaction.Patterns)
+		registry.SetCassette(provider.NewRecordingCassette(recordPath, redactor))
+	case replayPath != "":
+		cassette, err := provider.LoadCassette(replayPath, provider.MatchMode(replayMatch), replayRealtime)
+		if err != nil {
+			return err
+		}
+		registry.SetCassette(cassette)
+	}
+	return nil
 }
 
 func runDebate(cmd *cobra.Command, args []string) error {
@@ -178,7 +290,16 @@ func runDebate(cmd *cobra.Command, args []string) error {
 		councilMembers = cfg.GetCouncilMembers()
 	}
 
-	registry := setupProviders(cfg)
+	registry, err := setupProviders(cfg)
+	if err != nil {
+		return err
+	}
+	if err := attachCassette(registry, cfg); err != nil {
+		return err
+	}
+	if err := registry.UseAuditLog(cfg.Audit, cfg.Redaction); err != nil {
+		return err
+	}
 
 	// Use TUI mode if requested
 	if useTUI {
@@ -202,6 +323,7 @@ func runDebate(cmd *cobra.Command, args []string) error {
 		Members:   councilMembers,
 		Stream:    !noStream,
 		Verbose:   verbose,
+		ShowCosts: showCosts,
 		OutputDir: cfg.Output.DebatesDir,
 	}
 
@@ -219,13 +341,110 @@ func runListModels(cmd *cobra.Command, args []string) error {
 	fmt.Println("Configured models:")
 	fmt.Println()
 
+	registry, err := setupProviders(cfg)
+	if err != nil {
+		return err
+	}
 	for id, model := range cfg.Models {
 		fmt.Printf("  %-15s %s (%s)\n", id, model.DisplayName, model.Provider)
+		if source := registry.CredentialSource(id); source != "" {
+			fmt.Printf("  %-15s key from %s\n", "", source)
+		}
 	}
 
 	fmt.Println()
 	fmt.Println("Default council:", cfg.GetCouncilMembers())
 
+	ollama := ollamaProvider(cfg)
+	models, err := ollama.ListModels(cmd.Context())
+	if err != nil {
+		fmt.Printf("\nOllama: not available at %s\n", ollama.GetBaseURL())
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Printf("Ollama models (%s):\n", ollama.GetBaseURL())
+	fmt.Println()
+	if len(models) == 0 {
+		fmt.Println("  none pulled; try: council models pull llama3.2")
+	}
+	for _, m := range models {
+		fmt.Printf("  %-30s %-8s %-8s %5.1f GB\n", m.Name, m.ParameterSize, m.QuantizationLevel, float64(m.Size)/1e9)
+	}
+
+	return nil
+}
+
+// ollamaProvider returns a client for the --ollama-url server, or the
+// configured one.
+func ollamaProvider(cfg *config.Config) *provider.OllamaProvider {
+	endpoint := ollamaURL
+	if endpoint == "" {
+		endpoint = provider.OllamaEndpoint(cfg.Models)
+	}
+	return provider.NewOllamaProvider("", endpoint)
+}
+
+func runPullModel(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	// Rewrite a single progress line as layers download
+	err = ollamaProvider(cfg).PullModel(cmd.Context(), args[0], func(p provider.OllamaPullProgress) {
+		line := p.Status
+		if p.Total > 0 {
+			line = fmt.Sprintf("%s %5.1f%% of %.1f GB", p.Status, float64(p.Completed)*100/float64(p.Total), float64(p.Total)/1e9)
+		}
+		fmt.Printf("\r\033[K%s", line)
+	})
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Pulled %s; use it as %s%s\n", args[0], provider.OllamaModelPrefix, args[0])
+	return nil
+}
+
+func runShowModel(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	info, err := ollamaProvider(cfg).ShowModel(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Model:         %s\n", info.Name)
+	fmt.Printf("Family:        %s\n", info.Family)
+	fmt.Printf("Parameters:    %s\n", info.ParameterSize)
+	fmt.Printf("Quantization:  %s\n", info.QuantizationLevel)
+	if info.ContextLength > 0 {
+		fmt.Printf("Context:       %d tokens\n", info.ContextLength)
+	}
+	if len(info.Capabilities) > 0 {
+		fmt.Printf("Capabilities:  %s\n", strings.Join(info.Capabilities, ", "))
+	}
+	if info.Parameters != "" {
+		fmt.Printf("\nModelfile parameters:\n%s\n", info.Parameters)
+	}
+	return nil
+}
+
+func runDeleteModel(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	if err := ollamaProvider(cfg).DeleteModel(cmd.Context(), args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %s\n", args[0])
 	return nil
 }
 
@@ -247,7 +466,16 @@ func runSetup(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	registry := setupProviders(cfg)
+	registry, err := setupProviders(cfg)
+	if err != nil {
+		return err
+	}
+	if err := attachCassette(registry, cfg); err != nil {
+		return err
+	}
+	if err := registry.UseAuditLog(cfg.Audit, cfg.Redaction); err != nil {
+		return err
+	}
 
 	// Run with TUI
 	tuiOpts := form.GetOptions(cfg.Output.DebatesDir)
@@ -261,6 +489,53 @@ func runManage(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("loading config: %w", err)
 	}
 
-	registry := setupProviders(cfg)
+	registry, err := setupProviders(cfg)
+	if err != nil {
+		return err
+	}
 	return tui.RunModelManager(cfg, registry)
 }
+
+func runCacheStats(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	stats, err := provider.NewResponseCacheFromConfig(cfg.Cache).Stats()
+	if err != nil {
+		return err
+	}
+
+	status := "enabled"
+	if !cfg.Cache.Enabled {
+		status = "disabled"
+	}
+
+	fmt.Printf("Response cache (%s): %s\n", status, stats.Dir)
+	fmt.Println()
+	fmt.Printf("  Entries:  %d (%d expired)\n", stats.Entries, stats.Expired)
+	fmt.Printf("  Size:     %.1f / %d MB\n", float64(stats.Bytes)/(1024*1024), cfg.Cache.MaxSizeMB)
+	fmt.Printf("  TTL:      %dh\n", cfg.Cache.TTLHours)
+	if stats.Entries > 0 {
+		fmt.Printf("  Oldest:   %s\n", stats.Oldest.Format("2006-01-02 15:04"))
+		fmt.Printf("  Newest:   %s\n", stats.Newest.Format("2006-01-02 15:04"))
+	}
+
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	removed, err := provider.NewResponseCacheFromConfig(cfg.Cache).Clear()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %d cached responses from %s\n", removed, cfg.Cache.Dir)
+	return nil
+}
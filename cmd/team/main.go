@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/jxmullins/thekanbansociety/internal/config"
 	"github.com/jxmullins/thekanbansociety/internal/provider"
+	"github.com/jxmullins/thekanbansociety/internal/redact"
 	"github.com/jxmullins/thekanbansociety/internal/team"
 	"github.com/jxmullins/thekanbansociety/internal/tui"
 )
@@ -27,6 +28,16 @@ var (
 	verbose         bool
 	useTUI          bool
 	useCLI          bool
+	cliProfile      string
+	noCache         bool
+	recordPath      string
+	replayPath      string
+	replayMatch     string
+	replayRealtime  bool
+	useTools        bool
+	allowCommands   bool
+	workspace       string
+	attachPaths     []string
 )
 
 func main() {
@@ -52,7 +63,8 @@ The team is led by a Project Manager (PM) who:
 Example:
   team "Build a REST API for user authentication"
   team "Design a database schema" --pm gpt --mode divide_conquer
-  team "Review this codebase for security issues" --mode consultation`,
+  team "Review this codebase for security issues" --mode consultation
+  team "Implement this screen" --attach mockup.png --attach spec.md`,
 	Args: cobra.ExactArgs(1),
 	RunE: runTeam,
 }
@@ -70,6 +82,16 @@ func init() {
 	rootCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "output directory for artifacts")
 	rootCmd.Flags().BoolVar(&useTUI, "tui", false, "use interactive TUI with Kanban board")
 	rootCmd.Flags().BoolVar(&useCLI, "cli", false, "use CLI tools (claude, gemini) instead of API keys")
+	rootCmd.Flags().StringVar(&cliProfile, "cli-profile", string(provider.DefaultPermissionProfile), "what CLI agents may do: read-only, workspace-write, full")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "bypass the response cache")
+	rootCmd.Flags().StringVar(&recordPath, "record", "", "record all provider calls to a cassette file")
+	rootCmd.Flags().StringVar(&replayPath, "replay", "", "replay provider calls from a cassette file instead of calling models")
+	rootCmd.Flags().StringVar(&replayMatch, "replay-match", "strict", "cassette matching: strict, lenient")
+	rootCmd.Flags().BoolVar(&replayRealtime, "replay-realtime", false, "replay stream chunks with their recorded timing")
+	rootCmd.Flags().BoolVar(&useTools, "tools", false, "let team members read files in the workspace")
+	rootCmd.Flags().BoolVar(&allowCommands, "allow-commands", false, "with --tools, also let team members run shell commands")
+	rootCmd.Flags().StringVar(&workspace, "workspace", ".", "workspace directory for --tools")
+	rootCmd.Flags().StringSliceVar(&attachPaths, "attach", nil, "attach a file (image, PDF or text) to the plan and task prompts; repeatable")
 }
 
 func loadConfig() (*config.Config, error) {
@@ -93,12 +115,49 @@ func loadConfig() (*config.Config, error) {
 	return nil, fmt.Errorf("no config file found, tried: %v", locations)
 }
 
-func setupProviders(cfg *config.Config, preferCLI bool) *provider.Registry {
+// detectCLIProviders returns the installed CLI tools declared in the config,
+// or the built-in ones if none are declared, restricted to the --cli-profile
+// permissions and pinned to a fresh scratch directory, which is also returned.
+// No directory is created when no CLI is found; the caller removes it.
+func detectCLIProviders(cfg *config.Config) ([]provider.Provider, string, error) {
+	profile, err := provider.ParsePermissionProfile(cliProfile)
+	if err != nil {
+		return nil, "", err
+	}
+
+	providers, err := provider.DetectConfiguredCLIProviders(cfg.CLIProviders)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if len(providers) == 0 {
+		return nil, "", nil
+	}
+
+	dir, err := os.MkdirTemp("", "kanban-society-")
+	if err != nil {
+		return nil, "", fmt.Errorf("creating CLI scratch directory: %w", err)
+	}
+	providers, err = provider.ApplyPermissionProfile(providers, profile, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, "", err
+	}
+	return providers, dir, nil
+}
+
+// setupProviders builds the registry, using cliProviders instead of the API
+// providers when any are given.
+func setupProviders(cfg *config.Config, cliProviders []provider.Provider) (*provider.Registry, error) {
 	registry := provider.NewRegistry()
+	registry.SetRetryConfig(provider.RetryConfigFromExecution(cfg.Execution))
+	registry.SetRateLimits(cfg.RateLimits)
+	if cfg.Cache.Enabled && !noCache {
+		registry.SetCache(provider.NewResponseCacheFromConfig(cfg.Cache))
+	}
 
-	if preferCLI {
+	if len(cliProviders) > 0 {
 		// Register CLI providers (use installed CLI tools instead of API keys)
-		for _, p := range provider.DetectCLIProviders() {
+		for _, p := range cliProviders {
 			registry.Register(p)
 		}
 	} else {
@@ -116,8 +175,46 @@ func setupProviders(cfg *config.Config, preferCLI bool) *provider.Registry {
 
 	// Register models from config
 	registry.RegisterModels(cfg.Models)
+	if err := registry.RegisterScriptedModels(cfg.Models); err != nil {
+		return nil, err
+	}
+	// A model left on the shared provider would use the wrong key or network
+	if err := registry.RegisterDedicatedProviders(cfg.Models); err != nil {
+		return nil, err
+	}
+	// Replays and CLI tools never reach Ollama, so don't wait on it. Ollama not
+	// running is the usual case, so failures are only reported with --verbose
+	if replayPath == "" && len(cliProviders) == 0 {
+		added, err := registry.DiscoverOllamaModels(cfg.Models)
+		if verbose {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Ollama models not discovered: %v\n", err)
+			} else if len(added) > 0 {
+				fmt.Fprintf(os.Stderr, "Discovered Ollama models: %v\n", added)
+			}
+		}
+	}
 
-	return registry
+	return registry, nil
+}
+
+// attachCassette installs the --record or --replay cassette, if requested,
+// redacting recordings with cfg's patterns.
+func attachCassette(registry *provider.Registry, cfg *config.Config) error {
+	switch {
+	case recordPath != "" && replayPath != "":
+		return fmt.Errorf("--record and --replay cannot be used together")
+	case recordPath != "":
+		redactor, _ := redact.New(cfg.Redaction.Patterns)
+		registry.SetCassette(provider.NewRecordingCassette(recordPath, redactor))
+	case replayPath != "":
+		cassette, err := provider.LoadCassette(replayPath, provider.MatchMode(replayMatch), replayRealtime)
+		if err != nil {
+			return err
+		}
+		registry.SetCassette(cassette)
+	}
+	return nil
 }
 
 func runTeam(cmd *cobra.Command, args []string) error {
@@ -128,6 +225,15 @@ func runTeam(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("loading config: %w", err)
 	}
 
+	var attachments []provider.Attachment
+	for _, path := range attachPaths {
+		a, err := provider.LoadAttachment(path)
+		if err != nil {
+			return err
+		}
+		attachments = append(attachments, a)
+	}
+
 	// Set default members if not specified
 	teamMembers := members
 	if len(teamMembers) == 0 {
@@ -139,10 +245,23 @@ func runTeam(cmd *cobra.Command, args []string) error {
 		teamMembers = append(teamMembers, "groq")
 	}
 
+	var cliProviders []provider.Provider
+	var cliDir string
+	if useCLI {
+		var err error
+		if cliProviders, cliDir, err = detectCLIProviders(cfg); err != nil {
+			return err
+		}
+		if cliDir != "" {
+			defer os.RemoveAll(cliDir)
+			fmt.Printf("CLI agents: %s profile, working in %s\n", cliProfile, cliDir)
+		}
+	}
+
 	// If using CLI mode, override members with CLI provider names
 	if useCLI && len(members) == 0 {
 		teamMembers = []string{}
-		for _, p := range provider.DetectCLIProviders() {
+		for _, p := range cliProviders {
 			teamMembers = append(teamMembers, p.Name())
 		}
 		if len(teamMembers) == 0 {
@@ -150,7 +269,16 @@ func runTeam(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	registry := setupProviders(cfg, useCLI)
+	registry, err := setupProviders(cfg, cliProviders)
+	if err != nil {
+		return err
+	}
+	if err := attachCassette(registry, cfg); err != nil {
+		return err
+	}
+	if err := registry.UseAuditLog(cfg.Audit, cfg.Redaction); err != nil {
+		return err
+	}
 
 	// TUI mode
 	if useTUI {
@@ -169,10 +297,18 @@ func runTeam(cmd *cobra.Command, args []string) error {
 		}
 
 		tuiOpts := tui.TeamTUIOptions{
-			Task:    task,
-			PM:      pm,
-			Mode:    mode,
-			Members: teamMembers,
+			Task:          task,
+			PM:            pm,
+			Mode:          mode,
+			Members:       teamMembers,
+			Tools:         useTools,
+			AllowCommands: allowCommands,
+			Workspace:     workspace,
+			Attachments:   attachments,
+		}
+		if useCLI {
+			tuiOpts.CLIProfile = cliProfile
+			tuiOpts.CLIDir = cliDir
 		}
 
 		return tui.RunTeamTUI(context.Background(), tuiOpts, cfg, registry)
@@ -219,6 +355,14 @@ func runTeam(cmd *cobra.Command, args []string) error {
 		ShowCosts:       showCosts,
 		OutputDir:       outputDir,
 		Verbose:         verbose,
+		Tools:           useTools,
+		AllowCommands:   allowCommands,
+		Workspace:       workspace,
+		Attachments:     attachments,
+	}
+	if useCLI {
+		opts.CLIProfile = cliProfile
+		opts.CLIDir = cliDir
 	}
 
 	runner := team.NewRunner(registry, cfg)
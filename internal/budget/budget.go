@@ -116,7 +116,11 @@ func (t *Tracker) SetShowCosts(show bool) {
 func (t *Tracker) GetPricing(provider, model string) (PricingTier, bool) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	return t.pricingFor(provider, model)
+}
 
+// pricingFor looks up pricing for a model. The caller must hold mu.
+func (t *Tracker) pricingFor(provider, model string) (PricingTier, bool) {
 	// Try exact match
 	key := fmt.Sprintf("%s/%s", provider, model)
 	if p, ok := t.pricing[key]; ok {
@@ -137,7 +141,7 @@ func (t *Tracker) RecordUsage(provider, model, aiID string, inputTokens, outputT
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	pricing, _ := t.GetPricing(provider, model)
+	pricing, _ := t.pricingFor(provider, model)
 
 	cost := (float64(inputTokens)/1000)*pricing.InputPer1K +
 		(float64(outputTokens)/1000)*pricing.OutputPer1K
@@ -5,19 +5,25 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds all application configuration.
 type Config struct {
-	Debate         DebateConfig            `yaml:"debate"`
-	Execution      ExecutionConfig         `yaml:"execution"`
-	Output         OutputConfig            `yaml:"output"`
-	Context        ContextConfig           `yaml:"context"`
-	Team           TeamConfig              `yaml:"team"`
-	Models         map[string]ModelConfig  `yaml:"models"`
-	DefaultCouncil []string                `yaml:"default_council"`
+	Debate         DebateConfig               `yaml:"debate"`
+	Execution      ExecutionConfig            `yaml:"execution"`
+	Output         OutputConfig               `yaml:"output"`
+	Context        ContextConfig              `yaml:"context"`
+	Team           TeamConfig                 `yaml:"team"`
+	Cache          CacheConfig                `yaml:"cache"`
+	Audit          AuditConfig                `yaml:"audit"`
+	Redaction      RedactionConfig            `yaml:"redaction"`
+	RateLimits     map[string]RateLimitConfig `yaml:"rate_limits"`   // Keyed by provider name
+	CLIProviders   []CLIProviderConfig        `yaml:"cli_providers"` // Detected in order by --cli
+	Models         map[string]ModelConfig     `yaml:"models"`
+	DefaultCouncil []string                   `yaml:"default_council"`
 }
 
 // DebateConfig holds debate-related settings.
@@ -34,6 +40,7 @@ type ExecutionConfig struct {
 	RetryOnFailure  bool `yaml:"retry_on_failure"`
 	MaxRetries      int  `yaml:"max_retries"`
 	RetryDelay      int  `yaml:"retry_delay"`
+	MaxRetryDelay   int  `yaml:"max_retry_delay"`
 }
 
 // OutputConfig holds output-related settings.
@@ -47,8 +54,9 @@ type OutputConfig struct {
 
 // ContextConfig holds context management settings.
 type ContextConfig struct {
-	MaxContextChars    int  `yaml:"max_context_chars"`
-	IncludeFullHistory bool `yaml:"include_full_history"`
+	MaxContextChars    int    `yaml:"max_context_chars"`    // Cap on the history included in a prompt
+	IncludeFullHistory bool   `yaml:"include_full_history"` // Ignore the cap and fill the model's context window
+	SummaryModel       string `yaml:"summary_model"`        // AI ID that summarizes older history; empty drops it instead
 }
 
 // TeamConfig holds team mode settings.
@@ -58,13 +66,104 @@ type TeamConfig struct {
 	DefaultShowCosts       bool   `yaml:"default_show_costs"`
 }
 
+// CacheConfig holds response cache settings.
+type CacheConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Dir       string `yaml:"dir"`
+	TTLHours  int    `yaml:"ttl_hours"`
+	MaxSizeMB int    `yaml:"max_size_mb"`
+}
+
+// AuditConfig holds settings for the log of every provider call.
+type AuditConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"` // JSONL file, appended to
+}
+
+// RedactionConfig holds the patterns scrubbed from the audit log, transcripts,
+// cassettes, team artifacts and session summaries, on top of the built-in
+// secret patterns.
+type RedactionConfig struct {
+	Patterns []string `yaml:"patterns"` // Regular expressions
+}
+
 // ModelConfig holds configuration for a single AI model.
 type ModelConfig struct {
-	Provider    string `yaml:"provider"`
-	Model       string `yaml:"model"`
-	DisplayName string `yaml:"display_name"`
-	Endpoint    string `yaml:"endpoint,omitempty"`
-	AuthEnvVar  string `yaml:"auth_env_var,omitempty"`
+	Provider       string          `yaml:"provider"`
+	Model          string          `yaml:"model"`
+	DisplayName    string          `yaml:"display_name"`
+	Endpoint       string          `yaml:"endpoint,omitempty"`
+	AuthEnvVar     string          `yaml:"auth_env_var,omitempty"`
+	Fallbacks      []string        `yaml:"fallbacks,omitempty"`       // AI IDs tried in order if this model fails
+	Script         string          `yaml:"script,omitempty"`          // Response script for the scripted provider
+	ThinkingBudget int             `yaml:"thinking_budget,omitempty"` // Default reasoning token budget (0 = provider default)
+	EmbeddingModel string          `yaml:"embedding_model,omitempty"` // Model for Registry.Embed (empty = provider default)
+	RateLimit      RateLimitConfig `yaml:"rate_limit,omitempty"`      // Limits for this model alone, on top of its provider's
+
+	// Credentials for this model alone, in place of its provider's default
+	// environment variable. The first set of auth_command, auth_file and
+	// auth_dotenv is used; auth_env_var names the variable to read, from the
+	// environment or the dotenv file.
+	AuthFile       string `yaml:"auth_file,omitempty"`        // File holding only the API key
+	AuthDotenv     string `yaml:"auth_dotenv,omitempty"`      // .env file holding the key
+	AuthCommand    string `yaml:"auth_command,omitempty"`     // Command printing the key, run with sh -c
+	AuthCommandTTL int    `yaml:"auth_command_ttl,omitempty"` // Seconds to reuse auth_command's output (default 300)
+
+	// HTTP holds transport settings for reaching this model, such as a
+	// corporate proxy or an internal gateway's CA
+	HTTP HTTPConfig `yaml:"http,omitempty"`
+
+	// Limits override the provider's known values, for new or local models
+	ContextWindow   int  `yaml:"context_window,omitempty"`    // Tokens of input and output per request
+	MaxOutputTokens int  `yaml:"max_output_tokens,omitempty"` // Tokens of output per request
+	Vision          bool `yaml:"vision,omitempty"`            // Accepts images, where the provider can't tell
+}
+
+// HTTPConfig holds HTTP transport settings for a model's API calls. Zero
+// values keep the defaults.
+type HTTPConfig struct {
+	Proxy            string            `yaml:"proxy,omitempty"`              // Proxy URL (default: HTTPS_PROXY and friends)
+	CABundle         string            `yaml:"ca_bundle,omitempty"`          // PEM file of CAs trusted on top of the system's
+	ClientCert       string            `yaml:"client_cert,omitempty"`        // PEM certificate for mutual TLS
+	ClientKey        string            `yaml:"client_key,omitempty"`         // PEM key for client_cert
+	Headers          map[string]string `yaml:"headers,omitempty"`            // Sent with every request; values may reference $VARS
+	ConnectTimeout   int               `yaml:"connect_timeout,omitempty"`    // Seconds to connect, including the TLS handshake
+	FirstByteTimeout int               `yaml:"first_byte_timeout,omitempty"` // Seconds from sending a request to its response headers
+	Timeout          int               `yaml:"timeout,omitempty"`            // Seconds for a whole call, streaming included (default 180)
+	IdleTimeout      int               `yaml:"idle_timeout,omitempty"`       // Seconds a stream may send nothing before it counts as stalled (default 120)
+}
+
+// IsZero reports whether no HTTP settings are configured.
+func (h HTTPConfig) IsZero() bool {
+	return h.Proxy == "" && h.CABundle == "" && h.ClientCert == "" && h.ClientKey == "" &&
+		len(h.Headers) == 0 && h.ConnectTimeout == 0 && h.FirstByteTimeout == 0 && h.Timeout == 0 &&
+		h.IdleTimeout == 0
+}
+
+// CLIProviderConfig declares a local CLI tool that can act as a provider.
+type CLIProviderConfig struct {
+	Name        string            `yaml:"name"`                   // Provider name, also usable as an AI ID
+	Command     string            `yaml:"command"`                // Executable, looked up on PATH
+	Args        []string          `yaml:"args,omitempty"`         // Arguments before the prompt
+	PromptFlag  string            `yaml:"prompt_flag,omitempty"`  // Flag preceding the prompt; empty for positional
+	SystemFlag  string            `yaml:"system_flag,omitempty"`  // Flag for the system prompt, if supported
+	PromptInput string            `yaml:"prompt_input,omitempty"` // "argv" (default) or "stdin"
+	Env         map[string]string `yaml:"env,omitempty"`          // Extra environment variables; values may reference $VARS
+	Dir         string            `yaml:"dir,omitempty"`          // Working directory (default: current)
+	Output      string            `yaml:"output,omitempty"`       // "plain" (default), "jsonl", or a named event format
+	OutputField string            `yaml:"output_field,omitempty"` // Dotted path to the text in each jsonl line (default: text)
+
+	// Permissions maps permission profiles (read-only, workspace-write, full)
+	// to the flags that enforce them for this CLI.
+	Permissions map[string][]string `yaml:"permissions,omitempty"`
+}
+
+// RateLimitConfig limits how fast calls are sent to a provider or model.
+// Zero values leave that dimension unlimited.
+type RateLimitConfig struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	TokensPerMinute   int `yaml:"tokens_per_minute"`
+	MaxConcurrent     int `yaml:"max_concurrent"`
 }
 
 // Persona holds persona configuration.
@@ -99,6 +198,12 @@ func Load(path string) (*Config, error) {
 	// Set defaults
 	cfg.setDefaults()
 
+	for _, p := range cfg.Redaction.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+	}
+
 	return &cfg, nil
 }
 
@@ -125,6 +230,9 @@ func (c *Config) setDefaults() {
 	if c.Execution.RetryDelay == 0 {
 		c.Execution.RetryDelay = 5
 	}
+	if c.Execution.MaxRetryDelay == 0 {
+		c.Execution.MaxRetryDelay = 60
+	}
 	if c.Output.Format == "" {
 		c.Output.Format = "markdown"
 	}
@@ -140,6 +248,18 @@ func (c *Config) setDefaults() {
 	if c.Team.DefaultCheckpointLevel == "" {
 		c.Team.DefaultCheckpointLevel = "all"
 	}
+	if c.Cache.Dir == "" {
+		c.Cache.Dir = "./.cache/responses"
+	}
+	if c.Cache.TTLHours == 0 {
+		c.Cache.TTLHours = 168
+	}
+	if c.Cache.MaxSizeMB == 0 {
+		c.Cache.MaxSizeMB = 100
+	}
+	if c.Audit.Path == "" {
+		c.Audit.Path = "./.audit/calls.jsonl"
+	}
 }
 
 // GetModel returns the model configuration for the given AI ID.
@@ -77,6 +77,17 @@ func TestLoadDefaults(t *testing.T) {
 	}
 }
 
+func TestLoadInvalidRedactionPattern(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("redaction:\n  patterns: ['(unclosed']\n"), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	if _, err := Load(configPath); err == nil {
+		t.Error("Load() accepted an invalid redaction pattern")
+	}
+}
+
 func TestLoadPersona(t *testing.T) {
 	tmpDir := t.TempDir()
 	personaPath := filepath.Join(tmpDir, "test.yaml")
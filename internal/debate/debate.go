@@ -9,8 +9,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jxmullins/thekanbansociety/internal/budget"
 	"github.com/jxmullins/thekanbansociety/internal/config"
+	"github.com/jxmullins/thekanbansociety/internal/history"
 	"github.com/jxmullins/thekanbansociety/internal/provider"
+	"github.com/jxmullins/thekanbansociety/internal/redact"
 )
 
 // Mode represents the debate mode.
@@ -30,6 +33,7 @@ type Options struct {
 	Members   []string
 	Stream    bool
 	Verbose   bool
+	ShowCosts bool
 	OutputDir string
 }
 
@@ -45,6 +49,7 @@ type Response struct {
 
 // Transcript holds the complete debate record.
 type Transcript struct {
+	ID        string
 	Topic     string
 	Mode      Mode
 	StartTime time.Time
@@ -57,17 +62,28 @@ type Transcript struct {
 
 // Runner orchestrates debate sessions.
 type Runner struct {
-	registry *provider.Registry
-	config   *config.Config
-	personas map[string]*config.Persona
+	registry     *provider.Registry
+	config       *config.Config
+	personas     map[string]*config.Persona
+	capabilities map[string]provider.Capabilities // Per member, filled in by Run
+	history      *history.Manager                 // Fits the transcript into each prompt
+	redactor     *redact.Redactor                 // Scrubs secrets from saved transcripts
+	usage        *budget.Tracker
 }
 
 // NewRunner creates a new debate runner.
 func NewRunner(registry *provider.Registry, cfg *config.Config) *Runner {
+	// The config's patterns were checked when it loaded; any that fail here
+	// are skipped and the built-in patterns still apply
+	redactor, _ := redact.New(cfg.Redaction.Patterns)
 	return &Runner{
-		registry: registry,
-		config:   cfg,
-		personas: make(map[string]*config.Persona),
+		registry:     registry,
+		config:       cfg,
+		personas:     make(map[string]*config.Persona),
+		capabilities: make(map[string]provider.Capabilities),
+		history:      history.NewManager(registry, cfg.Context),
+		redactor:     redactor,
+		usage:        budget.NewTracker(),
 	}
 }
 
@@ -85,18 +101,25 @@ func (r *Runner) Run(ctx context.Context, opts Options) error {
 	fmt.Println()
 
 	transcript := &Transcript{
+		ID:        fmt.Sprintf("debate_%d", time.Now().Unix()),
 		Topic:     opts.Topic,
 		Mode:      opts.Mode,
 		StartTime: time.Now(),
 		Members:   opts.Members,
 		Rounds:    make([][]Response, 0, opts.Rounds),
 	}
+	ctx = provider.WithMiddleware(provider.WithSessionID(ctx, transcript.ID), provider.RecordUsage(r.usage))
 
-	// Validate members exist in registry
+	// Validate members exist in registry and note what each supports
 	for _, member := range opts.Members {
 		if _, _, err := r.registry.GetForModel(member); err != nil {
 			return fmt.Errorf("member %q: %w", member, err)
 		}
+		caps, err := r.registry.Capabilities(member)
+		if err != nil {
+			return fmt.Errorf("member %q: %w", member, err)
+		}
+		r.capabilities[member] = caps
 	}
 
 	// Round 1: Opening Statements
@@ -142,6 +165,9 @@ func (r *Runner) Run(ctx context.Context, opts Options) error {
 	fmt.Println("           DEBATE COMPLETE")
 	fmt.Println("═══════════════════════════════════════════════════════")
 	fmt.Printf("Duration: %s\n", transcript.EndTime.Sub(transcript.StartTime).Round(time.Second))
+	if opts.ShowCosts {
+		fmt.Println(r.usage.Summary())
+	}
 
 	return nil
 }
@@ -156,16 +182,18 @@ func (r *Runner) runOpeningRound(ctx context.Context, opts Options) ([]Response,
 	responses := make([]Response, 0, len(opts.Members))
 
 	for _, member := range opts.Members {
-		resp, err := r.invokeAI(ctx, member, prompt, opts)
+		resp, err := r.invokeAI(ctx, member, provider.Request{Prompt: prompt}, opts)
 		if err != nil {
 			fmt.Printf("[%s failed: %v]\n\n", member, err)
-			continue
+			if resp == nil {
+				continue
+			}
 		}
 
 		responses = append(responses, Response{
 			AIID:      member,
 			AIName:    r.getDisplayName(member),
-			Content:   resp.Content,
+			Content:   answerText(resp),
 			Round:     1,
 			Phase:     "opening",
 			Timestamp: time.Now(),
@@ -184,17 +212,19 @@ func (r *Runner) runRebuttalRound(ctx context.Context, opts Options, round int,
 	responses := make([]Response, 0, len(opts.Members))
 
 	for _, member := range opts.Members {
-		prompt := r.buildRebuttalPrompt(opts, member, round, transcript)
-		resp, err := r.invokeAI(ctx, member, prompt, opts)
+		req := r.buildRebuttalRequest(ctx, opts, member, round, transcript)
+		resp, err := r.invokeAI(ctx, member, r.history.FitMessages(ctx, member, req), opts)
 		if err != nil {
 			fmt.Printf("[%s failed: %v]\n\n", member, err)
-			continue
+			if resp == nil {
+				continue
+			}
 		}
 
 		responses = append(responses, Response{
 			AIID:      member,
 			AIName:    r.getDisplayName(member),
-			Content:   resp.Content,
+			Content:   answerText(resp),
 			Round:     round,
 			Phase:     "rebuttal",
 			Timestamp: time.Now(),
@@ -213,17 +243,19 @@ func (r *Runner) runSynthesisRound(ctx context.Context, opts Options, transcript
 	responses := make([]Response, 0, len(opts.Members))
 
 	for _, member := range opts.Members {
-		prompt := r.buildSynthesisPrompt(opts, member, transcript)
-		resp, err := r.invokeAI(ctx, member, prompt, opts)
+		prompt := r.buildSynthesisPrompt(ctx, opts, member, transcript)
+		resp, err := r.invokeAI(ctx, member, provider.Request{Prompt: prompt}, opts)
 		if err != nil {
 			fmt.Printf("[%s failed: %v]\n\n", member, err)
-			continue
+			if resp == nil {
+				continue
+			}
 		}
 
 		responses = append(responses, Response{
 			AIID:      member,
 			AIName:    r.getDisplayName(member),
-			Content:   resp.Content,
+			Content:   answerText(resp),
 			Round:     0,
 			Phase:     "synthesis",
 			Timestamp: time.Now(),
@@ -241,17 +273,29 @@ func (r *Runner) runFinalVerdict(ctx context.Context, opts Options, transcript *
 
 	// Use the first member (typically Claude) for the final synthesis
 	synthesizer := opts.Members[0]
-	prompt := r.buildFinalVerdictPrompt(opts, transcript)
+	prompt := r.buildFinalVerdictPrompt(ctx, opts, synthesizer, transcript)
 
-	resp, err := r.invokeAI(ctx, synthesizer, prompt, opts)
+	resp, err := r.invokeAI(ctx, synthesizer, provider.Request{Prompt: prompt}, opts)
 	if err != nil {
-		return "", err
+		if resp == nil {
+			return "", err
+		}
+		fmt.Printf("[%s's verdict was cut off: %v]\n\n", synthesizer, err)
 	}
 
-	return resp.Content, nil
+	return answerText(resp), nil
 }
 
-func (r *Runner) invokeAI(ctx context.Context, aiID string, prompt string, opts Options) (*provider.Response, error) {
+// answerText returns an answer's text for the transcript, marking one that
+// was cut off partway.
+func answerText(resp *provider.Response) string {
+	if resp.Partial {
+		return resp.Content + "\n\n[cut off]"
+	}
+	return resp.Content
+}
+
+func (r *Runner) invokeAI(ctx context.Context, aiID string, req provider.Request, opts Options) (*provider.Response, error) {
 	modelCfg, ok := r.config.GetModel(aiID)
 	if !ok {
 		return nil, fmt.Errorf("model %q not found in config", aiID)
@@ -265,16 +309,19 @@ func (r *Runner) invokeAI(ctx context.Context, aiID string, prompt string, opts
 	fmt.Printf("▶ %s\n", displayName)
 	fmt.Println()
 
-	systemPrompt := r.getSystemPrompt(aiID, opts)
+	caps := r.capabilities[aiID]
+	if caps.SystemPrompt {
+		req.SystemPrompt = r.getSystemPrompt(aiID, opts)
+	} else {
+		// Keep the debate framing for members that would drop a system prompt
+		req.Prompt = r.getSystemPrompt(aiID, opts) + "\n\n" + req.Prompt
+	}
 
-	if opts.Stream {
-		return r.invokeStreaming(ctx, aiID, prompt, systemPrompt)
+	if opts.Stream && caps.Streaming {
+		return r.invokeStreaming(ctx, aiID, req)
 	}
 
-	resp, err := r.registry.Invoke(ctx, aiID, provider.Request{
-		Prompt:       prompt,
-		SystemPrompt: systemPrompt,
-	})
+	resp, err := r.registry.Invoke(ctx, aiID, req)
 	if err != nil {
 		return nil, err
 	}
@@ -285,29 +332,15 @@ func (r *Runner) invokeAI(ctx context.Context, aiID string, prompt string, opts
 	return resp, nil
 }
 
-func (r *Runner) invokeStreaming(ctx context.Context, aiID, prompt, systemPrompt string) (*provider.Response, error) {
-	stream, err := r.registry.Stream(ctx, aiID, provider.Request{
-		Prompt:       prompt,
-		SystemPrompt: systemPrompt,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	var content strings.Builder
-	for chunk := range stream {
-		if chunk.Error != nil {
-			return nil, chunk.Error
-		}
-		if chunk.Content != "" {
-			fmt.Print(chunk.Content)
-			content.WriteString(chunk.Content)
-		}
-	}
+// invokeStreaming prints a streamed answer as it arrives. An answer that is
+// cut off is returned, with Partial set, alongside the error.
+func (r *Runner) invokeStreaming(ctx context.Context, aiID string, req provider.Request) (*provider.Response, error) {
+	resp, err := r.registry.StreamResumable(ctx, aiID, req, func(chunk provider.StreamChunk) {
+		fmt.Print(chunk.Content)
+	}, nil)
 	fmt.Println()
 	fmt.Println()
-
-	return &provider.Response{Content: content.String()}, nil
+	return resp, err
 }
 
 func (r *Runner) getDisplayName(aiID string) string {
@@ -349,40 +382,90 @@ Please present your opening position on this topic. Provide:
 Remember to be clear, logical, and concise.`, opts.Topic)
 }
 
-func (r *Runner) buildRebuttalPrompt(opts Options, aiID string, round int, transcript *Transcript) string {
-	var context strings.Builder
-	context.WriteString(fmt.Sprintf("Topic: %s\n\n", opts.Topic))
-	context.WriteString("Previous discussion:\n\n")
+// buildRebuttalRequest replays the debate from aiID's point of view: each of
+// its earlier statements is an assistant turn, and each user turn carries what
+// the other participants said in the preceding round.
+func (r *Runner) buildRebuttalRequest(ctx context.Context, opts Options, aiID string, round int, transcript *Transcript) provider.Request {
+	var messages []provider.Message
+	pending := r.buildOpeningPrompt(opts)
 
-	// Include previous rounds
 	for _, roundResponses := range transcript.Rounds {
-		for _, resp := range roundResponses {
-			context.WriteString(fmt.Sprintf("**%s** (Round %d):\n%s\n\n", resp.AIName, resp.Round, resp.Content))
+		own := findResponse(roundResponses, aiID)
+		if own == nil {
+			// No reply this round; fold the next prompt into the pending turn
+			pending += "\n\n" + r.buildRebuttalPrompt(ctx, opts, aiID, roundResponses)
+			continue
 		}
+
+		messages = append(messages,
+			provider.Message{Role: provider.RoleUser, Content: pending},
+			provider.Message{Role: provider.RoleAssistant, Content: own.Content},
+		)
+		pending = r.buildRebuttalPrompt(ctx, opts, aiID, roundResponses)
 	}
 
-	return fmt.Sprintf(`%s
-This is Round %d: Rebuttals.
+	return provider.Request{
+		Messages: messages,
+		Prompt:   fmt.Sprintf("This is Round %d: Rebuttals.\n\n%s", round, pending),
+	}
+}
 
+// buildRebuttalPrompt presents the other participants' latest responses,
+// fitted to aiID's context window.
+func (r *Runner) buildRebuttalPrompt(ctx context.Context, opts Options, aiID string, previous []Response) string {
+	var others []Response
+	for _, resp := range previous {
+		if resp.AIID != aiID {
+			others = append(others, resp)
+		}
+	}
+
+	var context strings.Builder
+	context.WriteString(fmt.Sprintf("Topic: %s\n\n", opts.Topic))
+	context.WriteString("The other participants said:\n\n")
+	context.WriteString(r.history.Fit(ctx, aiID, responseTurns(others), opts.Topic))
+	context.WriteString("\n\n")
+
+	return fmt.Sprintf(`%s
 Based on the discussion so far:
 1. Respond to the strongest arguments made by other participants
 2. Strengthen or refine your position
 3. Identify any common ground
 4. Address any weaknesses in opposing arguments
 
-Be respectful but rigorous in your analysis.`, context.String(), round)
+Be respectful but rigorous in your analysis.`, context.String())
 }
 
-func (r *Runner) buildSynthesisPrompt(opts Options, aiID string, transcript *Transcript) string {
-	var context strings.Builder
-	context.WriteString(fmt.Sprintf("Topic: %s\n\n", opts.Topic))
-	context.WriteString("Complete debate history:\n\n")
+// responseTurns converts debate responses to history turns.
+func responseTurns(responses []Response) []history.Turn {
+	turns := make([]history.Turn, len(responses))
+	for i, resp := range responses {
+		turns[i] = history.Turn{Speaker: resp.AIName, Heading: fmt.Sprintf("Round %d", resp.Round), Content: resp.Content}
+	}
+	return turns
+}
 
-	for _, roundResponses := range transcript.Rounds {
-		for _, resp := range roundResponses {
-			context.WriteString(fmt.Sprintf("**%s** (Round %d):\n%s\n\n", resp.AIName, resp.Round, resp.Content))
+// findResponse returns aiID's response from a round, if it gave one.
+func findResponse(responses []Response, aiID string) *Response {
+	for i := range responses {
+		if responses[i].AIID == aiID {
+			return &responses[i]
 		}
 	}
+	return nil
+}
+
+func (r *Runner) buildSynthesisPrompt(ctx context.Context, opts Options, aiID string, transcript *Transcript) string {
+	var all []Response
+	for _, roundResponses := range transcript.Rounds {
+		all = append(all, roundResponses...)
+	}
+
+	var context strings.Builder
+	context.WriteString(fmt.Sprintf("Topic: %s\n\n", opts.Topic))
+	context.WriteString("Complete debate history:\n\n")
+	context.WriteString(r.history.Fit(ctx, aiID, responseTurns(all), opts.Topic))
+	context.WriteString("\n\n")
 
 	return fmt.Sprintf(`%s
 This is the Final Round: Synthesis.
@@ -394,14 +477,17 @@ Please provide your synthesis of this debate:
 4. Offer your final assessment and recommendations`, context.String())
 }
 
-func (r *Runner) buildFinalVerdictPrompt(opts Options, transcript *Transcript) string {
+func (r *Runner) buildFinalVerdictPrompt(ctx context.Context, opts Options, aiID string, transcript *Transcript) string {
+	syntheses := make([]history.Turn, len(transcript.Synthesis))
+	for i, resp := range transcript.Synthesis {
+		syntheses[i] = history.Turn{Speaker: resp.AIName, Heading: "Synthesis", Content: resp.Content}
+	}
+
 	var context strings.Builder
 	context.WriteString(fmt.Sprintf("Topic: %s\n\n", opts.Topic))
 	context.WriteString("Individual syntheses from each AI:\n\n")
-
-	for _, resp := range transcript.Synthesis {
-		context.WriteString(fmt.Sprintf("**%s's Synthesis:**\n%s\n\n", resp.AIName, resp.Content))
-	}
+	context.WriteString(r.history.Fit(ctx, aiID, syntheses, opts.Topic))
+	context.WriteString("\n\n")
 
 	return fmt.Sprintf(`%s
 As the synthesizer for The Council of Legends, provide a combined final verdict:
@@ -459,6 +545,7 @@ func (r *Runner) saveTranscript(opts Options, transcript *Transcript) error {
 	var content strings.Builder
 	content.WriteString(fmt.Sprintf("# Council Debate: %s\n\n", opts.Topic))
 	content.WriteString(fmt.Sprintf("**Date:** %s\n", transcript.StartTime.Format("January 2, 2006 3:04 PM")))
+	content.WriteString(fmt.Sprintf("**Session:** %s\n", transcript.ID))
 	content.WriteString(fmt.Sprintf("**Mode:** %s\n", transcript.Mode))
 	content.WriteString(fmt.Sprintf("**Members:** %s\n\n", strings.Join(transcript.Members, ", ")))
 
@@ -483,5 +570,5 @@ func (r *Runner) saveTranscript(opts Options, transcript *Transcript) error {
 	content.WriteString(transcript.Final)
 	content.WriteString("\n")
 
-	return os.WriteFile(filename, []byte(content.String()), 0640)
+	return os.WriteFile(filename, []byte(r.redactor.Redact(content.String())), 0640)
 }
@@ -0,0 +1,114 @@
+package debate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+	"github.com/jxmullins/thekanbansociety/internal/provider"
+)
+
+// speaker answers each call with a numbered point of its own.
+type speaker struct {
+	name  string
+	calls int
+}
+
+func (s *speaker) Name() string { return s.name }
+
+func (s *speaker) Invoke(ctx context.Context, req provider.Request) (*provider.Response, error) {
+	s.calls++
+	return &provider.Response{Content: fmt.Sprintf("%s point %d", s.name, s.calls)}, nil
+}
+
+func (s *speaker) Stream(ctx context.Context, req provider.Request) (<-chan provider.StreamChunk, error) {
+	s.calls++
+	ch := make(chan provider.StreamChunk, 3)
+	ch <- provider.StreamChunk{Content: s.name + " point "}
+	ch <- provider.StreamChunk{Content: fmt.Sprint(s.calls)}
+	ch <- provider.StreamChunk{Done: true, Usage: &provider.Usage{InputTokens: 10, OutputTokens: 3}}
+	close(ch)
+	return ch, nil
+}
+
+func (s *speaker) HealthCheck(ctx context.Context) error { return nil }
+
+// datePattern matches the transcript lines that change between runs.
+var datePattern = regexp.MustCompile(`(?m)^\*\*(Date|Session):\*\* .*$`)
+
+// runDebate runs a short debate between alpha and beta and returns its
+// transcript.
+func runDebate(t *testing.T, registry *provider.Registry, cfg *config.Config) string {
+	t.Helper()
+	dir := t.TempDir()
+	err := NewRunner(registry, cfg).Run(context.Background(), Options{
+		Topic:     "Tabs or spaces",
+		Mode:      ModeAdversarial,
+		Rounds:    2,
+		Members:   []string{"alpha", "beta"},
+		Stream:    true,
+		OutputDir: dir,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.md"))
+	if len(files) != 1 {
+		t.Fatalf("saved transcripts = %v, want one", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return datePattern.ReplaceAllString(string(data), "")
+}
+
+func TestDebateReplay(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "debate.jsonl")
+	cfg := &config.Config{Models: map[string]config.ModelConfig{
+		"alpha": {Provider: "alpha", DisplayName: "Alpha"},
+		"beta":  {Provider: "beta", DisplayName: "Beta"},
+	}}
+
+	live := provider.NewRegistry()
+	live.Register(&speaker{name: "alpha"})
+	live.Register(&speaker{name: "beta"})
+	live.RegisterModels(cfg.Models)
+	live.SetCassette(provider.NewRecordingCassette(cassette, nil))
+	recorded := runDebate(t, live, cfg)
+	if !strings.Contains(recorded, "## The Council's Final Verdict\n\nalpha point 4") {
+		t.Fatalf("recorded transcript lacks alpha's verdict:\n%s", recorded)
+	}
+
+	// The replay must not reach the members, so they are offline
+	offline := &speaker{name: "offline"}
+	replayCfg := &config.Config{Models: map[string]config.ModelConfig{
+		"alpha": {Provider: "offline", DisplayName: "Alpha"},
+		"beta":  {Provider: "offline", DisplayName: "Beta"},
+	}}
+	replay := provider.NewRegistry()
+	replay.Register(offline)
+	replay.RegisterModels(replayCfg.Models)
+	c, err := provider.LoadCassette(cassette, provider.MatchStrict, false)
+	if err != nil {
+		t.Fatalf("LoadCassette() error = %v", err)
+	}
+	replay.SetCassette(c)
+	replayed := runDebate(t, replay, replayCfg)
+
+	if replayed != recorded {
+		t.Errorf("replayed transcript differs:\n%s\nrecorded:\n%s", replayed, recorded)
+	}
+	if offline.calls != 0 || c.Unused() != 0 {
+		t.Errorf("replay made %d live calls and left %d recordings unused", offline.calls, c.Unused())
+	}
+	if _, err := replay.Invoke(context.Background(), "alpha", provider.Request{Prompt: "more"}); !errors.Is(err, provider.ErrCassetteMiss) {
+		t.Errorf("Invoke() past the recording error = %v, want ErrCassetteMiss", err)
+	}
+}
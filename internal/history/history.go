@@ -0,0 +1,313 @@
+// Package history fits discussion history into each model's context window.
+// Recent turns are kept verbatim; older ones are folded into a rolling
+// summary written by a cheap model, or dropped when none is available.
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+	"github.com/jxmullins/thekanbansociety/internal/provider"
+)
+
+const (
+	// defaultContextWindow and defaultOutputTokens are assumed, in tokens,
+	// for models whose limits are unknown.
+	defaultContextWindow = 8192
+	defaultOutputTokens  = 2048
+
+	// summaryShare is the fraction of the history budget, as a divisor,
+	// left for the summary of older turns.
+	summaryShare = 4
+
+	// maxSummaryTokens caps each summary, however large the budget.
+	maxSummaryTokens = 1024
+)
+
+// Turn is one contribution to a discussion.
+type Turn struct {
+	Speaker string // Display name of the contributor
+	Heading string // Where it was made, such as "Round 2"; optional
+	Content string
+}
+
+// String renders the turn as a Markdown section.
+func (t Turn) String() string {
+	if t.Heading != "" {
+		return fmt.Sprintf("### %s (%s):\n%s", t.Speaker, t.Heading, t.Content)
+	}
+	return fmt.Sprintf("### %s:\n%s", t.Speaker, t.Content)
+}
+
+// Render joins turns in full.
+func Render(turns []Turn) string {
+	parts := make([]string, len(turns))
+	for i, t := range turns {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Manager fits history into prompts for the models in a registry. Summaries
+// are cached, so a discussion that grows turn by turn only summarizes each
+// turn once. It is safe for concurrent use.
+type Manager struct {
+	registry    *provider.Registry
+	maxChars    int
+	fullHistory bool
+
+	mu         sync.Mutex
+	summarizer string              // AI ID for summaries; cleared if it fails
+	summaries  map[[32]byte]string // Keyed by the hash chain of the turns covered
+}
+
+// NewManager creates a context manager using the limits in cfg.
+func NewManager(registry *provider.Registry, cfg config.ContextConfig) *Manager {
+	return &Manager{
+		registry:    registry,
+		maxChars:    cfg.MaxContextChars,
+		fullHistory: cfg.IncludeFullHistory,
+		summarizer:  cfg.SummaryModel,
+		summaries:   make(map[[32]byte]string),
+	}
+}
+
+// Budget returns the tokens of history that fit in a prompt to aiID
+// alongside reserve, the rest of the prompt. Room is left for the reply, and
+// unless full history is enabled, the configured character cap applies.
+func (m *Manager) Budget(aiID, reserve string) int {
+	budget := m.room(aiID, reserve)
+	if !m.fullHistory && m.maxChars > 0 {
+		budget = min(budget, m.maxChars/provider.CharsPerToken)
+	}
+	return budget
+}
+
+// room returns the tokens left in aiID's context window once reserve and
+// the reply are accounted for.
+func (m *Manager) room(aiID, reserve string) int {
+	window, output := defaultContextWindow, defaultOutputTokens
+	if caps, err := m.registry.Capabilities(aiID); err == nil {
+		if caps.ContextWindow > 0 {
+			window = caps.ContextWindow
+		}
+		if caps.MaxOutputTokens > 0 {
+			output = min(caps.MaxOutputTokens, window/4)
+		}
+	}
+	return max(window-output-provider.EstimateTokens(reserve), 0)
+}
+
+// Fit renders turns for a prompt to aiID, where reserve is the rest of the
+// prompt. When the turns do not fit, the most recent are kept and the older
+// ones are replaced by a summary.
+func (m *Manager) Fit(ctx context.Context, aiID string, turns []Turn, reserve string) string {
+	budget := m.Budget(aiID, reserve)
+	full := Render(turns)
+	if provider.EstimateTokens(full) <= budget || len(turns) == 0 {
+		return full
+	}
+
+	keep := len(turns) - recentTurns(turns, budget-budget/summaryShare)
+	if keep == len(turns) {
+		// Even the latest turn is too long; keep the start of it
+		latest := turns[keep-1]
+		latest.Content = truncate(latest.Content, budget-budget/summaryShare)
+		turns = append(turns[:keep-1:keep-1], latest)
+		keep--
+	}
+
+	recent := Render(turns[keep:])
+	if keep == 0 {
+		return recent
+	}
+	return m.earlier(ctx, turns[:keep], budget/summaryShare) + "\n\n" + recent
+}
+
+// FitMessages compacts a conversation for aiID. When req's messages do not
+// fit, the earliest exchanges are replaced by a summary placed at the start
+// of the first remaining user turn.
+func (m *Manager) FitMessages(ctx context.Context, aiID string, req provider.Request) provider.Request {
+	budget := m.Budget(aiID, req.SystemPrompt+req.Prompt)
+	if messageTokens(req.Messages) <= budget {
+		return req
+	}
+
+	// Drop whole exchanges, so the kept messages still open with a user turn
+	start := 0
+	for start < len(req.Messages) && messageTokens(req.Messages[start:]) > budget-budget/summaryShare {
+		start++
+		for start < len(req.Messages) && req.Messages[start].Role != provider.RoleUser {
+			start++
+		}
+	}
+
+	dropped := make([]Turn, len(req.Messages[:start]))
+	for i, msg := range req.Messages[:start] {
+		speaker := "Moderator"
+		if msg.Role == provider.RoleAssistant {
+			speaker = aiID
+		}
+		dropped[i] = Turn{Speaker: speaker, Content: msg.Content}
+	}
+	earlier := m.earlier(ctx, dropped, budget/summaryShare)
+
+	kept := append([]provider.Message(nil), req.Messages[start:]...)
+	if len(kept) > 0 {
+		kept[0].Content = earlier + "\n\n" + kept[0].Content
+	} else {
+		req.Prompt = earlier + "\n\n" + req.Prompt
+	}
+	req.Messages = kept
+	return req
+}
+
+// earlier stands in for turns that no longer fit: a summary when one can be
+// written, otherwise a note that they were left out.
+func (m *Manager) earlier(ctx context.Context, turns []Turn, budget int) string {
+	if summary := m.summarize(ctx, turns, min(budget, maxSummaryTokens)); summary != "" {
+		return "### Summary of the earlier discussion:\n" + summary
+	}
+	return fmt.Sprintf("[%d earlier contributions omitted to fit the context window]", len(turns))
+}
+
+// summarize returns a summary of turns in at most maxTokens, or "" if there
+// is no summarizer. It extends the longest cached summary of a prefix of
+// turns, feeding the summarizer as many new turns at a time as it can take.
+func (m *Manager) summarize(ctx context.Context, turns []Turn, maxTokens int) string {
+	m.mu.Lock()
+	summarizer := m.summarizer
+	m.mu.Unlock()
+	if summarizer == "" || maxTokens <= 0 {
+		return ""
+	}
+
+	chain := hashChain(turns)
+	done, summary := 0, ""
+	m.mu.Lock()
+	for i := len(turns); i > 0; i-- {
+		if s, ok := m.summaries[chain[i-1]]; ok {
+			done, summary = i, s
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	for done < len(turns) {
+		budget := m.room(summarizer, summary+summaryInstructions)
+		n := max(firstTurns(turns[done:], budget), 1)
+		batch := turns[done : done+n]
+		if n == 1 {
+			batch = []Turn{{Speaker: batch[0].Speaker, Heading: batch[0].Heading, Content: truncate(batch[0].Content, budget)}}
+		}
+
+		next, err := m.summarizeBatch(ctx, summarizer, summary, batch, maxTokens)
+		if err != nil {
+			if ctx.Err() == nil {
+				// Fall back to dropping turns rather than failing every call
+				m.mu.Lock()
+				m.summarizer = ""
+				m.mu.Unlock()
+			}
+			return ""
+		}
+
+		done += n
+		summary = next
+		m.mu.Lock()
+		m.summaries[chain[done-1]] = summary
+		m.mu.Unlock()
+	}
+	return summary
+}
+
+// summaryInstructions opens each summarization prompt, with the word limit.
+const summaryInstructions = `Summarize this discussion for participants who will continue it.
+Keep each participant's positions, key arguments, decisions and open
+questions, attributed by name. Use at most %d words.
+
+`
+
+// summarizeBatch asks the summarizer to fold batch into the summary so far.
+func (m *Manager) summarizeBatch(ctx context.Context, summarizer, summary string, batch []Turn, maxTokens int) (string, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, summaryInstructions, maxTokens*3/4)
+	if summary != "" {
+		fmt.Fprintf(&prompt, "Summary so far:\n%s\n\nNew contributions:\n", summary)
+	}
+	prompt.WriteString(Render(batch))
+
+	resp, err := m.registry.Invoke(ctx, summarizer, provider.Request{
+		Prompt:       prompt.String(),
+		SystemPrompt: "You write concise, faithful summaries of discussions.",
+		MaxTokens:    maxTokens,
+	})
+	if err != nil {
+		return "", fmt.Errorf("summarizing history: %w", err)
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// recentTurns returns how many turns, counted from the end, fit in budget
+// tokens.
+func recentTurns(turns []Turn, budget int) int {
+	used := 0
+	for n := 0; n < len(turns); n++ {
+		used += provider.EstimateTokens(turns[len(turns)-1-n].String() + "\n\n")
+		if used > budget {
+			return n
+		}
+	}
+	return len(turns)
+}
+
+// firstTurns returns how many turns, counted from the start, fit in budget
+// tokens.
+func firstTurns(turns []Turn, budget int) int {
+	used := 0
+	for n, t := range turns {
+		used += provider.EstimateTokens(t.String() + "\n\n")
+		if used > budget {
+			return n
+		}
+	}
+	return len(turns)
+}
+
+// messageTokens estimates the tokens in a list of messages.
+func messageTokens(messages []provider.Message) int {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += provider.EstimateTokens(msg.Content)
+	}
+	return tokens
+}
+
+// hashChain returns, for each turn, a hash identifying it and every turn
+// before it.
+func hashChain(turns []Turn) [][32]byte {
+	chain := make([][32]byte, len(turns))
+	var prev [32]byte
+	for i, t := range turns {
+		prev = sha256.Sum256(fmt.Appendf(prev[:], "%s\x00%s\x00%s", t.Speaker, t.Heading, t.Content))
+		chain[i] = prev
+	}
+	return chain
+}
+
+// truncate shortens text to about budget tokens, cutting at a rune boundary.
+func truncate(text string, budget int) string {
+	n := max(budget, 0) * provider.CharsPerToken
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n] + "\n[... truncated]"
+}
@@ -0,0 +1,107 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+	"github.com/jxmullins/thekanbansociety/internal/provider"
+)
+
+// summaryProvider answers every request with a numbered summary and records
+// the prompts it was sent.
+type summaryProvider struct {
+	prompts []string
+}
+
+func (p *summaryProvider) Name() string                          { return "summary" }
+func (p *summaryProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (p *summaryProvider) Invoke(ctx context.Context, req provider.Request) (*provider.Response, error) {
+	p.prompts = append(p.prompts, req.Prompt)
+	return &provider.Response{Content: fmt.Sprintf("summary %d", len(p.prompts))}, nil
+}
+
+func (p *summaryProvider) Stream(ctx context.Context, req provider.Request) (<-chan provider.StreamChunk, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func newTestManager(summarizer string) (*Manager, *summaryProvider) {
+	p := &summaryProvider{}
+	r := provider.NewRegistry()
+	r.Register(p)
+	r.RegisterModels(map[string]config.ModelConfig{
+		"cheap":  {Provider: "summary"},
+		"member": {Provider: "summary"},
+	})
+	return NewManager(r, config.ContextConfig{MaxContextChars: 400, SummaryModel: summarizer}), p
+}
+
+func turns(n int) []Turn {
+	out := make([]Turn, n)
+	for i := range out {
+		out[i] = Turn{Speaker: fmt.Sprintf("AI %d", i+1), Content: strings.Repeat("x", 80)}
+	}
+	return out
+}
+
+func TestFitSummarizesOlderTurns(t *testing.T) {
+	m, p := newTestManager("cheap")
+	ctx := context.Background()
+
+	if got := m.Fit(ctx, "member", turns(2), ""); got != Render(turns(2)) {
+		t.Errorf("Fit() changed history that fits: %q", got)
+	}
+	if len(p.prompts) != 0 {
+		t.Fatalf("summarizer called %d times for history that fits", len(p.prompts))
+	}
+
+	got := m.Fit(ctx, "member", turns(6), "")
+	if !strings.HasPrefix(got, "### Summary of the earlier discussion:\nsummary 1") || !strings.HasSuffix(got, Render(turns(6)[5:])) {
+		t.Errorf("Fit() = %q, want a summary then the latest turns", got)
+	}
+	if provider.EstimateTokens(got) > m.Budget("member", "") {
+		t.Errorf("Fit() returned %d tokens, over the %d token budget", provider.EstimateTokens(got), m.Budget("member", ""))
+	}
+
+	// A longer discussion extends the cached summary with only the new turns
+	m.Fit(ctx, "member", turns(8), "")
+	if len(p.prompts) != 2 || !strings.Contains(p.prompts[1], "Summary so far:\nsummary 1") || strings.Contains(p.prompts[1], "### AI 1:") {
+		t.Errorf("second summary prompt = %q, want the earlier summary extended", p.prompts[len(p.prompts)-1])
+	}
+}
+
+func TestFitWithoutSummarizerDropsOlderTurns(t *testing.T) {
+	m, _ := newTestManager("")
+
+	got := m.Fit(context.Background(), "member", turns(6), "")
+	if !strings.HasPrefix(got, "[3 earlier contributions omitted") {
+		t.Errorf("Fit() = %q, want older turns dropped", got)
+	}
+}
+
+func TestFitMessagesKeepsUserTurnFirst(t *testing.T) {
+	m, _ := newTestManager("cheap")
+
+	var messages []provider.Message
+	for i := range 4 {
+		messages = append(messages,
+			provider.Message{Role: provider.RoleUser, Content: fmt.Sprintf("question %d %s", i, strings.Repeat("q", 60))},
+			provider.Message{Role: provider.RoleAssistant, Content: fmt.Sprintf("answer %d %s", i, strings.Repeat("a", 60))},
+		)
+	}
+
+	req := m.FitMessages(context.Background(), "member", provider.Request{Messages: messages, Prompt: "next"})
+	if len(req.Messages) == 0 || len(req.Messages) >= len(messages) {
+		t.Fatalf("kept %d of %d messages", len(req.Messages), len(messages))
+	}
+	first := req.Messages[0]
+	if first.Role != provider.RoleUser || !strings.HasPrefix(first.Content, "### Summary of the earlier discussion:") {
+		t.Errorf("first message = %+v, want a user turn opening with the summary", first)
+	}
+	if req.Messages[len(req.Messages)-1].Content != messages[len(messages)-1].Content {
+		t.Error("latest message was not kept verbatim")
+	}
+}
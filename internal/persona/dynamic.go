@@ -4,6 +4,7 @@ package persona
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/jxmullins/thekanbansociety/internal/config"
@@ -160,62 +161,99 @@ Round: %d
 Recent responses:
 %s
 
-For each participant that should change approach, respond with:
-AI: [ai_id]
-FROM: [current_style]
-TO: [suggested_persona: analytical, devils_advocate, synthesizer, pragmatist, visionary]
-REASON: [brief reason]
-
-If no changes needed, respond: NO_CHANGES_NEEDED`,
-		topic, round, strings.Join(responses, "\n---\n"))
-
-	resp, err := m.registry.Invoke(ctx, "claude", provider.Request{
-		Prompt:       prompt,
-		SystemPrompt: "You are a debate moderator analyzing participant dynamics.",
-	})
+Reply with JSON listing a switch for each participant that should change
+approach: the AI ID, its current style, the persona to adopt (%s) and a
+brief reason. Return an empty list if no changes are needed.`,
+		topic, round, strings.Join(responses, "\n---\n"), strings.Join(m.personaIDs(), ", "))
+
+	var reply switchesReply
+	reply.personas = m.personaIDs()
+	_, err := m.registry.InvokeJSON(ctx, "claude", provider.Request{
+		Prompt:         prompt,
+		SystemPrompt:   "You are a debate moderator analyzing participant dynamics.",
+		ResponseSchema: switchesSchema(reply.personas),
+	}, &reply)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("parsing persona suggestions: %w", err)
 	}
 
-	// Parse suggestions
-	return m.parseSuggestions(resp.Content, round), nil
+	switches := make([]PersonaSwitch, 0, len(reply.Switches))
+	for _, sw := range reply.Switches {
+		switches = append(switches, PersonaSwitch{
+			AIID:        sw.AIID,
+			FromPersona: sw.From,
+			ToPersona:   sw.To,
+			Reason:      sw.Reason,
+			Round:       round,
+		})
+	}
+	return switches, nil
 }
 
-func (m *DynamicPersonaManager) parseSuggestions(content string, round int) []PersonaSwitch {
-	if strings.Contains(content, "NO_CHANGES_NEEDED") {
-		return nil
+// switchesReply is the moderator's structured list of suggested switches.
+type switchesReply struct {
+	Switches []struct {
+		AIID   string `json:"ai_id"`
+		From   string `json:"from"`
+		To     string `json:"to"`
+		Reason string `json:"reason"`
+	} `json:"switches"`
+
+	personas []string // Valid target personas, checked by Validate
+}
+
+// switchesSchema returns the JSON Schema for persona switch suggestions.
+func switchesSchema(personas []string) *provider.ResponseSchema {
+	return &provider.ResponseSchema{
+		Name: "persona_switches",
+		Schema: map[string]any{
+			"type":     "object",
+			"required": []string{"switches"},
+			"properties": map[string]any{
+				"switches": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type":     "object",
+						"required": []string{"ai_id", "to", "reason"},
+						"properties": map[string]any{
+							"ai_id":  map[string]any{"type": "string"},
+							"from":   map[string]any{"type": "string"},
+							"to":     map[string]any{"type": "string", "enum": personas},
+							"reason": map[string]any{"type": "string"},
+						},
+					},
+				},
+			},
+		},
 	}
+}
 
-	var switches []PersonaSwitch
-	lines := strings.Split(content, "\n")
-
-	var current PersonaSwitch
-	current.Round = round
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "AI:") {
-			if current.AIID != "" && current.ToPersona != "" {
-				switches = append(switches, current)
-			}
-			current = PersonaSwitch{Round: round}
-			current.AIID = strings.TrimSpace(strings.TrimPrefix(line, "AI:"))
-		} else if strings.HasPrefix(line, "FROM:") {
-			current.FromPersona = strings.TrimSpace(strings.TrimPrefix(line, "FROM:"))
-		} else if strings.HasPrefix(line, "TO:") {
-			current.ToPersona = strings.TrimSpace(strings.TrimPrefix(line, "TO:"))
-		} else if strings.HasPrefix(line, "REASON:") {
-			current.Reason = strings.TrimSpace(strings.TrimPrefix(line, "REASON:"))
+// Validate checks each switch names an AI and a known persona.
+func (r *switchesReply) Validate() error {
+	for i, sw := range r.Switches {
+		if strings.TrimSpace(sw.AIID) == "" {
+			return fmt.Errorf("switch %d has no ai_id", i+1)
+		}
+		if !slices.Contains(r.personas, sw.To) {
+			return fmt.Errorf("switch %d targets unknown persona %q", i+1, sw.To)
 		}
 	}
+	return nil
+}
 
-	// Add last switch if valid
-	if current.AIID != "" && current.ToPersona != "" {
-		switches = append(switches, current)
+// personaIDs returns the built-in and registered persona IDs, sorted.
+func (m *DynamicPersonaManager) personaIDs() []string {
+	ids := make([]string, 0, len(m.personas)+5)
+	for _, p := range GetDefaultPersonas() {
+		ids = append(ids, p.ID)
 	}
-
-	return switches
+	for id := range m.personas {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
 }
 
 // ApplySwitch applies a persona switch.
@@ -6,11 +6,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
 const (
 	anthropicAPIURL     = "https://api.anthropic.com/v1/messages"
 	anthropicAPIVersion = "2023-06-01"
+
+	// anthropicMinThinkingBudget is the smallest budget the API accepts.
+	anthropicMinThinkingBudget = 1024
 )
 
 // AnthropicProvider implements the Provider interface for Anthropic's Claude API.
@@ -42,11 +46,28 @@ type anthropicRequest struct {
 	System    string             `json:"system,omitempty"`
 	Messages  []anthropicMessage `json:"messages"`
 	Stream    bool               `json:"stream,omitempty"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+	Thinking  *anthropicThinking `json:"thinking,omitempty"`
+}
+
+// anthropicThinking enables extended thinking with a token budget.
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
 }
 
+// anthropicMessage holds either plain text content or, for turns involving
+// tools, a list of content blocks.
 type anthropicMessage struct {
 	Role    string `json:"role"`
-	Content string `json:"content"`
+	Content any    `json:"content"`
+}
+
+// anthropicTool represents a tool definition offered to the model.
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
 }
 
 // anthropicResponse represents the response from the Anthropic API.
@@ -58,30 +79,60 @@ type anthropicResponse struct {
 	Model        string `json:"model"`
 	StopReason   string `json:"stop_reason"`
 	StopSequence string `json:"stop_sequence"`
-	Usage        struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	Usage        anthropicUsage `json:"usage"`
 }
 
+// anthropicUsage represents token usage reported by the Anthropic API.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// toUsage converts Anthropic usage into provider usage. Anthropic reports
+// cache reads and writes separately from uncached input, so they are added back.
+func (u anthropicUsage) toUsage() Usage {
+	return Usage{
+		InputTokens:  u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		OutputTokens: u.OutputTokens,
+		CachedTokens: u.CacheReadInputTokens,
+	}
+}
+
+// anthropicContent represents a content block in a message or response.
 type anthropicContent struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type      string           `json:"type"`
+	Text      string           `json:"text,omitempty"`
+	ID        string           `json:"id,omitempty"`          // tool_use
+	Name      string           `json:"name,omitempty"`        // tool_use
+	Input     json.RawMessage  `json:"input,omitempty"`       // tool_use
+	ToolUseID string           `json:"tool_use_id,omitempty"` // tool_result
+	Content   string           `json:"content,omitempty"`     // tool_result
+	Thinking  string           `json:"thinking,omitempty"`    // thinking
+	Source    *anthropicSource `json:"source,omitempty"`      // image, document
+}
+
+// anthropicSource carries the data of an image or document block.
+type anthropicSource struct {
+	Type      string `json:"type"` // Always "base64"
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
 }
 
 // anthropicStreamEvent represents a streaming event from the Anthropic API.
 type anthropicStreamEvent struct {
-	Type         string `json:"type"`
-	Index        int    `json:"index"`
-	ContentBlock *struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content_block"`
-	Delta *struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
+	Type         string            `json:"type"`
+	Index        int               `json:"index"`
+	ContentBlock *anthropicContent `json:"content_block"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJSON string `json:"partial_json"`
 	} `json:"delta"`
 	Message *anthropicResponse `json:"message"`
+	Usage   *anthropicUsage    `json:"usage"`
 }
 
 // anthropicErrorResponse represents an error response from the API.
@@ -100,7 +151,7 @@ func (p *AnthropicProvider) Invoke(ctx context.Context, req Request) (*Response,
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return nil, err
 	}
 
@@ -112,14 +163,14 @@ func (p *AnthropicProvider) Invoke(ctx context.Context, req Request) (*Response,
 	apiReq := anthropicRequest{
 		Model:     p.model,
 		MaxTokens: maxTokens,
-		System:    req.SystemPrompt,
-		Messages: []anthropicMessage{
-			{Role: "user", Content: req.Prompt},
-		},
+		System:    schemaSystemPrompt(req),
+		Messages:  buildAnthropicMessages(req),
+		Tools:     buildAnthropicTools(req.Tools),
 	}
+	applyAnthropicThinking(&apiReq, req)
 
 	headers := map[string]string{
-		"x-api-key":         p.GetAPIKey(),
+		"x-api-key":         p.GetAPIKey(ctx),
 		"anthropic-version": anthropicAPIVersion,
 	}
 
@@ -137,9 +188,9 @@ func (p *AnthropicProvider) Invoke(ctx context.Context, req Request) (*Response,
 	if resp.StatusCode != http.StatusOK {
 		var errResp anthropicErrorResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error.Message)
+			return nil, NewAPIError(resp, errResp.Error.Message)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(resp, string(body))
 	}
 
 	var apiResp anthropicResponse
@@ -147,19 +198,30 @@ func (p *AnthropicProvider) Invoke(ctx context.Context, req Request) (*Response,
 		return nil, fmt.Errorf("parsing response: %w", err)
 	}
 
-	// Extract text content
-	var content string
+	// Extract text content, thinking and tool calls
+	var content, reasoning string
+	var toolCalls []ToolCall
 	for _, c := range apiResp.Content {
-		if c.Type == "text" {
+		switch c.Type {
+		case "text":
 			content += c.Text
+		case "thinking":
+			reasoning += c.Thinking
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCall{ID: c.ID, Name: c.Name, Arguments: string(c.Input)})
 		}
 	}
 
+	usage := apiResp.Usage.toUsage()
+
 	return &Response{
 		Content:      content,
+		Reasoning:    reasoning,
 		Model:        apiResp.Model,
 		FinishReason: apiResp.StopReason,
-		TokensUsed:   apiResp.Usage.InputTokens + apiResp.Usage.OutputTokens,
+		TokensUsed:   usage.Total(),
+		Usage:        usage,
+		ToolCalls:    toolCalls,
 	}, nil
 }
 
@@ -170,7 +232,7 @@ func (p *AnthropicProvider) Stream(ctx context.Context, req Request) (<-chan Str
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return nil, err
 	}
 
@@ -182,15 +244,15 @@ func (p *AnthropicProvider) Stream(ctx context.Context, req Request) (<-chan Str
 	apiReq := anthropicRequest{
 		Model:     p.model,
 		MaxTokens: maxTokens,
-		System:    req.SystemPrompt,
-		Messages: []anthropicMessage{
-			{Role: "user", Content: req.Prompt},
-		},
-		Stream: true,
+		System:    schemaSystemPrompt(req),
+		Messages:  buildAnthropicMessages(req),
+		Tools:     buildAnthropicTools(req.Tools),
+		Stream:    true,
 	}
+	applyAnthropicThinking(&apiReq, req)
 
 	headers := map[string]string{
-		"x-api-key":         p.GetAPIKey(),
+		"x-api-key":         p.GetAPIKey(ctx),
 		"anthropic-version": anthropicAPIVersion,
 	}
 
@@ -204,37 +266,178 @@ func (p *AnthropicProvider) Stream(ctx context.Context, req Request) (<-chan Str
 		body, _ := io.ReadAll(resp.Body)
 		var errResp anthropicErrorResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error.Message)
+			return nil, NewAPIError(resp, errResp.Error.Message)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(resp, string(body))
 	}
 
 	out := make(chan StreamChunk, 100)
 
-	go p.ReadSSEStream(resp, out, func(data []byte) (string, bool, error) {
+	// Tool input arrives as JSON fragments between a block's start and stop
+	var pending *ToolCall
+	var input strings.Builder
+
+	go p.ReadSSEStream(resp, out, func(data []byte) (StreamChunk, error) {
 		var event anthropicStreamEvent
 		if err := json.Unmarshal(data, &event); err != nil {
-			return "", false, fmt.Errorf("parsing stream event: %w", err)
+			return StreamChunk{}, fmt.Errorf("parsing stream event: %w", err)
 		}
 
 		switch event.Type {
+		case "content_block_start":
+			if event.ContentBlock != nil && event.ContentBlock.Type == "tool_use" {
+				pending = &ToolCall{ID: event.ContentBlock.ID, Name: event.ContentBlock.Name}
+				input.Reset()
+			}
+		case "content_block_stop":
+			if pending != nil {
+				pending.Arguments = input.String()
+				call := *pending
+				pending = nil
+				return StreamChunk{ToolCalls: []ToolCall{call}}, nil
+			}
+		case "message_start":
+			// Input usage arrives up front; output usage follows in message_delta
+			if event.Message != nil {
+				usage := event.Message.Usage.toUsage()
+				return StreamChunk{Usage: &usage}, nil
+			}
 		case "content_block_delta":
 			if event.Delta != nil && event.Delta.Type == "text_delta" {
-				return event.Delta.Text, false, nil
+				return StreamChunk{Content: event.Delta.Text}, nil
+			}
+			if event.Delta != nil && event.Delta.Type == "thinking_delta" {
+				return StreamChunk{Reasoning: event.Delta.Thinking}, nil
+			}
+			if event.Delta != nil && event.Delta.Type == "input_json_delta" {
+				input.WriteString(event.Delta.PartialJSON)
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				return StreamChunk{Usage: &Usage{OutputTokens: event.Usage.OutputTokens}}, nil
 			}
 		case "message_stop":
-			return "", true, nil
+			return StreamChunk{Done: true}, nil
 		}
 
-		return "", false, nil
+		return StreamChunk{}, nil
 	})
 
 	return out, nil
 }
 
+// buildAnthropicMessages converts the request conversation into API messages.
+// Tool calls become tool_use blocks, and consecutive tool results are grouped
+// into a single user turn of tool_result blocks as the API requires.
+func buildAnthropicMessages(req Request) []anthropicMessage {
+	conversation := req.Conversation()
+	messages := make([]anthropicMessage, 0, len(conversation))
+	resultsTurn := -1
+
+	for _, m := range conversation {
+		switch {
+		case m.Role == RoleTool:
+			block := anthropicContent{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content}
+			if resultsTurn >= 0 && resultsTurn == len(messages)-1 {
+				blocks := messages[resultsTurn].Content.([]anthropicContent)
+				messages[resultsTurn].Content = append(blocks, block)
+			} else {
+				messages = append(messages, anthropicMessage{Role: RoleUser, Content: []anthropicContent{block}})
+				resultsTurn = len(messages) - 1
+			}
+
+		case len(m.ToolCalls) > 0:
+			blocks := make([]anthropicContent, 0, len(m.ToolCalls)+1)
+			if m.Content != "" {
+				blocks = append(blocks, anthropicContent{Type: "text", Text: m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				blocks = append(blocks, anthropicContent{
+					Type:  "tool_use",
+					ID:    call.ID,
+					Name:  call.Name,
+					Input: call.argumentsJSON(),
+				})
+			}
+			messages = append(messages, anthropicMessage{Role: m.Role, Content: blocks})
+
+		case len(mediaAttachments(m)) > 0:
+			messages = append(messages, anthropicMessage{Role: m.Role, Content: anthropicMediaBlocks(m)})
+
+		default:
+			messages = append(messages, anthropicMessage{Role: m.Role, Content: messageText(m)})
+		}
+	}
+	return messages
+}
+
+// anthropicMediaBlocks renders a turn with image or PDF attachments as
+// content blocks. The media come before the text, as the API recommends.
+func anthropicMediaBlocks(m Message) []anthropicContent {
+	media := mediaAttachments(m)
+	blocks := make([]anthropicContent, 0, len(media)+1)
+	for _, a := range media {
+		blockType := "image"
+		if a.Kind() == AttachmentDocument {
+			blockType = "document"
+		}
+		blocks = append(blocks, anthropicContent{
+			Type:   blockType,
+			Source: &anthropicSource{Type: "base64", MediaType: a.MIMEType, Data: a.base64()},
+		})
+	}
+	if text := messageText(m); text != "" {
+		blocks = append(blocks, anthropicContent{Type: "text", Text: text})
+	}
+	return blocks
+}
+
+// applyAnthropicThinking enables extended thinking when the request sets a
+// budget. The budget counts toward max_tokens, so that is raised to leave room
+// for the answer. Thinking is skipped for tool-loop turns, since the API then
+// requires the earlier thinking blocks to be sent back verbatim.
+func applyAnthropicThinking(apiReq *anthropicRequest, req Request) {
+	if req.ThinkingBudget <= 0 {
+		return
+	}
+	for _, m := range req.Messages {
+		if len(m.ToolCalls) > 0 {
+			return
+		}
+	}
+
+	budget := max(req.ThinkingBudget, anthropicMinThinkingBudget)
+	apiReq.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+	if apiReq.MaxTokens <= budget {
+		apiReq.MaxTokens += budget
+	}
+}
+
+// buildAnthropicTools converts tool definitions into the API format.
+func buildAnthropicTools(tools []Tool) []anthropicTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]anthropicTool, 0, len(tools))
+	for _, t := range tools {
+		schema := t.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		out = append(out, anthropicTool{Name: t.Name, Description: t.Description, InputSchema: schema})
+	}
+	return out
+}
+
+// Capabilities reports the features of the Messages API. Response schemas
+// are described in the system prompt, since the API has no JSON mode.
+func (p *AnthropicProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, SystemPrompt: true, Tools: true, Images: true, Documents: true}.withModelLimits(p.model)
+}
+
 // HealthCheck verifies the Anthropic API is accessible.
 func (p *AnthropicProvider) HealthCheck(ctx context.Context) error {
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return err
 	}
 
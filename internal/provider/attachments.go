@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxAttachmentBytes caps the size of a single attachment.
+const MaxAttachmentBytes = 20 * 1024 * 1024
+
+// AttachmentKind says how an attachment reaches a model.
+type AttachmentKind string
+
+const (
+	AttachmentText     AttachmentKind = "text"     // Inlined into the message for every model
+	AttachmentImage    AttachmentKind = "image"    // Needs Capabilities.Images
+	AttachmentDocument AttachmentKind = "document" // A PDF; needs Capabilities.Documents
+)
+
+// imageTypes are the image formats every multimodal API accepts.
+var imageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Attachment is a file sent along with a message, such as a screenshot, a
+// diagram or a spec.
+type Attachment struct {
+	Name     string // File name shown to the model
+	MIMEType string
+	Data     []byte
+}
+
+// LoadAttachment reads a file to attach to a request. Images, PDFs and UTF-8
+// text files are supported.
+func LoadAttachment(path string) (Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("reading attachment: %w", err)
+	}
+	if info.Size() > MaxAttachmentBytes {
+		return Attachment{}, fmt.Errorf("attachment %s is %d bytes (max: %d)", path, info.Size(), MaxAttachmentBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("reading attachment: %w", err)
+	}
+
+	a := Attachment{Name: filepath.Base(path), MIMEType: detectMIMEType(path, data), Data: data}
+	if a.Kind() == "" {
+		return Attachment{}, fmt.Errorf("attachment %s: unsupported type %s", path, a.MIMEType)
+	}
+	return a, nil
+}
+
+// detectMIMEType identifies a file by its extension, or failing that, by its
+// contents.
+func detectMIMEType(name string, data []byte) string {
+	t := mime.TypeByExtension(filepath.Ext(name))
+	if t == "" {
+		t = http.DetectContentType(data)
+	}
+	if base, _, err := mime.ParseMediaType(t); err == nil {
+		return base
+	}
+	return t
+}
+
+// Kind classifies the attachment, returning "" for binary formats no model
+// accepts.
+func (a Attachment) Kind() AttachmentKind {
+	switch {
+	case strings.HasPrefix(a.MIMEType, "image/"):
+		for _, t := range imageTypes {
+			if a.MIMEType == t {
+				return AttachmentImage
+			}
+		}
+		return ""
+	case a.MIMEType == "application/pdf":
+		return AttachmentDocument
+	case utf8.Valid(a.Data):
+		return AttachmentText
+	default:
+		return ""
+	}
+}
+
+// base64 returns the attachment's data in standard base64.
+func (a Attachment) base64() string {
+	return base64.StdEncoding.EncodeToString(a.Data)
+}
+
+// dataURL returns the attachment as a data: URL.
+func (a Attachment) dataURL() string {
+	return "data:" + a.MIMEType + ";base64," + a.base64()
+}
+
+// Accepts reports whether a model with these capabilities can read a.
+func (c Capabilities) Accepts(a Attachment) bool {
+	switch a.Kind() {
+	case AttachmentText:
+		return true
+	case AttachmentImage:
+		return c.Images
+	case AttachmentDocument:
+		return c.Documents
+	default:
+		return false
+	}
+}
+
+// messageText returns a message's content with its text attachments inlined
+// after it. Other attachments are left to the provider.
+func messageText(m Message) string {
+	text := m.Content
+	for _, a := range m.Attachments {
+		if a.Kind() == AttachmentText {
+			text += fmt.Sprintf("\n\n<file name=%q>\n%s\n</file>", a.Name, a.Data)
+		}
+	}
+	return text
+}
+
+// mediaAttachments returns the attachments providers must send as media
+// rather than text.
+func mediaAttachments(m Message) []Attachment {
+	var media []Attachment
+	for _, a := range m.Attachments {
+		if a.Kind() != AttachmentText {
+			media = append(media, a)
+		}
+	}
+	return media
+}
+
+// checkAttachments rejects attachments the model behind aiID cannot read,
+// before any request is sent.
+func (r *Registry) checkAttachments(aiID string, req Request) error {
+	var caps *Capabilities
+	for _, m := range req.Conversation() {
+		for _, a := range m.Attachments {
+			if caps == nil {
+				c, err := r.Capabilities(aiID)
+				if err != nil {
+					return err
+				}
+				caps = &c
+			}
+			if !caps.Accepts(a) {
+				kind := a.Kind()
+				if kind == "" {
+					kind = AttachmentKind(a.MIMEType)
+				}
+				return fmt.Errorf("%s cannot read %s attachment %q", aiID, kind, a.Name)
+			}
+		}
+	}
+	return nil
+}
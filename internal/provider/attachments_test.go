@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+var (
+	testImage = Attachment{Name: "mockup.png", MIMEType: "image/png", Data: []byte("\x89PNG\r\n\x1a\n")}
+	testSpec  = Attachment{Name: "spec.md", MIMEType: "text/markdown", Data: []byte("# Spec")}
+)
+
+func TestLoadAttachment(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	a, err := LoadAttachment(write("shot", testImage.Data))
+	if err != nil || a.Kind() != AttachmentImage || a.Name != "shot" {
+		t.Errorf("LoadAttachment(png) = %+v, %v", a, err)
+	}
+	if a, err := LoadAttachment(write("notes.txt", []byte("hello"))); err != nil || a.Kind() != AttachmentText {
+		t.Errorf("LoadAttachment(txt) = %+v, %v", a, err)
+	}
+	if _, err := LoadAttachment(write("app.bin", []byte{0x7f, 'E', 'L', 'F', 0xff, 0x00})); err == nil {
+		t.Error("expected error for a binary file")
+	}
+}
+
+func TestAttachmentMessages(t *testing.T) {
+	req := Request{Prompt: "review this", Attachments: []Attachment{testImage, testSpec}}
+
+	data, err := json.Marshal(buildOpenAIMessages(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `{"type":"image_url","image_url":{"url":"data:image/png;base64,`) ||
+		!strings.Contains(string(data), `{"type":"text","text":"review this\n\n\u003cfile name=\"spec.md\"\u003e\n# Spec`) {
+		t.Errorf("OpenAI messages = %s", data)
+	}
+
+	blocks, ok := buildAnthropicMessages(req)[0].Content.([]anthropicContent)
+	if !ok || len(blocks) != 2 || blocks[0].Type != "image" || blocks[0].Source.MediaType != "image/png" || blocks[1].Type != "text" {
+		t.Errorf("Anthropic blocks = %+v", blocks)
+	}
+
+	// CLIs only get text, so the spec is inlined
+	if got := FlattenMessages(req.Conversation()); !strings.Contains(got, "# Spec") {
+		t.Errorf("FlattenMessages() = %q, want the text attachment inlined", got)
+	}
+}
+
+func TestRegistryRejectsUnreadableAttachments(t *testing.T) {
+	flaky := &flakyProvider{}
+	r := NewRegistry()
+	r.Register(NewCLIProvider(CLIProviderConfig{Name: "plain-cli", Command: "plain"}))
+	r.Register(flaky)
+	r.RegisterModels(map[string]config.ModelConfig{
+		"vision": {Provider: "flaky"},
+	})
+
+	_, err := r.Invoke(context.Background(), "plain-cli", Request{Prompt: "hi", Attachments: []Attachment{testImage}})
+	if err == nil || !strings.Contains(err.Error(), `cannot read image attachment "mockup.png"`) {
+		t.Errorf("Invoke() error = %v, want the image rejected", err)
+	}
+
+	// Text attachments reach every model
+	if _, err := r.Invoke(context.Background(), "vision", Request{Prompt: "hi", Attachments: []Attachment{testSpec}}); err != nil || flaky.calls != 1 {
+		t.Errorf("Invoke() error = %v after %d calls", err, flaky.calls)
+	}
+}
@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+	"github.com/jxmullins/thekanbansociety/internal/redact"
+)
+
+// AuditEntry is one line of the audit log: everything sent to a provider in a
+// single call and what came back.
+type AuditEntry struct {
+	Time         time.Time      `json:"time"`
+	SessionID    string         `json:"session_id,omitempty"`
+	TaskID       string         `json:"task_id,omitempty"`
+	AIID         string         `json:"ai_id"`
+	Provider     string         `json:"provider"`
+	Model        string         `json:"model,omitempty"`
+	Stream       bool           `json:"stream"`
+	SystemPrompt string         `json:"system_prompt,omitempty"`
+	Messages     []AuditMessage `json:"messages"`
+	Response     string         `json:"response,omitempty"`
+	Reasoning    string         `json:"reasoning,omitempty"`
+	DurationMS   int64          `json:"duration_ms"`
+	InputTokens  int            `json:"input_tokens,omitempty"`
+	OutputTokens int            `json:"output_tokens,omitempty"`
+	Cached       bool           `json:"cached,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// AuditMessage is a conversation turn as it was sent. Text attachments are
+// inlined in Content; other attachments are listed by name.
+type AuditMessage struct {
+	Role        string   `json:"role"`
+	Content     string   `json:"content"`
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// AuditLog appends an AuditEntry per provider call to a JSONL file, with
+// secrets redacted first. It is safe for concurrent use.
+type AuditLog struct {
+	mu       sync.Mutex
+	path     string
+	redactor *redact.Redactor
+}
+
+// NewAuditLog creates an audit log appending to path, creating the file if
+// needed so an unwritable path is reported before any calls are made.
+func NewAuditLog(path string, redactor *redact.Redactor) (*AuditLog, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return nil, fmt.Errorf("creating audit log directory: %w", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	f.Close()
+	return &AuditLog{path: path, redactor: redactor}, nil
+}
+
+// NewAuditLogFromConfig creates an audit log from the audit settings.
+func NewAuditLogFromConfig(cfg config.AuditConfig, redactor *redact.Redactor) (*AuditLog, error) {
+	return NewAuditLog(cfg.Path, redactor)
+}
+
+// UseAuditLog logs every call made through the registry to the audit log cfg
+// configures, if it is enabled, redacting secrets matched by the built-in
+// patterns and redaction's. Embed calls are not logged.
+func (r *Registry) UseAuditLog(cfg config.AuditConfig, redaction config.RedactionConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	redactor, err := redact.New(redaction.Patterns)
+	if err != nil {
+		return err
+	}
+	audit, err := NewAuditLogFromConfig(cfg, redactor)
+	if err != nil {
+		return err
+	}
+	r.Use(Audit(audit))
+	return nil
+}
+
+// Audit returns middleware that writes every call to l. Failed writes are
+// dropped rather than failing the call.
+func Audit(l *AuditLog) Middleware {
+	return Timing(func(c CallReport) {
+		_ = l.Write(l.entry(c))
+	})
+}
+
+// entry builds the redacted audit entry for a finished call.
+func (l *AuditLog) entry(c CallReport) AuditEntry {
+	r := l.redactor
+	e := AuditEntry{
+		Time:         time.Now().Add(-c.Duration),
+		SessionID:    c.SessionID,
+		TaskID:       c.TaskID,
+		AIID:         c.AIID,
+		Provider:     c.Provider,
+		Model:        c.Model,
+		Stream:       c.Stream,
+		SystemPrompt: r.Redact(c.Request.SystemPrompt),
+		Response:     r.Redact(c.Content),
+		Reasoning:    r.Redact(c.Reasoning),
+		DurationMS:   c.Duration.Milliseconds(),
+		InputTokens:  c.Usage.InputTokens,
+		OutputTokens: c.Usage.OutputTokens,
+		Cached:       c.Cached,
+	}
+	for _, m := range c.Request.Conversation() {
+		am := AuditMessage{Role: m.Role, Content: r.Redact(messageText(m))}
+		for _, a := range mediaAttachments(m) {
+			am.Attachments = append(am.Attachments, a.Name)
+		}
+		e.Messages = append(e.Messages, am)
+	}
+	if c.Err != nil {
+		e.Error = r.Redact(c.Err.Error())
+	}
+	return e
+}
+
+// Write appends an entry to the log as one line of JSON.
+func (l *AuditLog) Write(e AuditEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+	"github.com/jxmullins/thekanbansociety/internal/redact"
+)
+
+func TestAuditLog(t *testing.T) {
+	redactor, err := redact.New([]string{`PROJECT-\d+`})
+	if err != nil {
+		t.Fatalf("redact.New() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "audit", "calls.jsonl")
+	audit, err := NewAuditLog(path, redactor)
+	if err != nil {
+		t.Fatalf("NewAuditLog() error = %v", err)
+	}
+
+	r := NewRegistry()
+	r.Register(&meteredProvider{})
+	r.RegisterModel("claude", config.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5-20250929"})
+	r.Use(Audit(audit))
+
+	ctx := WithTaskID(WithSessionID(context.Background(), "team_1"), "subtask_2")
+	req := Request{
+		SystemPrompt: "Work on PROJECT-42.",
+		Prompt:       "Deploy with OPENAI_API_KEY=sk-abcdefghijklmnopqrstuvwxyz",
+	}
+	if _, err := r.Invoke(ctx, "claude", req); err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	ch, err := r.Stream(context.Background(), "claude", Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	for range ch {
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2", len(entries))
+	}
+	e := entries[0]
+	if e.SessionID != "team_1" || e.TaskID != "subtask_2" || e.AIID != "claude" || e.Provider != "anthropic" {
+		t.Errorf("entry identifies %+v", e)
+	}
+	if e.SystemPrompt != "Work on [REDACTED]." || len(e.Messages) != 1 ||
+		e.Messages[0].Content != "Deploy with OPENAI_API_KEY=[REDACTED]" {
+		t.Errorf("entry not redacted: %q, %+v", e.SystemPrompt, e.Messages)
+	}
+	if e.Response != "ok" || e.OutputTokens != 2000 || e.Stream {
+		t.Errorf("entry response = %+v", e)
+	}
+	if !entries[1].Stream || entries[1].Response != "ok" || entries[1].SessionID != "" {
+		t.Errorf("stream entry = %+v", entries[1])
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "sk-abc") {
+		t.Error("audit log contains an API key")
+	}
+}
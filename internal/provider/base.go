@@ -8,20 +8,23 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
 )
 
 // BaseProvider provides common functionality for HTTP-based providers.
 type BaseProvider struct {
-	name       string
-	client     *http.Client
-	apiKey     string
-	apiKeyEnv  string
-	baseURL    string
-	model      string
-	maxTokens  int
-	timeout    time.Duration
+	name        string
+	client      *http.Client
+	apiKeyEnv   string     // Default variable for the key, named in errors
+	credential  Credential // Where the key comes from; nil for keyless providers
+	baseURL     string
+	model       string
+	maxTokens   int
+	timeout     time.Duration
+	idleTimeout time.Duration // Longest gap between stream reads; 0 waits for timeout
 }
 
 // BaseConfig holds configuration for creating a BaseProvider.
@@ -46,19 +49,20 @@ func NewBaseProvider(cfg BaseConfig) *BaseProvider {
 		maxTokens = 4096
 	}
 
-	apiKey := ""
+	var credential Credential
 	if cfg.APIKeyEnv != "" {
-		apiKey = os.Getenv(cfg.APIKeyEnv)
+		credential = EnvCredential(cfg.APIKeyEnv)
 	}
 
 	return &BaseProvider{
-		name:      cfg.Name,
-		apiKey:    apiKey,
-		apiKeyEnv: cfg.APIKeyEnv,
-		baseURL:   cfg.BaseURL,
-		model:     cfg.Model,
-		maxTokens: maxTokens,
-		timeout:   timeout,
+		name:        cfg.Name,
+		apiKeyEnv:   cfg.APIKeyEnv,
+		credential:  credential,
+		baseURL:     cfg.BaseURL,
+		model:       cfg.Model,
+		maxTokens:   maxTokens,
+		timeout:     timeout,
+		idleTimeout: defaultStreamIdleTimeout,
 		client: &http.Client{
 			Timeout: timeout,
 		},
@@ -70,12 +74,48 @@ func (b *BaseProvider) Name() string {
 	return b.name
 }
 
-// GetAPIKey returns the API key, loading from environment if needed.
-func (b *BaseProvider) GetAPIKey() string {
-	if b.apiKey == "" && b.apiKeyEnv != "" {
-		b.apiKey = os.Getenv(b.apiKeyEnv)
+// GetAPIKey returns the API key from the provider's credential, or "" if
+// there is none.
+func (b *BaseProvider) GetAPIKey(ctx context.Context) string {
+	if b.credential == nil {
+		return ""
+	}
+	key, _ := b.credential.Key(ctx)
+	return key
+}
+
+// SetCredential sets where the API key comes from, replacing the default
+// environment variable.
+func (b *BaseProvider) SetCredential(c Credential) {
+	b.credential = c
+}
+
+// CredentialSource describes where the API key comes from, or returns "" for
+// providers that need none.
+func (b *BaseProvider) CredentialSource() string {
+	if b.credential == nil {
+		return ""
+	}
+	return b.credential.Source()
+}
+
+// ConfigureHTTP replaces the provider's HTTP client with one built from a
+// model's HTTP settings.
+func (b *BaseProvider) ConfigureHTTP(cfg config.HTTPConfig) error {
+	client, err := NewHTTPClient(cfg, b.timeout)
+	if err != nil {
+		return err
 	}
-	return b.apiKey
+	b.client = client
+	if cfg.IdleTimeout > 0 {
+		b.idleTimeout = time.Duration(cfg.IdleTimeout) * time.Second
+	}
+	return nil
+}
+
+// keyEnv returns the provider's default API key variable.
+func (b *BaseProvider) keyEnv() string {
+	return b.apiKeyEnv
 }
 
 // SetModel allows overriding the model.
@@ -88,8 +128,27 @@ func (b *BaseProvider) GetModel() string {
 	return b.model
 }
 
+// GetBaseURL returns the API base URL.
+func (b *BaseProvider) GetBaseURL() string {
+	return b.baseURL
+}
+
 // DoRequest performs an HTTP request with common error handling.
 func (b *BaseProvider) DoRequest(ctx context.Context, method, url string, body interface{}, headers map[string]string) (*http.Response, error) {
+	return doRequest(ctx, b.client, method, url, body, headers)
+}
+
+// doRequestUntimed is DoRequest without the client's overall timeout, for
+// requests that may rightly run for longer, such as model downloads. Only ctx
+// bounds them.
+func (b *BaseProvider) doRequestUntimed(ctx context.Context, method, url string, body interface{}, headers map[string]string) (*http.Response, error) {
+	client := *b.client
+	client.Timeout = 0
+	return doRequest(ctx, &client, method, url, body, headers)
+}
+
+// doRequest performs an HTTP request through client.
+func doRequest(ctx context.Context, client *http.Client, method, url string, body interface{}, headers map[string]string) (*http.Response, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
@@ -109,7 +168,7 @@ func (b *BaseProvider) DoRequest(ctx context.Context, method, url string, body i
 		req.Header.Set(key, value)
 	}
 
-	resp, err := b.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
@@ -118,12 +177,20 @@ func (b *BaseProvider) DoRequest(ctx context.Context, method, url string, body i
 }
 
 // ReadSSEStream reads a Server-Sent Events stream and sends chunks to the channel.
-// The parseFunc extracts content from each SSE data line.
-func (b *BaseProvider) ReadSSEStream(resp *http.Response, out chan<- StreamChunk, parseFunc func([]byte) (string, bool, error)) {
-	defer resp.Body.Close()
+// The parseFunc converts each SSE data line into a chunk; a chunk with Done set
+// ends the stream. Usage reported by any event is merged and delivered on the
+// final chunk.
+func (b *BaseProvider) ReadSSEStream(resp *http.Response, out chan<- StreamChunk, parseFunc func([]byte) (StreamChunk, error)) {
+	body := b.streamBody(resp)
+	defer body.Close()
 	defer close(out)
 
-	scanner := bufio.NewScanner(resp.Body)
+	var usage *Usage
+	finish := func() {
+		out <- StreamChunk{Done: true, Usage: usage}
+	}
+
+	scanner := bufio.NewScanner(body)
 	for scanner.Scan() {
 		line := scanner.Bytes()
 
@@ -141,35 +208,85 @@ func (b *BaseProvider) ReadSSEStream(resp *http.Response, out chan<- StreamChunk
 
 		// Check for stream end marker
 		if bytes.Equal(data, []byte("[DONE]")) {
-			out <- StreamChunk{Done: true}
+			finish()
 			return
 		}
 
-		content, done, err := parseFunc(data)
+		chunk, err := parseFunc(data)
 		if err != nil {
 			out <- StreamChunk{Error: err}
 			return
 		}
 
-		if content != "" {
-			out <- StreamChunk{Content: content}
+		if chunk.Usage != nil {
+			if usage == nil {
+				usage = &Usage{}
+			}
+			usage.merge(*chunk.Usage)
 		}
 
-		if done {
-			out <- StreamChunk{Done: true}
+		if chunk.Content != "" || chunk.Reasoning != "" || len(chunk.ToolCalls) > 0 {
+			out <- StreamChunk{Content: chunk.Content, Reasoning: chunk.Reasoning, ToolCalls: chunk.ToolCalls}
+		}
+
+		if chunk.Done {
+			finish()
 			return
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		out <- StreamChunk{Error: fmt.Errorf("reading stream: %w", err)}
+		return
 	}
+
+	finish()
 }
 
-// CheckAPIKeyRequired verifies the API key is set if required.
-func (b *BaseProvider) CheckAPIKeyRequired() error {
-	if b.apiKeyEnv != "" && b.GetAPIKey() == "" {
-		return fmt.Errorf("API key not set: please set %s environment variable", b.apiKeyEnv)
+// APIError is returned when a provider API responds with a non-success status.
+type APIError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // From the Retry-After header, if present
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// NewAPIError creates an APIError from an HTTP response and a decoded message.
+func NewAPIError(resp *http.Response, message string) *APIError {
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// CheckAPIKeyRequired verifies the API key is available if required.
+func (b *BaseProvider) CheckAPIKeyRequired(ctx context.Context) error {
+	if b.credential == nil {
+		return nil
+	}
+	if _, err := b.credential.Key(ctx); err != nil {
+		return fmt.Errorf("API key not set: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,430 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+// cachePruneInterval is how often the cache directory is swept for expired
+// entries while it is within its size limit.
+const cachePruneInterval = time.Hour
+
+// ResponseCache stores provider responses on disk, keyed by a hash of the
+// AI ID, model and request contents. Responses are stored unredacted, so
+// entries are readable only by their owner.
+type ResponseCache struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+	mu      sync.Mutex
+
+	size   int64     // Bytes on disk as of the last prune, plus entries written since
+	pruned time.Time // Last prune; zero until the first put
+}
+
+// CacheStats summarizes the contents of a ResponseCache.
+type CacheStats struct {
+	Dir     string
+	Entries int
+	Expired int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// cacheEntry is the on-disk form of a cached response. Chunks is set when the
+// response was recorded from a stream, so it can be replayed the same way.
+type cacheEntry struct {
+	CreatedAt     time.Time    `json:"created_at"`
+	Provider      string       `json:"provider"`
+	Model         string       `json:"model"`
+	Content       string       `json:"content"`
+	Reasoning     string       `json:"reasoning,omitempty"`
+	Chunks        []cacheChunk `json:"chunks,omitempty"`
+	FinishReason  string       `json:"finish_reason,omitempty"`
+	ResponseModel string       `json:"response_model,omitempty"`
+	ToolCalls     []ToolCall   `json:"tool_calls,omitempty"`
+	Usage         Usage        `json:"usage"` // Spent by the original call
+}
+
+// cacheChunk is a cached stream chunk. Usage is kept once, on the entry.
+type cacheChunk struct {
+	Content        string     `json:"content,omitempty"`
+	Reasoning      string     `json:"reasoning,omitempty"`
+	ToolCalls      []ToolCall `json:"tool_calls,omitempty"`
+	AgentToolCalls []ToolCall `json:"agent_tool_calls,omitempty"`
+}
+
+// NewResponseCache creates a cache rooted at dir. A zero ttl or maxSize
+// disables expiry or size-based eviction respectively.
+func NewResponseCache(dir string, ttl time.Duration, maxSize int64) *ResponseCache {
+	return &ResponseCache{
+		dir:     dir,
+		ttl:     ttl,
+		maxSize: maxSize,
+	}
+}
+
+// NewResponseCacheFromConfig creates a cache from the cache settings.
+func NewResponseCacheFromConfig(cfg config.CacheConfig) *ResponseCache {
+	return NewResponseCache(
+		cfg.Dir,
+		time.Duration(cfg.TTLHours)*time.Hour,
+		int64(cfg.MaxSizeMB)*1024*1024,
+	)
+}
+
+// CacheKey returns the content address for a request sent to a model, named
+// by the AI ID or provider it is reached through.
+func CacheKey(source, model string, req Request) string {
+	data, _ := json.Marshal(struct {
+		Provider     string          `json:"provider"`
+		Model        string          `json:"model"`
+		Messages     []Message       `json:"messages"`
+		SystemPrompt string          `json:"system_prompt"`
+		MaxTokens    int             `json:"max_tokens"`
+		Temperature  float64         `json:"temperature"`
+		Tools        []Tool          `json:"tools,omitempty"`
+		Schema       *ResponseSchema `json:"schema,omitempty"`
+		Thinking     int             `json:"thinking,omitempty"`
+	}{source, model, req.Conversation(), req.SystemPrompt, req.MaxTokens, req.Temperature, req.Tools, req.ResponseSchema, req.ThinkingBudget})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// path returns the file holding the entry for key.
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// get loads a fresh entry, removing it if it has expired.
+func (c *ResponseCache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		os.Remove(path)
+		return nil, false
+	}
+	if c.expired(entry.CreatedAt) {
+		os.Remove(path)
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+// put writes an entry. The cache is pruned when it grows past its size limit,
+// and otherwise every cachePruneInterval, rather than on every write.
+func (c *ResponseCache) put(key string, entry *cacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding cache entry: %w", err)
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	// Write to a temp file first so readers never see a partial entry
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	// Overwritten entries are counted twice until the next prune corrects it
+	c.size += int64(len(data))
+	if c.pruned.IsZero() || (c.maxSize > 0 && c.size > c.maxSize) || time.Since(c.pruned) > cachePruneInterval {
+		return c.prune()
+	}
+	return nil
+}
+
+// expired reports whether an entry created at t is past the TTL.
+func (c *ResponseCache) expired(t time.Time) bool {
+	return c.ttl > 0 && time.Since(t) > c.ttl
+}
+
+// cacheFile is an entry file found while scanning the cache directory.
+type cacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// scan lists all entry files in the cache directory.
+func (c *ResponseCache) scan() ([]cacheFile, error) {
+	var files []cacheFile
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, cacheFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning cache: %w", err)
+	}
+	return files, nil
+}
+
+// prune removes expired entries, then the oldest entries until the cache
+// fits within its size limit. Callers must hold c.mu.
+func (c *ResponseCache) prune() error {
+	files, err := c.scan()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	var total int64
+	kept := files[:0]
+	for _, f := range files {
+		if c.expired(f.modTime) {
+			os.Remove(f.path)
+			continue
+		}
+		total += f.size
+		kept = append(kept, f)
+	}
+
+	for _, f := range kept {
+		if c.maxSize <= 0 || total <= c.maxSize {
+			break
+		}
+		os.Remove(f.path)
+		total -= f.size
+	}
+
+	c.size = total
+	c.pruned = time.Now()
+	return nil
+}
+
+// Stats reports the number and size of cached entries.
+func (c *ResponseCache) Stats() (CacheStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := CacheStats{Dir: c.dir}
+	files, err := c.scan()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, f := range files {
+		stats.Entries++
+		stats.Bytes += f.size
+		if c.expired(f.modTime) {
+			stats.Expired++
+		}
+		if stats.Oldest.IsZero() || f.modTime.Before(stats.Oldest) {
+			stats.Oldest = f.modTime
+		}
+		if f.modTime.After(stats.Newest) {
+			stats.Newest = f.modTime
+		}
+	}
+
+	return stats, nil
+}
+
+// Clear removes every cached entry and returns how many were deleted.
+func (c *ResponseCache) Clear() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, err := c.scan()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(c.dir); err != nil {
+		return 0, fmt.Errorf("clearing cache: %w", err)
+	}
+	c.size = 0
+	return len(files), nil
+}
+
+// CachingProvider wraps a Provider and serves repeated requests from a
+// ResponseCache. Cache hits are marked Cached and carry the original call's
+// usage, which was not spent again.
+type CachingProvider struct {
+	Provider
+	cache *ResponseCache
+	aiID  string
+	model string
+}
+
+// NewCachingProvider wraps p, keying cache entries on aiID and model. The AI
+// ID keeps apart models that share a provider and model name but are served
+// from different endpoints.
+func NewCachingProvider(p Provider, cache *ResponseCache, aiID, model string) *CachingProvider {
+	return &CachingProvider{
+		Provider: p,
+		cache:    cache,
+		aiID:     aiID,
+		model:    model,
+	}
+}
+
+// Invoke returns a cached response if one exists, otherwise calls the wrapped
+// provider and caches its response.
+func (p *CachingProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	key := CacheKey(p.aiID, p.model, req)
+	if entry, ok := p.cache.get(key); ok {
+		return &Response{
+			Content:      entry.Content,
+			Reasoning:    entry.Reasoning,
+			Model:        entry.ResponseModel,
+			FinishReason: entry.FinishReason,
+			ToolCalls:    entry.ToolCalls,
+			TokensUsed:   entry.Usage.Total(),
+			Usage:        entry.Usage,
+			Cached:       true,
+		}, nil
+	}
+
+	resp, err := p.Provider.Invoke(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed cache write should never fail the call itself
+	_ = p.cache.put(key, &cacheEntry{
+		CreatedAt:     time.Now(),
+		Provider:      p.Name(),
+		Model:         p.model,
+		Content:       resp.Content,
+		Reasoning:     resp.Reasoning,
+		FinishReason:  resp.FinishReason,
+		ResponseModel: resp.Model,
+		ToolCalls:     resp.ToolCalls,
+		Usage:         resp.Usage,
+	})
+	return resp, nil
+}
+
+// Stream replays a cached response chunk by chunk if one exists, otherwise
+// streams from the wrapped provider and caches the stream once it completes.
+// Streams that fail are not cached.
+func (p *CachingProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	key := CacheKey(p.aiID, p.model, req)
+	if entry, ok := p.cache.get(key); ok {
+		return replayEntry(ctx, entry), nil
+	}
+
+	upstream, err := p.Provider.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 100)
+	go func() {
+		defer close(out)
+
+		entry := &cacheEntry{Provider: p.Name(), Model: p.model}
+		var content, reasoning strings.Builder
+		failed := false
+		for chunk := range upstream {
+			if chunk.Error != nil {
+				failed = true
+			}
+			if chunk.Usage != nil {
+				entry.Usage = *chunk.Usage
+			}
+			if chunk.Content != "" || chunk.Reasoning != "" || len(chunk.ToolCalls) > 0 || len(chunk.AgentToolCalls) > 0 {
+				content.WriteString(chunk.Content)
+				reasoning.WriteString(chunk.Reasoning)
+				entry.ToolCalls = append(entry.ToolCalls, chunk.ToolCalls...)
+				entry.Chunks = append(entry.Chunks, cacheChunk{
+					Content:        chunk.Content,
+					Reasoning:      chunk.Reasoning,
+					ToolCalls:      chunk.ToolCalls,
+					AgentToolCalls: chunk.AgentToolCalls,
+				})
+			}
+			if chunk.Done && !failed {
+				entry.CreatedAt = time.Now()
+				entry.Content = content.String()
+				entry.Reasoning = reasoning.String()
+				_ = p.cache.put(key, entry)
+			}
+			out <- chunk
+		}
+	}()
+
+	return out, nil
+}
+
+// replayEntry streams a cached entry, using its recorded chunks when present.
+// The final chunk carries the original call's usage.
+func replayEntry(ctx context.Context, entry *cacheEntry) <-chan StreamChunk {
+	chunks := entry.Chunks
+	if len(chunks) == 0 && (entry.Content != "" || entry.Reasoning != "" || len(entry.ToolCalls) > 0) {
+		chunks = []cacheChunk{{Content: entry.Content, Reasoning: entry.Reasoning, ToolCalls: entry.ToolCalls}}
+	}
+
+	out := make(chan StreamChunk, 100)
+	go func() {
+		defer close(out)
+		for _, rec := range chunks {
+			chunk := StreamChunk{
+				Content:        rec.Content,
+				Reasoning:      rec.Reasoning,
+				ToolCalls:      rec.ToolCalls,
+				AgentToolCalls: rec.AgentToolCalls,
+				Cached:         true,
+			}
+			select {
+			case <-ctx.Done():
+				out <- StreamChunk{Error: ctx.Err(), Done: true}
+				return
+			case out <- chunk:
+			}
+		}
+		usage := entry.Usage
+		out <- StreamChunk{Done: true, Usage: &usage, Cached: true}
+	}()
+	return out
+}
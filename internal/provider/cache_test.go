@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/budget"
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+func TestCachingProviderReplaysStream(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour, 0)
+	upstream := &flakyProvider{}
+	p := NewCachingProvider(upstream, cache, "test", "test-model")
+	req := Request{Prompt: "hello"}
+
+	collect := func() string {
+		ch, err := p.Stream(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Stream() error = %v", err)
+		}
+		var content string
+		for chunk := range ch {
+			content += chunk.Content
+		}
+		return content
+	}
+
+	if got := collect(); got != "ok" {
+		t.Fatalf("first stream = %q, want ok", got)
+	}
+	if got := collect(); got != "ok" {
+		t.Fatalf("replayed stream = %q, want ok", got)
+	}
+	if upstream.calls != 1 {
+		t.Errorf("upstream called %d times, want 1", upstream.calls)
+	}
+
+	resp, err := p.Invoke(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if !resp.Cached || resp.Content != "ok" {
+		t.Errorf("Invoke() = %+v, want cached ok", resp)
+	}
+
+	// A different request must miss
+	if _, err := p.Invoke(context.Background(), Request{Prompt: "other"}); err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if upstream.calls != 2 {
+		t.Errorf("upstream called %d times, want 2", upstream.calls)
+	}
+
+	// So must a model of the same name behind another endpoint
+	other := NewCachingProvider(upstream, cache, "test-gateway", "test-model")
+	if resp, err := other.Invoke(context.Background(), req); err != nil || resp.Cached {
+		t.Fatalf("Invoke() for another AI = %+v, %v; want a live call", resp, err)
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Entries != 3 {
+		t.Errorf("Entries = %d, want 3", stats.Entries)
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour, 0)
+	key := CacheKey("test", "model", Request{Prompt: "hi"})
+
+	if err := cache.put(key, &cacheEntry{CreatedAt: time.Now().Add(-2 * time.Hour), Content: "stale"}); err != nil {
+		t.Fatalf("put() error = %v", err)
+	}
+	if _, ok := cache.get(key); ok {
+		t.Error("get() returned an expired entry")
+	}
+}
+
+// thinkingProvider streams reasoning before its answer and reports usage.
+type thinkingProvider struct {
+	meteredProvider
+	calls int
+}
+
+func (p *thinkingProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	p.calls++
+	ch := make(chan StreamChunk, 3)
+	ch <- StreamChunk{Reasoning: "hmm"}
+	ch <- StreamChunk{Content: "ok"}
+	ch <- StreamChunk{Done: true, Usage: &Usage{InputTokens: 1000, OutputTokens: 2000}}
+	close(ch)
+	return ch, nil
+}
+
+func TestCachedStreamKeepsReasoningAndUsage(t *testing.T) {
+	upstream := &thinkingProvider{}
+	r := NewRegistry()
+	r.Register(upstream)
+	r.RegisterModel("claude", config.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5-20250929"})
+	r.SetCache(NewResponseCache(t.TempDir(), time.Hour, 0))
+	tracker := budget.NewTracker()
+	r.Use(RecordUsage(tracker))
+
+	for range 2 {
+		ch, err := r.Stream(context.Background(), "claude", Request{Prompt: "hi"})
+		if err != nil {
+			t.Fatalf("Stream() error = %v", err)
+		}
+		var reasoning, content string
+		var usage *Usage
+		for chunk := range ch {
+			reasoning += chunk.Reasoning
+			content += chunk.Content
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+		}
+		if reasoning != "hmm" || content != "ok" || usage == nil || usage.OutputTokens != 2000 {
+			t.Errorf("stream = %q, %q, %+v; want reasoning, answer and usage", reasoning, content, usage)
+		}
+	}
+	if upstream.calls != 1 {
+		t.Errorf("upstream called %d times, want 1", upstream.calls)
+	}
+	// The replayed usage was not spent again
+	if usages := tracker.GetUsages(); len(usages) != 1 {
+		t.Errorf("recorded usage = %+v, want the live call only", usages)
+	}
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	entry := &cacheEntry{CreatedAt: time.Now(), Content: strings.Repeat("x", 1000)}
+	data, _ := json.Marshal(entry)
+	cache := NewResponseCache(t.TempDir(), 0, int64(2*len(data)))
+
+	var keys []string
+	for i := range 3 {
+		key := CacheKey("test", "model", Request{Prompt: fmt.Sprint(i)})
+		keys = append(keys, key)
+		if err := cache.put(key, entry); err != nil {
+			t.Fatalf("put() error = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond) // Distinct modification times
+	}
+
+	if _, ok := cache.get(keys[0]); ok {
+		t.Error("oldest entry survived going over the size limit")
+	}
+	if _, ok := cache.get(keys[2]); !ok {
+		t.Error("newest entry was evicted")
+	}
+}
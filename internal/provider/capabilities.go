@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capabilities describes what a provider can do with a request, so callers
+// can pick between Invoke and Stream and reject configurations that cannot
+// work before spending any tokens.
+type Capabilities struct {
+	Streaming    bool // Stream delivers output as it is produced rather than all at the end
+	SystemPrompt bool // Request.SystemPrompt is sent separately from the prompt
+	Tools        bool // The model can call Request.Tools
+	Images       bool // Image attachments are accepted
+	Documents    bool // PDF attachments are accepted
+	JSONMode     bool // ResponseSchema is enforced by the API, not just described in the prompt
+	Embeddings   bool // Registry.Embed works; see Embedder
+
+	ContextWindow   int // Tokens of input and output per request (0 = unknown)
+	MaxOutputTokens int // Tokens of output per request (0 = unknown)
+}
+
+// CapabilityReporter is implemented by providers that describe their
+// capabilities. Providers that do not are assumed to stream and to accept
+// system prompts and tools, as the registry has always treated them.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// modelCapabilityReporter is implemented by shared providers whose
+// capabilities depend on the model a call asks for, not only on their API.
+type modelCapabilityReporter interface {
+	capabilitiesFor(model string) Capabilities
+}
+
+// defaultCapabilities is assumed for providers that do not report their own.
+var defaultCapabilities = Capabilities{Streaming: true, SystemPrompt: true, Tools: true}
+
+// CapabilitiesOf returns the capabilities p reports, or the defaults.
+func CapabilitiesOf(p Provider) Capabilities {
+	if r, ok := p.(CapabilityReporter); ok {
+		return r.Capabilities()
+	}
+	return defaultCapabilities
+}
+
+// Badges returns short labels for the supported features and limits, for
+// display in model lists.
+func (c Capabilities) Badges() []string {
+	var badges []string
+	for _, f := range []struct {
+		ok    bool
+		label string
+	}{
+		{c.Streaming, "stream"},
+		{c.SystemPrompt, "system"},
+		{c.Tools, "tools"},
+		{c.Images, "images"},
+		{c.Documents, "pdf"},
+		{c.JSONMode, "json"},
+		{c.Embeddings, "embed"},
+	} {
+		if f.ok {
+			badges = append(badges, f.label)
+		}
+	}
+	if c.ContextWindow > 0 {
+		badges = append(badges, formatTokenCount(c.ContextWindow))
+	}
+	return badges
+}
+
+// formatTokenCount abbreviates a token count, as in "200k" or "1M".
+func formatTokenCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%gM", float64(n/100_000)/10)
+	case n >= 1000:
+		return fmt.Sprintf("%dk", n/1000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// CharsPerToken is the rough number of characters in a token of English
+// text or code, for estimates made without a model's tokenizer.
+const CharsPerToken = 4
+
+// EstimateTokens roughly counts the tokens in text.
+func EstimateTokens(text string) int {
+	return (len(text) + CharsPerToken - 1) / CharsPerToken
+}
+
+// modelLimits lists context windows and output limits by model name prefix.
+// More specific prefixes come first.
+var modelLimits = []struct {
+	prefix          string
+	contextWindow   int
+	maxOutputTokens int
+}{
+	{"claude-opus-4", 200_000, 32_000},
+	{"claude-sonnet-4", 200_000, 64_000},
+	{"claude-haiku-4", 200_000, 64_000},
+	{"claude-3-5", 200_000, 8192},
+	{"gpt-5", 400_000, 128_000},
+	{"gpt-4.1", 1_047_576, 32_768},
+	{"gpt-4o", 128_000, 16_384},
+	{"o3", 200_000, 100_000},
+	{"o4-mini", 200_000, 100_000},
+	{"gemini-", 1_048_576, 65_536},
+	{"grok-4", 256_000, 0},
+	{"grok-3", 131_072, 0},
+	{"deepseek-reasoner", 128_000, 64_000},
+	{"deepseek-chat", 128_000, 8192},
+	{"llama-3.3", 128_000, 32_768},
+	{"mistral-large", 128_000, 0},
+}
+
+// withModelLimits sets the known limits for model, or clears them when the
+// model is not listed.
+func (c Capabilities) withModelLimits(model string) Capabilities {
+	c.ContextWindow, c.MaxOutputTokens = 0, 0
+	for _, l := range modelLimits {
+		if strings.HasPrefix(model, l.prefix) {
+			c.ContextWindow, c.MaxOutputTokens = l.contextWindow, l.maxOutputTokens
+			break
+		}
+	}
+	return c
+}
+
+// openaiImageModels lists, by model name prefix, whether OpenAI models accept
+// images. More specific prefixes come first; unlisted models are assumed not
+// to.
+var openaiImageModels = []struct {
+	prefix string
+	images bool
+}{
+	{"o1-mini", false},
+	{"o1-preview", false},
+	{"o3-mini", false},
+	{"o1", true},
+	{"o3", true},
+	{"o4-mini", true},
+	{"gpt-5", true},
+	{"gpt-4.1", true},
+	{"gpt-4o", true},
+	{"gpt-4-turbo", true},
+}
+
+// openaiAcceptsImages reports whether an OpenAI model accepts images.
+func openaiAcceptsImages(model string) bool {
+	for _, m := range openaiImageModels {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.images
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+func TestRegistryCapabilities(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewAnthropicProvider(""))
+	r.Register(&flakyProvider{})
+	r.Register(NewCLIProvider(CLIProviderConfig{Name: "plain-cli", Command: "plain"}))
+	r.RegisterModels(map[string]config.ModelConfig{
+		"claude": {Provider: "anthropic", Model: "claude-opus-4-5"},
+		"local":  {Provider: "anthropic", Model: "my-finetune", ContextWindow: 32_000},
+		"flaky":  {Provider: "flaky"},
+	})
+
+	caps, err := r.Capabilities("claude")
+	if err != nil {
+		t.Fatalf("Capabilities() error = %v", err)
+	}
+	if !caps.Streaming || !caps.Tools || caps.JSONMode || caps.ContextWindow != 200_000 || caps.MaxOutputTokens != 32_000 {
+		t.Errorf("claude capabilities = %+v", caps)
+	}
+
+	// Configured limits win; unknown models have none otherwise
+	if caps, _ := r.Capabilities("local"); caps.ContextWindow != 32_000 || caps.MaxOutputTokens != 0 {
+		t.Errorf("local capabilities = %+v, want the configured context window only", caps)
+	}
+
+	// Providers that do not report capabilities get the defaults
+	if caps, _ := r.Capabilities("flaky"); caps != defaultCapabilities {
+		t.Errorf("flaky capabilities = %+v, want defaults", caps)
+	}
+
+	caps, err = r.Capabilities("plain-cli")
+	if err != nil {
+		t.Fatalf("Capabilities() error = %v", err)
+	}
+	if caps.Streaming || caps.SystemPrompt || caps.Tools {
+		t.Errorf("plain-cli capabilities = %+v, want none", caps)
+	}
+
+	if _, err := r.Capabilities("missing"); err == nil {
+		t.Error("expected error for an unknown AI ID")
+	}
+}
+
+func TestModelImageCapabilities(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewOpenAIProvider(""))
+	r.Register(NewOllamaProvider("", ""))
+	r.RegisterModels(map[string]config.ModelConfig{
+		"gpt":    {Provider: "openai", Model: "gpt-4o"},
+		"o3mini": {Provider: "openai", Model: "o3-mini"},
+		"llama":  {Provider: "ollama", Model: "llama3.2"},
+		"llava":  {Provider: "ollama", Model: "llava", Vision: true},
+	})
+
+	for id, want := range map[string]bool{"gpt": true, "o3mini": false, "llama": false, "llava": true} {
+		caps, err := r.Capabilities(id)
+		if err != nil {
+			t.Fatalf("Capabilities(%q) error = %v", id, err)
+		}
+		if caps.Images != want {
+			t.Errorf("%s accepts images = %v, want %v", id, caps.Images, want)
+		}
+	}
+}
+
+func TestCapabilityBadges(t *testing.T) {
+	caps := Capabilities{Streaming: true, Tools: true, JSONMode: true, ContextWindow: 1_048_576}
+	want := []string{"stream", "tools", "json", "1M"}
+	if got := caps.Badges(); !slices.Equal(got, want) {
+		t.Errorf("Badges() = %v, want %v", got, want)
+	}
+}
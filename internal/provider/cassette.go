@@ -0,0 +1,459 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/redact"
+)
+
+// CassetteMode selects whether a cassette records live calls or replays them.
+type CassetteMode string
+
+const (
+	CassetteRecord CassetteMode = "record"
+	CassetteReplay CassetteMode = "replay"
+)
+
+// MatchMode controls how replayed requests are matched to recorded ones.
+type MatchMode string
+
+const (
+	// MatchStrict requires each AI's requests to arrive in recorded order and
+	// match the recording exactly.
+	MatchStrict MatchMode = "strict"
+
+	// MatchLenient serves any unused recording with a matching request, and
+	// otherwise the AI's next unused recording, so prompts containing
+	// timestamps or paths still replay.
+	MatchLenient MatchMode = "lenient"
+)
+
+// ErrCassetteMiss is returned when a replayed request has no matching recording.
+var ErrCassetteMiss = errors.New("no matching cassette interaction")
+
+// Interaction is a single recorded request and its outcome.
+type Interaction struct {
+	AIID       string          `json:"ai_id"`
+	Stream     bool            `json:"stream"`
+	Key        string          `json:"key"`
+	Request    cassetteRequest `json:"request"`
+	Response   *Response       `json:"response,omitempty"`
+	Chunks     []CassetteChunk `json:"chunks,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	StatusCode int             `json:"status_code,omitempty"`
+}
+
+// cassetteRequest is the recorded form of a Request, kept for readability
+// when inspecting or hand-editing a cassette.
+type cassetteRequest struct {
+	Messages     []Message `json:"messages"`
+	SystemPrompt string    `json:"system_prompt,omitempty"`
+	MaxTokens    int       `json:"max_tokens,omitempty"`
+	Temperature  float64   `json:"temperature,omitempty"`
+}
+
+// CassetteChunk is a recorded stream chunk with the delay since the previous one.
+type CassetteChunk struct {
+	Content        string     `json:"content,omitempty"`
+	Reasoning      string     `json:"reasoning,omitempty"`
+	ToolCalls      []ToolCall `json:"tool_calls,omitempty"`
+	AgentToolCalls []ToolCall `json:"agent_tool_calls,omitempty"`
+	DelayMs        int64      `json:"delay_ms"`
+	Usage          *Usage     `json:"usage,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	Done           bool       `json:"done,omitempty"`
+}
+
+// Cassette records provider interactions to a file, or replays them from one.
+// The file holds one Interaction per line of JSON, with secrets in the text
+// redacted so a recording can be shared. It is safe for concurrent use by
+// parallel team members.
+type Cassette struct {
+	path     string
+	mode     CassetteMode
+	match    MatchMode
+	realtime bool
+	redactor *redact.Redactor // Scrubs recorded text
+
+	mu           sync.Mutex
+	Interactions []*Interaction // Loaded for replay
+	used         []bool
+	started      bool // The recording file has been truncated
+}
+
+// NewRecordingCassette creates a cassette that records to path, overwriting it
+// once the first call is recorded. Text matching redactor's patterns is
+// replaced before it is written; requests still match on replay, since
+// their keys are taken before redaction.
+func NewRecordingCassette(path string, redactor *redact.Redactor) *Cassette {
+	return &Cassette{
+		path:     path,
+		mode:     CassetteRecord,
+		redactor: redactor,
+	}
+}
+
+// LoadCassette opens a recorded cassette for replay. If realtime is set,
+// stream chunks are replayed with their recorded delays.
+func LoadCassette(path string, match MatchMode, realtime bool) (*Cassette, error) {
+	if match == "" {
+		match = MatchStrict
+	}
+	if match != MatchStrict && match != MatchLenient {
+		return nil, fmt.Errorf("invalid cassette match mode %q (want strict or lenient)", match)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+	defer f.Close()
+
+	c := &Cassette{
+		path:     path,
+		mode:     CassetteReplay,
+		match:    match,
+		realtime: realtime,
+	}
+	dec := json.NewDecoder(f)
+	for {
+		in := &Interaction{}
+		if err := dec.Decode(in); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing cassette interaction %d: %w", len(c.Interactions)+1, err)
+		}
+		c.Interactions = append(c.Interactions, in)
+	}
+	c.used = make([]bool, len(c.Interactions))
+
+	return c, nil
+}
+
+// Mode returns whether the cassette is recording or replaying.
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Wrap returns a provider that records calls to p under aiID, or replays
+// them. When replaying, p is never called and may be nil.
+func (c *Cassette) Wrap(aiID string, p Provider) Provider {
+	if c.mode == CassetteReplay {
+		return &replayProvider{name: aiID, cassette: c}
+	}
+	return &recordingProvider{Provider: p, aiID: aiID, cassette: c}
+}
+
+// Unused returns the number of recorded interactions that were never replayed.
+func (c *Cassette) Unused() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, used := range c.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// record appends an interaction to the cassette file as it happens, so a
+// crashed or cancelled session still leaves a usable recording.
+func (c *Cassette) record(in *Interaction) error {
+	data, err := json.Marshal(in.redacted(c.redactor))
+	if err != nil {
+		return fmt.Errorf("encoding cassette interaction: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	flags := os.O_CREATE | os.O_APPEND | os.O_WRONLY
+	if !c.started {
+		if dir := filepath.Dir(c.path); dir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("creating cassette directory: %w", err)
+			}
+		}
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(c.path, flags, 0600)
+	if err != nil {
+		return fmt.Errorf("opening cassette: %w", err)
+	}
+	c.started = true
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	return nil
+}
+
+// next finds the recording to serve for a request from aiID and marks it used.
+func (c *Cassette) next(aiID string, stream bool, req Request) (*Interaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cassetteKey(aiID, req)
+	first := -1
+	for i, in := range c.Interactions {
+		if c.used[i] || in.AIID != aiID {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		if in.Key == key && in.Stream == stream {
+			c.used[i] = true
+			return in, nil
+		}
+		if c.match == MatchStrict {
+			break
+		}
+	}
+
+	if c.match == MatchLenient && first >= 0 {
+		c.used[first] = true
+		return c.Interactions[first], nil
+	}
+	if first >= 0 {
+		return nil, fmt.Errorf("%w: request for %q differs from recording %d", ErrCassetteMiss, aiID, first+1)
+	}
+	return nil, fmt.Errorf("%w: no recordings left for %q", ErrCassetteMiss, aiID)
+}
+
+// cassetteKey identifies a request independently of the serving provider.
+func cassetteKey(aiID string, req Request) string {
+	return CacheKey(aiID, "", req)
+}
+
+// newInteraction starts an interaction record for a request.
+func newInteraction(aiID string, stream bool, req Request) *Interaction {
+	return &Interaction{
+		AIID:   aiID,
+		Stream: stream,
+		Key:    cassetteKey(aiID, req),
+		Request: cassetteRequest{
+			Messages:     req.Conversation(),
+			SystemPrompt: req.SystemPrompt,
+			MaxTokens:    req.MaxTokens,
+			Temperature:  req.Temperature,
+		},
+	}
+}
+
+// redacted returns a copy of in with r applied to its prompts, answers and
+// errors. Stream chunks are redacted one at a time, so a secret split across
+// two chunks is missed.
+func (in *Interaction) redacted(r *redact.Redactor) *Interaction {
+	out := *in
+	out.Request.Messages = make([]Message, len(in.Request.Messages))
+	for i, m := range in.Request.Messages {
+		m.Content = r.Redact(m.Content)
+		out.Request.Messages[i] = m
+	}
+	out.Request.SystemPrompt = r.Redact(in.Request.SystemPrompt)
+	if in.Response != nil {
+		resp := *in.Response
+		resp.Content = r.Redact(resp.Content)
+		resp.Reasoning = r.Redact(resp.Reasoning)
+		out.Response = &resp
+	}
+	out.Chunks = make([]CassetteChunk, len(in.Chunks))
+	for i, chunk := range in.Chunks {
+		chunk.Content = r.Redact(chunk.Content)
+		chunk.Reasoning = r.Redact(chunk.Reasoning)
+		chunk.Error = r.Redact(chunk.Error)
+		out.Chunks[i] = chunk
+	}
+	out.Error = r.Redact(in.Error)
+	return &out
+}
+
+// setError records a failed call, keeping the status code so replayed
+// errors classify the same way for retries and fallbacks.
+func (in *Interaction) setError(err error) {
+	in.Error = err.Error()
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		in.StatusCode = apiErr.StatusCode
+		in.Error = apiErr.Message
+	}
+}
+
+// err rebuilds a recorded error.
+func (in *Interaction) err() error {
+	if in.StatusCode != 0 {
+		return &APIError{StatusCode: in.StatusCode, Message: in.Error}
+	}
+	return errors.New(in.Error)
+}
+
+// recordingProvider passes calls through to a live provider and records them.
+type recordingProvider struct {
+	Provider
+	aiID     string
+	cassette *Cassette
+}
+
+// Invoke calls the live provider and records the outcome.
+func (p *recordingProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	in := newInteraction(p.aiID, false, req)
+
+	resp, err := p.Provider.Invoke(ctx, req)
+	if err != nil {
+		in.setError(err)
+	} else {
+		in.Response = resp
+	}
+
+	if recErr := p.cassette.record(in); recErr != nil && err == nil {
+		err = recErr
+	}
+	return resp, err
+}
+
+// Stream calls the live provider and records each chunk with its timing.
+func (p *recordingProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	in := newInteraction(p.aiID, true, req)
+
+	upstream, err := p.Provider.Stream(ctx, req)
+	if err != nil {
+		in.setError(err)
+		if recErr := p.cassette.record(in); recErr != nil {
+			return nil, fmt.Errorf("%w (recording failed: %v)", err, recErr)
+		}
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 100)
+	go func() {
+		defer close(out)
+
+		last := time.Now()
+		for chunk := range upstream {
+			now := time.Now()
+			rec := CassetteChunk{
+				Content:        chunk.Content,
+				Reasoning:      chunk.Reasoning,
+				ToolCalls:      chunk.ToolCalls,
+				AgentToolCalls: chunk.AgentToolCalls,
+				DelayMs:        now.Sub(last).Milliseconds(),
+				Usage:          chunk.Usage,
+				Done:           chunk.Done,
+			}
+			if chunk.Error != nil {
+				rec.Error = chunk.Error.Error()
+			}
+			in.Chunks = append(in.Chunks, rec)
+			last = now
+			out <- chunk
+		}
+
+		if err := p.cassette.record(in); err != nil {
+			out <- StreamChunk{Error: err, Done: true}
+		}
+	}()
+
+	return out, nil
+}
+
+// replayProvider serves calls from a cassette without touching the network.
+type replayProvider struct {
+	name     string
+	cassette *Cassette
+}
+
+// Name returns the AI ID being replayed.
+func (p *replayProvider) Name() string {
+	return p.name
+}
+
+// HealthCheck always succeeds; a replay has nothing to reach.
+func (p *replayProvider) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// Invoke returns the recorded response for req.
+func (p *replayProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	in, err := p.cassette.next(p.name, false, req)
+	if err != nil {
+		return nil, err
+	}
+	if in.Error != "" {
+		return nil, in.err()
+	}
+	if in.Response == nil {
+		// Recorded as a stream but matched leniently; assemble the content
+		resp := &Response{}
+		for _, chunk := range in.Chunks {
+			resp.Content += chunk.Content
+			resp.Reasoning += chunk.Reasoning
+			resp.ToolCalls = append(resp.ToolCalls, chunk.ToolCalls...)
+		}
+		return resp, nil
+	}
+
+	resp := *in.Response
+	return &resp, nil
+}
+
+// Stream replays the recorded chunks for req.
+func (p *replayProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	in, err := p.cassette.next(p.name, true, req)
+	if err != nil {
+		return nil, err
+	}
+	if in.Error != "" {
+		return nil, in.err()
+	}
+
+	chunks := in.Chunks
+	if len(chunks) == 0 && in.Response != nil {
+		usage := in.Response.Usage
+		chunks = []CassetteChunk{
+			{Content: in.Response.Content},
+			{Done: true, Usage: &usage},
+		}
+	}
+
+	out := make(chan StreamChunk, 100)
+	go func() {
+		defer close(out)
+		for _, rec := range chunks {
+			if p.cassette.realtime && rec.DelayMs > 0 {
+				if err := sleepContext(ctx, time.Duration(rec.DelayMs)*time.Millisecond); err != nil {
+					out <- StreamChunk{Error: err, Done: true}
+					return
+				}
+			}
+
+			chunk := StreamChunk{
+				Content:        rec.Content,
+				Reasoning:      rec.Reasoning,
+				ToolCalls:      rec.ToolCalls,
+				AgentToolCalls: rec.AgentToolCalls,
+				Done:           rec.Done,
+				Usage:          rec.Usage,
+			}
+			if rec.Error != "" {
+				chunk.Error = errors.New(rec.Error)
+			}
+			out <- chunk
+		}
+	}()
+
+	return out, nil
+}
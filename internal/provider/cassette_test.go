@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+	"github.com/jxmullins/thekanbansociety/internal/redact"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	ctx := context.Background()
+	first := "first, with DEPLOY_TOKEN=hunter22"
+
+	live := NewRegistry()
+	live.Register(&namedProvider{name: "live"})
+	live.RegisterModel("claude", config.ModelConfig{Provider: "live"})
+	redactor, _ := redact.New(nil)
+	live.SetCassette(NewRecordingCassette(path, redactor))
+
+	if _, err := live.Invoke(ctx, "claude", Request{Prompt: first}); err != nil {
+		t.Fatalf("recording Invoke() error = %v", err)
+	}
+	ch, err := live.Stream(ctx, "claude", Request{Prompt: "second"})
+	if err != nil {
+		t.Fatalf("recording Stream() error = %v", err)
+	}
+	for range ch {
+	}
+	// Each call is appended as a line of its own
+	if data, err := os.ReadFile(path); err != nil || strings.Count(string(data), "\n") != 2 {
+		t.Fatalf("cassette file = %q, %v; want a line per call", data, err)
+	} else if strings.Contains(string(data), "hunter22") {
+		t.Errorf("cassette file kept a secret: %s", data)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("cassette file mode = %v, want 0600", info.Mode())
+	}
+
+	replay := func(match MatchMode) *Registry {
+		cassette, err := LoadCassette(path, match, false)
+		if err != nil {
+			t.Fatalf("LoadCassette() error = %v", err)
+		}
+		r := NewRegistry()
+		r.SetCassette(cassette)
+		return r
+	}
+
+	// Strict replay requires the recorded order
+	strict := replay(MatchStrict)
+	if _, err := strict.Invoke(ctx, "claude", Request{Prompt: "second"}); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("out-of-order strict Invoke() error = %v, want ErrCassetteMiss", err)
+	}
+	resp, err := strict.Invoke(ctx, "claude", Request{Prompt: first})
+	if err != nil || resp.Content != "ok" {
+		t.Fatalf("strict Invoke() = %v, %v", resp, err)
+	}
+	ch, err = strict.Stream(ctx, "claude", Request{Prompt: "second"})
+	if err != nil {
+		t.Fatalf("strict Stream() error = %v", err)
+	}
+	var content string
+	for chunk := range ch {
+		content += chunk.Content
+	}
+	if content != "ok" {
+		t.Errorf("replayed stream = %q, want ok", content)
+	}
+
+	// Lenient replay serves the next recording even if the prompt changed
+	lenient := replay(MatchLenient)
+	if _, err := lenient.Invoke(ctx, "claude", Request{Prompt: "first, edited"}); err != nil {
+		t.Errorf("lenient Invoke() error = %v", err)
+	}
+}
@@ -5,9 +5,32 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+const (
+	// maxCLIEventBytes caps a single line of a CLI's JSON event stream. Events
+	// can carry whole tool results, so this is well above bufio's default.
+	maxCLIEventBytes = 16 * 1024 * 1024
+
+	// maxCLIOutputBytes caps everything a CLI writes to stdout in one call, so
+	// a runaway agent cannot exhaust memory.
+	maxCLIOutputBytes = 64 * 1024 * 1024
+
+	// maxCLIStderrBytes is how much of the end of a CLI's stderr is kept for
+	// error messages. All of it goes to the debug log.
+	maxCLIStderrBytes = 4 * 1024
+
+	// cliStopGrace is how long a cancelled CLI has to exit after SIGTERM
+	// before its process group is killed, by default.
+	cliStopGrace = 5 * time.Second
 )
 
 // CLIProvider wraps a CLI tool as a provider.
@@ -18,6 +41,17 @@ type CLIProvider struct {
 	promptFlag  string   // Flag to pass prompt (e.g., "-p")
 	systemFlag  string   // Flag for system prompt if supported
 	streamable  bool
+	eventFormat string        // JSON event stream format, empty for plain text
+	eventArgs   []string      // Args that switch the CLI to eventFormat
+	outputField string        // Text field for the jsonl format
+	stdin       bool          // Pass the prompt on stdin rather than as an argument
+	env         []string      // Extra environment, as KEY=value
+	dir         string        // Working directory
+	stopGrace   time.Duration // Time to exit after SIGTERM before SIGKILL
+
+	permissions map[PermissionProfile][]string // Flags for each permission profile
+	profile     PermissionProfile              // Selected profile
+	profileArgs []string                       // Flags for the selected profile
 }
 
 // CLIProviderConfig configures a CLI provider.
@@ -28,18 +62,113 @@ type CLIProviderConfig struct {
 	PromptFlag string
 	SystemFlag string
 	Streamable bool
+
+	// EventFormat selects a structured JSON event stream (CLIStreamClaude,
+	// CLIStreamGemini or CLIStreamCodex) enabled by EventArgs. When empty,
+	// output is read as plain text.
+	EventFormat string
+	EventArgs   []string
+	OutputField string // Dotted path to each line's text, for CLIStreamJSONL
+
+	PromptStdin bool              // Pass the prompt on stdin rather than as an argument
+	Env         map[string]string // Extra environment variables
+	Dir         string            // Working directory (default: current)
+
+	// Permissions maps each permission profile to the CLI's own allowlist or
+	// sandbox flags. The provider starts in DefaultPermissionProfile; see
+	// WithPermissions.
+	Permissions map[PermissionProfile][]string
 }
 
 // NewCLIProvider creates a new CLI-based provider.
 func NewCLIProvider(cfg CLIProviderConfig) *CLIProvider {
-	return &CLIProvider{
-		name:       cfg.Name,
-		command:    cfg.Command,
-		args:       cfg.Args,
-		promptFlag: cfg.PromptFlag,
-		systemFlag: cfg.SystemFlag,
-		streamable: cfg.Streamable,
+	p := &CLIProvider{
+		name:        cfg.Name,
+		command:     cfg.Command,
+		args:        cfg.Args,
+		promptFlag:  cfg.PromptFlag,
+		systemFlag:  cfg.SystemFlag,
+		streamable:  cfg.Streamable,
+		eventFormat: cfg.EventFormat,
+		eventArgs:   cfg.EventArgs,
+		outputField: cfg.OutputField,
+		stdin:       cfg.PromptStdin,
+		env:         envList(cfg.Env),
+		dir:         cfg.Dir,
+		permissions: cfg.Permissions,
+		stopGrace:   cliStopGrace,
 	}
+	if args, ok := cfg.Permissions[DefaultPermissionProfile]; ok {
+		p.profile = DefaultPermissionProfile
+		p.profileArgs = args
+	}
+	return p
+}
+
+// NewCLIProviderFromConfig creates a provider for a CLI declared in the
+// cli_providers section of the config.
+func NewCLIProviderFromConfig(cfg config.CLIProviderConfig) (*CLIProvider, error) {
+	if cfg.Name == "" || cfg.Command == "" {
+		return nil, fmt.Errorf("cli provider needs a name and command")
+	}
+
+	format := cfg.Output
+	switch format {
+	case "", "plain":
+		format = ""
+	case CLIStreamJSONL, CLIStreamClaude, CLIStreamGemini, CLIStreamCodex:
+	default:
+		return nil, fmt.Errorf("cli provider %q: unknown output %q", cfg.Name, cfg.Output)
+	}
+
+	var stdin bool
+	switch cfg.PromptInput {
+	case "", "argv":
+	case "stdin":
+		stdin = true
+	default:
+		return nil, fmt.Errorf("cli provider %q: prompt_input must be argv or stdin, got %q", cfg.Name, cfg.PromptInput)
+	}
+
+	var permissions map[PermissionProfile][]string
+	for name, args := range cfg.Permissions {
+		profile, err := ParsePermissionProfile(name)
+		if err != nil {
+			return nil, fmt.Errorf("cli provider %q: %w", cfg.Name, err)
+		}
+		if permissions == nil {
+			permissions = make(map[PermissionProfile][]string)
+		}
+		permissions[profile] = args
+	}
+
+	return NewCLIProvider(CLIProviderConfig{
+		Name:        cfg.Name,
+		Command:     cfg.Command,
+		Args:        cfg.Args,
+		PromptFlag:  cfg.PromptFlag,
+		SystemFlag:  cfg.SystemFlag,
+		Streamable:  true,
+		EventFormat: format,
+		OutputField: cfg.OutputField,
+		PromptStdin: stdin,
+		Env:         cfg.Env,
+		Dir:         cfg.Dir,
+		Permissions: permissions,
+	}), nil
+}
+
+// envList converts variables to KEY=value form, expanding $VAR references
+// against the current environment.
+func envList(vars map[string]string) []string {
+	if len(vars) == 0 {
+		return nil
+	}
+	env := make([]string, 0, len(vars))
+	for k, v := range vars {
+		env = append(env, k+"="+os.ExpandEnv(v))
+	}
+	return env
 }
 
 // Name returns the provider name.
@@ -52,99 +181,335 @@ func (p *CLIProvider) Models() []string {
 	return []string{p.name}
 }
 
-// Invoke calls the CLI and returns the response.
-func (p *CLIProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
-	// Validate request
-	if err := req.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+// newCommand prepares the process for a request, with the prompt on the command
+// line or stdin. The process runs in its own group, which is sent SIGTERM and
+// then SIGKILL when ctx is cancelled.
+func (p *CLIProvider) newCommand(ctx context.Context, req Request) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, p.command, p.buildArgs(req)...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return stopProcessGroup(cmd, p.stopGrace)
+	}
+	// Don't wait forever on a process that ignores cancellation or leaves
+	// its output open
+	cmd.WaitDelay = 2 * p.stopGrace
+	if p.stdin {
+		cmd.Stdin = strings.NewReader(p.prompt(req))
+	}
+	if len(p.env) > 0 {
+		cmd.Env = append(os.Environ(), p.env...)
+	}
+	cmd.Dir = p.dir
+	return cmd
+}
+
+// prompt renders a request as the CLI's single prompt. CLI tools take one
+// block of text, so multi-turn conversations are flattened into a transcript,
+// and any response schema is described at the end since CLI tools have no
+// structured output option.
+func (p *CLIProvider) prompt(req Request) string {
+	prompt := FlattenMessages(req.Conversation())
+	if req.ResponseSchema != nil {
+		prompt += "\n\n" + req.ResponseSchema.instruction()
 	}
+	return prompt
+}
 
+// buildArgs assembles the command line for a request.
+func (p *CLIProvider) buildArgs(req Request) []string {
 	args := append([]string{}, p.args...)
+	args = append(args, p.eventArgs...)
+	args = append(args, p.profileArgs...)
 
 	// Add system prompt if supported and provided
 	if p.systemFlag != "" && req.SystemPrompt != "" {
 		args = append(args, p.systemFlag, req.SystemPrompt)
 	}
 
-	// Add prompt
-	if p.promptFlag != "" {
-		args = append(args, p.promptFlag, req.Prompt)
-	} else {
-		args = append(args, req.Prompt)
+	// Add prompt, unless it goes to stdin
+	switch {
+	case p.stdin:
+	case p.promptFlag != "":
+		args = append(args, p.promptFlag, p.prompt(req))
+	default:
+		args = append(args, p.prompt(req))
 	}
 
-	cmd := exec.CommandContext(ctx, p.command, args...)
+	return args
+}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// Invoke calls the CLI and returns the response.
+func (p *CLIProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	// Validate request
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	cmd := p.newCommand(ctx, req)
+
+	stdout := &cappedBuffer{limit: maxCLIOutputBytes}
+	stderr := p.newStderr(ctx)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	stderr.flush()
+	if stdout.exceeded {
+		return nil, p.errOutputTooLarge()
+	}
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, p.exitError(err, stderr)
+	}
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("%s CLI error: %w (stderr: %s)", p.name, err, stderr.String())
+	if parse := newCLIEventParser(p.eventFormat, p.outputField); parse != nil {
+		return collectCLIEvents(&stdout.buf, parse)
 	}
 
 	return &Response{
-		Content:    strings.TrimSpace(stdout.String()),
+		Content:    strings.TrimSpace(stdout.buf.String()),
 		TokensUsed: 0, // CLI doesn't report tokens
 	}, nil
 }
 
-// Stream calls the CLI and streams the response line by line.
+// Stream calls the CLI and streams the response line by line. A CLI that
+// exits unsuccessfully ends the stream with an error chunk.
 func (p *CLIProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	args := append([]string{}, p.args...)
-
-	// Add system prompt if supported and provided
-	if p.systemFlag != "" && req.SystemPrompt != "" {
-		args = append(args, p.systemFlag, req.SystemPrompt)
-	}
-
-	// Add prompt
-	if p.promptFlag != "" {
-		args = append(args, p.promptFlag, req.Prompt)
-	} else {
-		args = append(args, req.Prompt)
-	}
-
-	cmd := exec.CommandContext(ctx, p.command, args...)
+	// Cancelling runCtx stops the process, for when we stop reading early
+	runCtx, stop := context.WithCancel(ctx)
+	cmd := p.newCommand(runCtx, req)
+	stderr := p.newStderr(ctx)
+	cmd.Stderr = stderr
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
+		stop()
 		return nil, fmt.Errorf("creating stdout pipe: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
+		stop()
 		return nil, fmt.Errorf("starting %s CLI: %w", p.name, err)
 	}
 
 	ch := make(chan StreamChunk, 10)
+	parse := newCLIEventParser(p.eventFormat, p.outputField)
 
 	go func() {
 		defer close(ch)
-		defer cmd.Wait()
+		defer stop()
 
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
+		// Sends give up once the caller has cancelled and may have stopped
+		// reading
+		send := func(chunk StreamChunk) bool {
 			select {
+			case ch <- chunk:
+				return true
 			case <-ctx.Done():
-				cmd.Process.Kill()
+				return false
+			}
+		}
+		finish := func(chunk StreamChunk) {
+			stop()
+			cmd.Wait()
+			stderr.flush()
+			send(chunk)
+		}
+
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxCLIEventBytes)
+		read := 0
+		for scanner.Scan() {
+			if read += len(scanner.Bytes()) + 1; read > maxCLIOutputBytes {
+				finish(StreamChunk{Error: p.errOutputTooLarge()})
+				return
+			}
+			chunk := StreamChunk{Content: scanner.Text() + "\n"}
+			if parse != nil {
+				var ok bool
+				if chunk, ok = parseCLIEvent(parse, scanner.Bytes()); !ok {
+					continue
+				}
+			}
+			if chunk.Error != nil {
+				finish(chunk)
+				return
+			}
+			if !send(chunk) {
+				finish(StreamChunk{Error: ctx.Err()})
 				return
-			case ch <- StreamChunk{Content: scanner.Text() + "\n"}:
 			}
 		}
 
-		if err := scanner.Err(); err != nil {
-			ch <- StreamChunk{Error: err}
+		scanErr := scanner.Err()
+		waitErr := cmd.Wait()
+		stderr.flush()
+		switch {
+		case ctx.Err() != nil:
+			send(StreamChunk{Error: ctx.Err()})
+		case scanErr != nil:
+			send(StreamChunk{Error: fmt.Errorf("reading %s CLI output: %w", p.name, scanErr)})
+		case waitErr != nil:
+			send(StreamChunk{Error: p.exitError(waitErr, stderr)})
 		}
 	}()
 
 	return ch, nil
 }
 
+// newStderr returns a writer for a call's stderr, copied to the call's debug
+// log if it has one.
+func (p *CLIProvider) newStderr(ctx context.Context) *cliStderr {
+	s := &cliStderr{}
+	if logf, ok := debugLogFrom(ctx); ok {
+		s.logf = func(line string) {
+			logf(fmt.Sprintf("%s stderr: %s", p.name, line))
+		}
+	}
+	return s
+}
+
+// exitError describes a failed run, with the end of its stderr.
+func (p *CLIProvider) exitError(err error, stderr *cliStderr) error {
+	if msg := stderr.String(); msg != "" {
+		return fmt.Errorf("%s CLI error: %w (stderr: %s)", p.name, err, msg)
+	}
+	return fmt.Errorf("%s CLI error: %w", p.name, err)
+}
+
+func (p *CLIProvider) errOutputTooLarge() error {
+	return fmt.Errorf("%s CLI output exceeded %d MiB", p.name, maxCLIOutputBytes/(1024*1024))
+}
+
+// cappedBuffer collects output up to limit bytes, failing writes beyond it so
+// the process sees a closed pipe.
+type cappedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (b *cappedBuffer) Write(data []byte) (int, error) {
+	if b.buf.Len()+len(data) > b.limit {
+		b.exceeded = true
+		return 0, errors.New("output limit exceeded")
+	}
+	return b.buf.Write(data)
+}
+
+// cliStderr keeps the end of a CLI's stderr for error messages and sends
+// each line to a debug log.
+type cliStderr struct {
+	logf    func(line string) // nil without a debug log
+	tail    []byte
+	partial []byte // Start of a line not yet logged
+}
+
+func (s *cliStderr) Write(data []byte) (int, error) {
+	s.tail = append(s.tail, data...)
+	if len(s.tail) > maxCLIStderrBytes {
+		s.tail = s.tail[len(s.tail)-maxCLIStderrBytes:]
+	}
+	if s.logf == nil {
+		return len(data), nil
+	}
+
+	s.partial = append(s.partial, data...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			break
+		}
+		s.log(s.partial[:i])
+		s.partial = s.partial[i+1:]
+	}
+	if len(s.partial) > maxCLIStderrBytes {
+		s.flush()
+	}
+	return len(data), nil
+}
+
+// flush logs any unfinished last line. Call it once the process has exited.
+func (s *cliStderr) flush() {
+	if s.logf != nil && len(s.partial) > 0 {
+		s.log(s.partial)
+		s.partial = nil
+	}
+}
+
+func (s *cliStderr) log(line []byte) {
+	if line := strings.TrimSpace(string(line)); line != "" {
+		s.logf(line)
+	}
+}
+
+// String returns the end of stderr, trimmed.
+func (s *cliStderr) String() string {
+	return strings.TrimSpace(string(s.tail))
+}
+
+// parseCLIEvent parses one line of a CLI event stream, reporting false for
+// lines that carry nothing to forward. Lines that are not JSON objects, such
+// as warnings some CLIs print, are skipped.
+func parseCLIEvent(parse func([]byte) (StreamChunk, error), line []byte) (StreamChunk, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return StreamChunk{}, false
+	}
+	chunk, err := parse(line)
+	if err != nil {
+		return StreamChunk{Error: err}, true
+	}
+	ok := chunk.Content != "" || chunk.Reasoning != "" || len(chunk.AgentToolCalls) > 0 || chunk.Done
+	return chunk, ok
+}
+
+// collectCLIEvents assembles a response from a finished CLI's event stream.
+func collectCLIEvents(stdout *bytes.Buffer, parse func([]byte) (StreamChunk, error)) (*Response, error) {
+	var content, reasoning strings.Builder
+	var usage Usage
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxCLIEventBytes)
+	for scanner.Scan() {
+		chunk, ok := parseCLIEvent(parse, scanner.Bytes())
+		if !ok {
+			continue
+		}
+		if chunk.Error != nil {
+			return nil, chunk.Error
+		}
+		content.WriteString(chunk.Content)
+		reasoning.WriteString(chunk.Reasoning)
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading CLI events: %w", err)
+	}
+
+	return &Response{
+		Content:    strings.TrimSpace(content.String()),
+		Reasoning:  reasoning.String(),
+		TokensUsed: usage.Total(),
+		Usage:      usage,
+	}, nil
+}
+
+// Capabilities reports what the CLI supports. CLI agents run their own tools
+// rather than calling ours, and get schemas described in the prompt.
+func (p *CLIProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: p.streamable, SystemPrompt: p.systemFlag != ""}
+}
+
 // HealthCheck verifies the CLI is available.
 func (p *CLIProvider) HealthCheck(ctx context.Context) error {
 	_, err := exec.LookPath(p.command)
@@ -155,44 +520,75 @@ func (p *CLIProvider) HealthCheck(ctx context.Context) error {
 }
 
 // NewClaudeCLIProvider creates a provider using the Claude Code CLI.
-// 
-// Security Note: Uses --dangerously-skip-permissions flag which bypasses
-// Claude CLI's normal permission prompts. This is necessary for automation
-// but means the CLI will execute without user confirmation. Only use in
-// trusted environments and carefully review tasks before execution.
+//
+// Security Note: The full permission profile passes
+// --dangerously-skip-permissions, which lets the CLI edit files and run
+// commands without confirmation. The default read-only profile only allows
+// tools that read files.
 func NewClaudeCLIProvider() *CLIProvider {
 	return NewCLIProvider(CLIProviderConfig{
 		Name:       "claude-cli",
 		Command:    "claude",
-		Args:       []string{"--dangerously-skip-permissions", "-p"},
+		Args:       []string{"-p"},
 		PromptFlag: "", // Prompt is positional after -p
 		SystemFlag: "--append-system-prompt",
 		Streamable: true,
+		// Partial messages give token-level text; stream-json requires --verbose with -p
+		EventFormat: CLIStreamClaude,
+		EventArgs:   []string{"--output-format", "stream-json", "--verbose", "--include-partial-messages"},
+		Permissions: map[PermissionProfile][]string{
+			ProfileReadOnly: {
+				"--allowedTools", "Read,Glob,Grep,LS",
+				"--disallowedTools", "Bash,Edit,MultiEdit,Write,NotebookEdit,WebFetch",
+			},
+			ProfileWorkspaceWrite: {
+				"--permission-mode", "acceptEdits",
+				"--allowedTools", "Read,Glob,Grep,LS,Edit,MultiEdit,Write",
+				"--disallowedTools", "Bash,WebFetch",
+			},
+			ProfileFull: {"--dangerously-skip-permissions"},
+		},
 	})
 }
 
 // NewGeminiCLIProvider creates a provider using the Gemini CLI.
 //
-// Security Note: Uses --yolo flag which may bypass certain safety checks.
-// Review the gemini CLI documentation for security implications.
+// Security Note: The full permission profile passes --yolo, which approves
+// every tool call. The default read-only profile leaves approvals on, so
+// tools that would change anything are refused in non-interactive runs.
 func NewGeminiCLIProvider() *CLIProvider {
 	return NewCLIProvider(CLIProviderConfig{
-		Name:       "gemini-cli",
-		Command:    "gemini",
-		Args:       []string{"--yolo"},
-		PromptFlag: "-p",
-		Streamable: true,
+		Name:        "gemini-cli",
+		Command:     "gemini",
+		PromptFlag:  "-p",
+		Streamable:  true,
+		EventFormat: CLIStreamGemini,
+		EventArgs:   []string{"--output-format", "stream-json"},
+		Permissions: map[PermissionProfile][]string{
+			ProfileReadOnly:       {"--approval-mode", "default"},
+			ProfileWorkspaceWrite: {"--approval-mode", "auto_edit"},
+			ProfileFull:           {"--yolo"},
+		},
 	})
 }
 
-// NewCodexCLIProvider creates a provider using the OpenAI Codex CLI.
+// NewCodexCLIProvider creates a provider using the OpenAI Codex CLI. Each
+// permission profile selects the matching codex sandbox.
 func NewCodexCLIProvider() *CLIProvider {
 	return NewCLIProvider(CLIProviderConfig{
-		Name:       "codex-cli",
-		Command:    "codex",
-		Args:       []string{"exec"},
-		PromptFlag: "", // Prompt is passed as positional arg
-		Streamable: true,
+		Name:    "codex-cli",
+		Command: "codex",
+		// Scratch working directories are not git repositories
+		Args:        []string{"exec", "--skip-git-repo-check"},
+		PromptFlag:  "", // Prompt is passed as positional arg
+		Streamable:  true,
+		EventFormat: CLIStreamCodex,
+		EventArgs:   []string{"--json"},
+		Permissions: map[PermissionProfile][]string{
+			ProfileReadOnly:       {"--sandbox", "read-only"},
+			ProfileWorkspaceWrite: {"--sandbox", "workspace-write"},
+			ProfileFull:           {"--sandbox", "danger-full-access"},
+		},
 	})
 }
 
@@ -217,3 +613,27 @@ func DetectCLIProviders() []Provider {
 
 	return providers
 }
+
+// DetectConfiguredCLIProviders returns providers for the CLIs declared in the
+// cli_providers config section whose commands are installed, in the order
+// declared. With no declarations it falls back to DetectCLIProviders. Invalid
+// declarations are reported in the error; the rest are still returned.
+func DetectConfiguredCLIProviders(cfgs []config.CLIProviderConfig) ([]Provider, error) {
+	if len(cfgs) == 0 {
+		return DetectCLIProviders(), nil
+	}
+
+	var providers []Provider
+	var errs []error
+	for _, cfg := range cfgs {
+		p, err := NewCLIProviderFromConfig(cfg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := exec.LookPath(cfg.Command); err == nil {
+			providers = append(providers, p)
+		}
+	}
+	return providers, errors.Join(errs...)
+}
@@ -0,0 +1,276 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CLI event stream formats, for CLIs that can report their progress as one
+// JSON event per line instead of plain text.
+const (
+	CLIStreamClaude = "claude-stream-json" // claude --output-format stream-json
+	CLIStreamGemini = "gemini-stream-json" // gemini --output-format stream-json
+	CLIStreamCodex  = "codex-json"         // codex exec --json
+	CLIStreamJSONL  = "jsonl"              // Any CLI printing one JSON object per line
+)
+
+// newCLIEventParser returns the line parser for a CLI event stream format,
+// or nil for plain text output. Parsers return a chunk with Done set once
+// the CLI reports its final result. For CLIStreamJSONL, field names the
+// dotted path of each line's text.
+func newCLIEventParser(format, field string) func(line []byte) (StreamChunk, error) {
+	switch format {
+	case CLIStreamJSONL:
+		return newJSONLParser(field)
+	case CLIStreamClaude:
+		return newClaudeEventParser()
+	case CLIStreamGemini:
+		return parseGeminiEvent
+	case CLIStreamCodex:
+		return parseCodexEvent
+	default:
+		return nil
+	}
+}
+
+// claudeEvent is one line of claude's stream-json output.
+type claudeEvent struct {
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+
+	// stream_event: a raw Messages API streaming event
+	Event *anthropicStreamEvent `json:"event"`
+
+	// assistant: a complete message, sent after its stream events. Decoded
+	// only for assistant events, since user events carry tool results whose
+	// content may be structured.
+	Message json.RawMessage `json:"message"`
+
+	// result: the final answer and totals for the run
+	Result       string          `json:"result"`
+	IsError      bool            `json:"is_error"`
+	TotalCostUSD float64         `json:"total_cost_usd"`
+	Usage        *anthropicUsage `json:"usage"`
+}
+
+// newClaudeEventParser parses claude's stream-json events. Text arrives
+// incrementally through stream events, so complete assistant messages only
+// contribute text that was not already streamed, plus their tool uses.
+func newClaudeEventParser() func(line []byte) (StreamChunk, error) {
+	streamed := false
+
+	return func(line []byte) (StreamChunk, error) {
+		var event claudeEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return StreamChunk{}, fmt.Errorf("parsing claude event: %w", err)
+		}
+
+		var out StreamChunk
+		switch event.Type {
+		case "stream_event":
+			if e := event.Event; e != nil && e.Type == "content_block_delta" && e.Delta != nil {
+				switch e.Delta.Type {
+				case "text_delta":
+					out.Content = e.Delta.Text
+					streamed = true
+				case "thinking_delta":
+					out.Reasoning = e.Delta.Thinking
+					streamed = true
+				}
+			}
+
+		case "assistant":
+			var message struct {
+				Content []anthropicContent `json:"content"`
+			}
+			if err := json.Unmarshal(event.Message, &message); err != nil {
+				return StreamChunk{}, fmt.Errorf("parsing claude message: %w", err)
+			}
+			for _, c := range message.Content {
+				switch c.Type {
+				case "text":
+					if !streamed {
+						out.Content += c.Text
+					}
+				case "thinking":
+					if !streamed {
+						out.Reasoning += c.Thinking
+					}
+				case "tool_use":
+					out.AgentToolCalls = append(out.AgentToolCalls, ToolCall{ID: c.ID, Name: c.Name, Arguments: string(c.Input)})
+				}
+			}
+			streamed = false
+
+		case "result":
+			if event.IsError {
+				return StreamChunk{}, fmt.Errorf("claude run failed (%s): %s", event.Subtype, event.Result)
+			}
+			out.Done = true
+			if event.Usage != nil {
+				usage := event.Usage.toUsage()
+				usage.CostUSD = event.TotalCostUSD
+				out.Usage = &usage
+			}
+		}
+		return out, nil
+	}
+}
+
+// geminiEvent is one line of gemini's stream-json output.
+type geminiEvent struct {
+	Type       string          `json:"type"`
+	Role       string          `json:"role"`
+	Content    string          `json:"content"`
+	ToolName   string          `json:"tool_name"`
+	ToolID     string          `json:"tool_id"`
+	Parameters json.RawMessage `json:"parameters"`
+	Status     string          `json:"status"`
+	Error      *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Stats *struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		Cached       int `json:"cached"`
+	} `json:"stats"`
+}
+
+// parseGeminiEvent parses gemini's stream-json events.
+func parseGeminiEvent(line []byte) (StreamChunk, error) {
+	var event geminiEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		return StreamChunk{}, fmt.Errorf("parsing gemini event: %w", err)
+	}
+
+	var out StreamChunk
+	switch event.Type {
+	case "message":
+		if event.Role == RoleAssistant {
+			out.Content = event.Content
+		}
+	case "tool_use":
+		out.AgentToolCalls = []ToolCall{{ID: event.ToolID, Name: event.ToolName, Arguments: string(event.Parameters)}}
+	case "error":
+		if event.Error != nil {
+			return StreamChunk{}, errors.New(event.Error.Message)
+		}
+	case "result":
+		if event.Status != "" && event.Status != "success" {
+			msg := event.Status
+			if event.Error != nil {
+				msg = event.Error.Message
+			}
+			return StreamChunk{}, fmt.Errorf("gemini run failed: %s", msg)
+		}
+		out.Done = true
+		if event.Stats != nil {
+			out.Usage = &Usage{
+				InputTokens:  event.Stats.InputTokens,
+				OutputTokens: event.Stats.OutputTokens,
+				CachedTokens: event.Stats.Cached,
+			}
+		}
+	}
+	return out, nil
+}
+
+// codexEvent is one line of codex exec's --json output.
+type codexEvent struct {
+	Type string `json:"type"`
+	Item *struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Text    string `json:"text"`
+		Command string `json:"command"`
+		Server  string `json:"server"`
+		Tool    string `json:"tool"`
+		Query   string `json:"query"`
+	} `json:"item"`
+	Usage *struct {
+		InputTokens       int `json:"input_tokens"`
+		CachedInputTokens int `json:"cached_input_tokens"`
+		OutputTokens      int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// parseCodexEvent parses codex's JSON events. Codex reports whole items
+// rather than token deltas, so text arrives one message at a time.
+func parseCodexEvent(line []byte) (StreamChunk, error) {
+	var event codexEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		return StreamChunk{}, fmt.Errorf("parsing codex event: %w", err)
+	}
+
+	var out StreamChunk
+	switch event.Type {
+	case "item.started":
+		// Report commands as they start; their output follows on completion
+		if item := event.Item; item != nil && item.Type == "command_execution" {
+			out.AgentToolCalls = []ToolCall{{ID: item.ID, Name: "shell", Arguments: item.Command}}
+		}
+	case "item.completed":
+		item := event.Item
+		if item == nil {
+			break
+		}
+		switch item.Type {
+		case "agent_message":
+			out.Content = item.Text
+		case "reasoning":
+			out.Reasoning = item.Text
+		case "mcp_tool_call":
+			out.AgentToolCalls = []ToolCall{{ID: item.ID, Name: item.Server + "." + item.Tool}}
+		case "web_search":
+			out.AgentToolCalls = []ToolCall{{ID: item.ID, Name: "web_search", Arguments: item.Query}}
+		case "file_change":
+			out.AgentToolCalls = []ToolCall{{ID: item.ID, Name: "file_change"}}
+		}
+	case "turn.completed":
+		out.Done = true
+		if event.Usage != nil {
+			out.Usage = &Usage{
+				InputTokens:  event.Usage.InputTokens,
+				OutputTokens: event.Usage.OutputTokens,
+				CachedTokens: event.Usage.CachedInputTokens,
+			}
+		}
+	case "turn.failed":
+		if event.Error != nil {
+			return StreamChunk{}, fmt.Errorf("codex run failed: %s", event.Error.Message)
+		}
+		return StreamChunk{}, errors.New("codex run failed")
+	}
+	return out, nil
+}
+
+// newJSONLParser reads the text at a dotted field path, such as
+// "message.content", from each line. Lines without it are skipped, and the
+// stream ends when the CLI exits.
+func newJSONLParser(field string) func(line []byte) (StreamChunk, error) {
+	if field == "" {
+		field = "text"
+	}
+	path := strings.Split(field, ".")
+
+	return func(line []byte) (StreamChunk, error) {
+		var value any
+		if err := json.Unmarshal(line, &value); err != nil {
+			return StreamChunk{}, fmt.Errorf("parsing JSON line: %w", err)
+		}
+		for _, key := range path {
+			obj, ok := value.(map[string]any)
+			if !ok {
+				return StreamChunk{}, nil
+			}
+			value = obj[key]
+		}
+		text, _ := value.(string)
+		return StreamChunk{Content: text}, nil
+	}
+}
@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+// claudeEvents is a trimmed stream-json transcript of a run that reads a
+// file before answering.
+const claudeEvents = `{"type":"system","subtype":"init","session_id":"s1"}
+{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}}
+{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}}
+{"type":"assistant","message":{"content":[{"type":"text","text":"Let me check."},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"go.mod"}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"module x"}]}]}}
+warning: not an event
+{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" Done."}}}
+{"type":"assistant","message":{"content":[{"type":"text","text":" Done."}]}}
+{"type":"result","subtype":"success","is_error":false,"result":"Let me check. Done.","total_cost_usd":0.0123,"usage":{"input_tokens":10,"cache_read_input_tokens":90,"output_tokens":7}}
+`
+
+// newEchoCLIProvider returns a CLI provider whose command prints output,
+// standing in for a real CLI. The prompt is passed but ignored.
+func newEchoCLIProvider(format, output string) *CLIProvider {
+	return NewCLIProvider(CLIProviderConfig{
+		Name:        "echo-cli",
+		Command:     "sh",
+		Args:        []string{"-c", "cat <<'EOF'\n" + output + "\nEOF", "sh"},
+		EventFormat: format,
+	})
+}
+
+func TestCLIProviderStreamClaudeEvents(t *testing.T) {
+	p := newEchoCLIProvider(CLIStreamClaude, claudeEvents)
+
+	ch, err := p.Stream(context.Background(), Request{Prompt: "check go.mod"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	var content strings.Builder
+	var tools []ToolCall
+	var final StreamChunk
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected error: %v", chunk.Error)
+		}
+		content.WriteString(chunk.Content)
+		tools = append(tools, chunk.AgentToolCalls...)
+		if chunk.Done {
+			final = chunk
+		}
+	}
+
+	if content.String() != "Let me check. Done." {
+		t.Errorf("content = %q, want streamed text without repeats", content.String())
+	}
+	if len(tools) != 1 || tools[0].Name != "Read" || tools[0].Arguments != `{"file_path":"go.mod"}` {
+		t.Errorf("agent tool calls = %+v", tools)
+	}
+	want := Usage{InputTokens: 100, OutputTokens: 7, CachedTokens: 90, CostUSD: 0.0123}
+	if final.Usage == nil || *final.Usage != want {
+		t.Errorf("final usage = %+v, want %+v", final.Usage, want)
+	}
+}
+
+func TestCLIProviderInvokeCodexEvents(t *testing.T) {
+	events := `{"type":"thread.started","thread_id":"th"}
+{"type":"item.started","item":{"id":"i1","type":"command_execution","command":"ls"}}
+{"type":"item.completed","item":{"id":"i2","type":"agent_message","text":"Two files."}}
+{"type":"turn.completed","usage":{"input_tokens":50,"cached_input_tokens":20,"output_tokens":4}}
+`
+	p := newEchoCLIProvider(CLIStreamCodex, events)
+
+	resp, err := p.Invoke(context.Background(), Request{Prompt: "list files"})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if resp.Content != "Two files." || resp.Usage.Total() != 54 {
+		t.Errorf("response = %+v", resp)
+	}
+
+	failed := newEchoCLIProvider(CLIStreamCodex, `{"type":"turn.failed","error":{"message":"quota"}}`)
+	if _, err := failed.Invoke(context.Background(), Request{Prompt: "x"}); err == nil || !strings.Contains(err.Error(), "quota") {
+		t.Errorf("Invoke() error = %v, want the turn failure", err)
+	}
+}
+
+func TestCLIProviderStreamExitStatus(t *testing.T) {
+	p := NewCLIProvider(CLIProviderConfig{
+		Name:    "failing-cli",
+		Command: "sh",
+		Args:    []string{"-c", "echo partial; echo 'rate limited' >&2; exit 3", "sh"},
+	})
+	var logged []string
+	ctx := context.WithValue(context.Background(), debugLogKey{}, func(message string) {
+		logged = append(logged, message)
+	})
+
+	ch, err := p.Stream(ctx, Request{Prompt: "x"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var content strings.Builder
+	var final StreamChunk
+	for chunk := range ch {
+		content.WriteString(chunk.Content)
+		final = chunk
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(final.Error, &exitErr) || exitErr.ExitCode() != 3 || !strings.Contains(final.Error.Error(), "rate limited") {
+		t.Errorf("final chunk error = %v, want exit status 3 with stderr", final.Error)
+	}
+	if content.String() != "partial\n" {
+		t.Errorf("content = %q", content.String())
+	}
+	if len(logged) != 1 || logged[0] != "failing-cli stderr: rate limited" {
+		t.Errorf("debug log = %q", logged)
+	}
+}
+
+func TestCLIProviderFromConfigStdinJSONL(t *testing.T) {
+	p, err := NewCLIProviderFromConfig(config.CLIProviderConfig{
+		Name:        "reader",
+		Command:     "sh",
+		Args:        []string{"-c", `read -r prompt; printf '{"msg":{"text":"%s, %s"}}\n{"other":1}\n' "$GREETING" "$prompt"`},
+		PromptInput: "stdin",
+		Env:         map[string]string{"GREETING": "hello"},
+		Output:      "jsonl",
+		OutputField: "msg.text",
+	})
+	if err != nil {
+		t.Fatalf("NewCLIProviderFromConfig() error = %v", err)
+	}
+
+	resp, err := p.Invoke(context.Background(), Request{Prompt: "world"})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if resp.Content != "hello, world" {
+		t.Errorf("Content = %q, want text read from stdin and env", resp.Content)
+	}
+
+	if _, err := NewCLIProviderFromConfig(config.CLIProviderConfig{Name: "x", Command: "x", Output: "xml"}); err == nil {
+		t.Error("expected error for unknown output format")
+	}
+}
+
+func TestCLIProviderPermissionProfiles(t *testing.T) {
+	claude := NewClaudeCLIProvider()
+	args := strings.Join(claude.buildArgs(Request{Prompt: "hi"}), " ")
+	if strings.Contains(args, "--dangerously-skip-permissions") || !strings.Contains(args, "--allowedTools Read,Glob,Grep,LS") {
+		t.Errorf("default args = %q, want the read-only profile", args)
+	}
+
+	full, err := claude.WithPermissions(ProfileFull, "/tmp/scratch")
+	if err != nil {
+		t.Fatalf("WithPermissions() error = %v", err)
+	}
+	cmd := full.newCommand(context.Background(), Request{Prompt: "hi"})
+	if !strings.Contains(strings.Join(cmd.Args, " "), "--dangerously-skip-permissions") || cmd.Dir != "/tmp/scratch" {
+		t.Errorf("full profile command = %v in %q", cmd.Args, cmd.Dir)
+	}
+
+	partial, err := NewCLIProviderFromConfig(config.CLIProviderConfig{
+		Name:        "partial",
+		Command:     "tool",
+		Permissions: map[string][]string{"read-only": {"--safe"}},
+	})
+	if err != nil {
+		t.Fatalf("NewCLIProviderFromConfig() error = %v", err)
+	}
+	if _, err := partial.WithPermissions(ProfileWorkspaceWrite, ""); err == nil {
+		t.Error("expected error for a profile the CLI does not declare")
+	}
+
+	undeclared, _ := NewCLIProviderFromConfig(config.CLIProviderConfig{
+		Name:    "aider",
+		Command: "aider",
+		Args:    []string{"--yes-always"},
+		Dir:     "/work/repo",
+	})
+	if _, err := undeclared.WithPermissions(ProfileReadOnly, "/tmp/scratch"); err == nil {
+		t.Error("expected error for read-only on a CLI that declares no permission flags")
+	}
+	unrestricted, err := undeclared.WithPermissions(ProfileFull, "/tmp/scratch")
+	if err != nil {
+		t.Fatalf("WithPermissions(full) error = %v", err)
+	}
+	if unrestricted.dir != "/work/repo" {
+		t.Errorf("dir = %q, want the configured directory kept", unrestricted.dir)
+	}
+}
@@ -0,0 +1,233 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+const (
+	// defaultAuthCommandTTL is how long an auth_command's output is reused.
+	defaultAuthCommandTTL = 5 * time.Minute
+
+	// authCommandTimeout bounds an auth_command, which may prompt a password
+	// manager or refresh a token.
+	authCommandTimeout = 30 * time.Second
+)
+
+// Credential supplies the API key a provider sends.
+type Credential interface {
+	// Key returns the API key, or an error saying why there is none. ctx
+	// bounds any work fetching it.
+	Key(ctx context.Context) (string, error)
+
+	// Source describes where the key comes from, for display. It never
+	// includes the key itself.
+	Source() string
+}
+
+// EnvCredential reads the API key from an environment variable.
+type EnvCredential string
+
+// Key returns the variable's value.
+func (c EnvCredential) Key(ctx context.Context) (string, error) {
+	if key := os.Getenv(string(c)); key != "" {
+		return key, nil
+	}
+	return "", fmt.Errorf("please set %s environment variable", string(c))
+}
+
+// Source names the variable.
+func (c EnvCredential) Source() string {
+	return "env $" + string(c)
+}
+
+// FileCredential reads the API key from a file holding only the key.
+type FileCredential string
+
+// Key returns the file's contents, trimmed of surrounding whitespace.
+func (c FileCredential) Key(ctx context.Context) (string, error) {
+	data, err := os.ReadFile(expandHome(string(c)))
+	if err != nil {
+		return "", fmt.Errorf("reading key file: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("key file %s is empty", string(c))
+	}
+	return key, nil
+}
+
+// Source names the file.
+func (c FileCredential) Source() string {
+	return "file " + string(c)
+}
+
+// DotenvCredential reads the API key from a variable in a .env file.
+type DotenvCredential struct {
+	Path string
+	Var  string
+}
+
+// Key returns the variable's value from the file.
+func (c DotenvCredential) Key(ctx context.Context) (string, error) {
+	data, err := os.ReadFile(expandHome(c.Path))
+	if err != nil {
+		return "", fmt.Errorf("reading dotenv file: %w", err)
+	}
+	if key := parseDotenv(data)[c.Var]; key != "" {
+		return key, nil
+	}
+	return "", fmt.Errorf("%s is not set in %s", c.Var, c.Path)
+}
+
+// Source names the file and variable.
+func (c DotenvCredential) Source() string {
+	return fmt.Sprintf("dotenv %s ($%s)", c.Path, c.Var)
+}
+
+// CommandCredential runs a local command, such as a password manager lookup,
+// and uses its output as the API key. Output is cached for TTL so the command
+// is not run on every call. Create one with NewCommandCredential.
+type CommandCredential struct {
+	Command string // Run with sh -c
+	TTL     time.Duration
+
+	running chan struct{} // Held while checking the cache or running the command
+	key     string
+	expires time.Time
+}
+
+// NewCommandCredential creates a credential from command's output, cached for
+// ttl (0 uses a default of five minutes).
+func NewCommandCredential(command string, ttl time.Duration) *CommandCredential {
+	if ttl <= 0 {
+		ttl = defaultAuthCommandTTL
+	}
+	return &CommandCredential{Command: command, TTL: ttl, running: make(chan struct{}, 1)}
+}
+
+// Key returns the cached key, running the command if the cache has expired.
+// Calls waiting on another call's command give up when ctx is done.
+func (c *CommandCredential) Key(ctx context.Context) (string, error) {
+	select {
+	case c.running <- struct{}{}:
+	case <-ctx.Done():
+		return "", fmt.Errorf("waiting for auth command: %w", ctx.Err())
+	}
+	defer func() { <-c.running }()
+	if c.key != "" && time.Now().Before(c.expires) {
+		return c.key, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, authCommandTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Command)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // Don't wait on children still holding the output
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("running auth command: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("running auth command: %w", err)
+	}
+	key := strings.TrimSpace(string(out))
+	if key == "" {
+		return "", fmt.Errorf("auth command printed no key")
+	}
+
+	c.key = key
+	c.expires = time.Now().Add(c.TTL)
+	return key, nil
+}
+
+// Source names the command's program, leaving out its arguments, which may
+// name a vault item or carry a token.
+func (c *CommandCredential) Source() string {
+	name := c.Command
+	if fields := strings.Fields(name); len(fields) > 0 {
+		name = filepath.Base(fields[0])
+	}
+	return "command `" + name + "`"
+}
+
+// CredentialFromConfig returns the credential a model configures, or nil if
+// it configures none. auth_command takes precedence, then auth_file, then
+// auth_dotenv, then auth_env_var. A dotenv file is read for auth_env_var, or
+// defaultEnv if that is unset.
+func CredentialFromConfig(cfg config.ModelConfig, defaultEnv string) Credential {
+	switch {
+	case cfg.AuthCommand != "":
+		return NewCommandCredential(cfg.AuthCommand, time.Duration(cfg.AuthCommandTTL)*time.Second)
+	case cfg.AuthFile != "":
+		return FileCredential(cfg.AuthFile)
+	case cfg.AuthDotenv != "":
+		name := cfg.AuthEnvVar
+		if name == "" {
+			name = defaultEnv
+		}
+		return DotenvCredential{Path: cfg.AuthDotenv, Var: name}
+	case cfg.AuthEnvVar != "":
+		return EnvCredential(cfg.AuthEnvVar)
+	}
+	return nil
+}
+
+// CredentialSource describes where the provider behind aiID gets its API key,
+// or returns "" if it needs none.
+func (r *Registry) CredentialSource(aiID string) string {
+	p, _, err := r.lookup(aiID)
+	if err != nil {
+		return ""
+	}
+	if c, ok := p.(configurable); ok {
+		return c.CredentialSource()
+	}
+	return ""
+}
+
+// parseDotenv reads KEY=VALUE lines, skipping comments and blank lines and
+// stripping an "export " prefix and matching quotes.
+func parseDotenv(data []byte) map[string]string {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		vars[strings.TrimSpace(name)] = value
+	}
+	return vars
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + string(os.PathSeparator) + rest
+		}
+	}
+	return path
+}
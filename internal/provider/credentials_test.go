@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+func TestCredentialSources(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	os.WriteFile(keyFile, []byte("file-key\n"), 0600)
+	dotenv := filepath.Join(dir, ".env")
+	os.WriteFile(dotenv, []byte("# keys\nexport ANTHROPIC_API_KEY=\"dotenv-key\"\nOTHER=x # note\n"), 0600)
+	t.Setenv("TEAM_KEY", "env-key")
+
+	tests := []struct {
+		name   string
+		cfg    config.ModelConfig
+		key    string
+		source string
+	}{
+		{"env", config.ModelConfig{AuthEnvVar: "TEAM_KEY"}, "env-key", "env $TEAM_KEY"},
+		{"file", config.ModelConfig{AuthFile: keyFile, AuthEnvVar: "TEAM_KEY"}, "file-key", "file " + keyFile},
+		{"dotenv default var", config.ModelConfig{AuthDotenv: dotenv}, "dotenv-key", "dotenv " + dotenv + " ($ANTHROPIC_API_KEY)"},
+		{"dotenv comment", config.ModelConfig{AuthDotenv: dotenv, AuthEnvVar: "OTHER"}, "x", "dotenv " + dotenv + " ($OTHER)"},
+		{"command", config.ModelConfig{AuthCommand: "echo command-key"}, "command-key", "command `echo`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := CredentialFromConfig(tt.cfg, "ANTHROPIC_API_KEY")
+			key, err := c.Key(context.Background())
+			if err != nil {
+				t.Fatalf("Key() error = %v", err)
+			}
+			if key != tt.key || c.Source() != tt.source {
+				t.Errorf("Key(), Source() = %q, %q; want %q, %q", key, c.Source(), tt.key, tt.source)
+			}
+		})
+	}
+
+	if _, err := FileCredential(filepath.Join(dir, "missing")).Key(context.Background()); err == nil {
+		t.Error("expected error for a missing key file")
+	}
+	if _, err := NewCommandCredential("exit 3", 0).Key(context.Background()); err == nil {
+		t.Error("expected error for a failing auth command")
+	}
+}
+
+func TestCommandCredentialCaches(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "runs")
+	c := NewCommandCredential("echo run >> "+counter+"; echo key", time.Hour)
+	for range 3 {
+		if key, err := c.Key(context.Background()); err != nil || key != "key" {
+			t.Fatalf("Key() = %q, %v", key, err)
+		}
+	}
+	if data, _ := os.ReadFile(counter); strings.Count(string(data), "run") != 1 {
+		t.Errorf("auth command ran %d times, want once", strings.Count(string(data), "run"))
+	}
+
+	// Calls give up with their context, whether the command is theirs or
+	// another call's
+	slow := NewCommandCredential("sleep 5; echo key", 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := slow.Key(ctx); err == nil || time.Since(start) > 2*time.Second {
+		t.Errorf("Key() = %v after %s, want an error once the context ends", err, time.Since(start))
+	}
+	slow.running <- struct{}{}
+	if _, err := slow.Key(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Key() while another call runs the command error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestRegisterDedicatedProviders(t *testing.T) {
+	t.Setenv("WORK_ANTHROPIC_KEY", "work")
+	t.Setenv("ANTHROPIC_API_KEY", "personal")
+
+	models := map[string]config.ModelConfig{
+		"claude":      {Provider: "anthropic", Model: "claude-sonnet-4-5-20250929"},
+		"claude-work": {Provider: "anthropic", Model: "claude-opus-4-5-20250929", AuthEnvVar: "WORK_ANTHROPIC_KEY"},
+	}
+	r := NewRegistry()
+	r.Register(NewAnthropicProvider(""))
+	r.RegisterModels(models)
+	if err := r.RegisterDedicatedProviders(models); err != nil {
+		t.Fatalf("RegisterDedicatedProviders() error = %v", err)
+	}
+
+	shared, _, _ := r.GetForModel("claude")
+	work, _, _ := r.GetForModel("claude-work")
+	if shared.(*AnthropicProvider).GetAPIKey(context.Background()) != "personal" || work.(*AnthropicProvider).GetAPIKey(context.Background()) != "work" {
+		t.Error("models on the same provider share a key")
+	}
+	if got := r.CredentialSource("claude-work"); got != "env $WORK_ANTHROPIC_KEY" {
+		t.Errorf("CredentialSource(claude-work) = %q", got)
+	}
+	if got := r.CredentialSource("claude"); got != "env $ANTHROPIC_API_KEY" {
+		t.Errorf("CredentialSource(claude) = %q", got)
+	}
+
+	// A bad model is reported without keeping the others on the shared provider
+	mixed := map[string]config.ModelConfig{
+		"a-demo":    {Provider: ScriptedProviderName, AuthFile: "key"},
+		"b-work":    {Provider: "anthropic", AuthEnvVar: "WORK_ANTHROPIC_KEY"},
+		"c-nowhere": {Provider: GenericProviderName},
+	}
+	r.RegisterModels(mixed)
+	err := r.RegisterDedicatedProviders(mixed)
+	if err == nil || !strings.Contains(err.Error(), "a-demo") || !strings.Contains(err.Error(), "c-nowhere") {
+		t.Errorf("RegisterDedicatedProviders() error = %v, want both bad models", err)
+	}
+	if got := r.CredentialSource("b-work"); got != "env $WORK_ANTHROPIC_KEY" {
+		t.Errorf("CredentialSource(b-work) = %q, want its own key despite the errors", got)
+	}
+}
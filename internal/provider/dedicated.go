@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+// GenericProviderName is the provider value for any other OpenAI-compatible
+// server, such as an internal LLM gateway, reached at the model's endpoint.
+const GenericProviderName = "generic"
+
+// configurable is implemented by providers built on BaseProvider.
+type configurable interface {
+	SetCredential(Credential)
+	CredentialSource() string
+	ConfigureHTTP(cfg config.HTTPConfig) error
+	keyEnv() string
+}
+
+// RegisterDedicatedProviders gives every model that needs settings of its
+// own a provider of its own: models with their own credential or HTTP
+// settings, so models on the same provider can use different keys and
+// networks, and generic models, which each have their own endpoint. Every
+// model is tried; the error reports all that failed.
+func (r *Registry) RegisterDedicatedProviders(models map[string]config.ModelConfig) error {
+	var errs []error
+	for _, aiID := range slices.Sorted(maps.Keys(models)) {
+		if err := r.registerDedicatedProvider(aiID, models[aiID]); err != nil {
+			errs = append(errs, fmt.Errorf("model %q: %w", aiID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// registerDedicatedProvider gives one model its own provider, if it needs one.
+func (r *Registry) registerDedicatedProvider(aiID string, cfg config.ModelConfig) error {
+	if !needsDedicatedProvider(cfg) {
+		return nil
+	}
+	if cfg.Provider == GenericProviderName && cfg.Endpoint == "" {
+		return fmt.Errorf("generic models need an endpoint")
+	}
+	p, ok := newAPIProvider(cfg)
+	if !ok {
+		return fmt.Errorf("provider %q does not take credentials or HTTP settings", cfg.Provider)
+	}
+
+	c := p.(configurable)
+	if hasCredential(cfg) {
+		if cfg.Provider == "ollama" {
+			return fmt.Errorf("ollama does not take an API key")
+		}
+		c.SetCredential(CredentialFromConfig(cfg, c.keyEnv()))
+	}
+	if !cfg.HTTP.IsZero() {
+		if err := c.ConfigureHTTP(cfg.HTTP); err != nil {
+			return err
+		}
+	}
+	r.RegisterModelProvider(aiID, p)
+	return nil
+}
+
+// needsDedicatedProvider reports whether a model cannot share its provider's
+// registered instance.
+func needsDedicatedProvider(cfg config.ModelConfig) bool {
+	return cfg.Provider == GenericProviderName || hasCredential(cfg) || !cfg.HTTP.IsZero()
+}
+
+// hasCredential reports whether a model configures its own credential.
+func hasCredential(cfg config.ModelConfig) bool {
+	return cfg.AuthCommand != "" || cfg.AuthFile != "" || cfg.AuthDotenv != "" || cfg.AuthEnvVar != ""
+}
+
+// newAPIProvider creates a provider of its own for a configured model.
+func newAPIProvider(cfg config.ModelConfig) (Provider, bool) {
+	switch cfg.Provider {
+	case "anthropic":
+		return NewAnthropicProvider(cfg.Model), true
+	case "openai":
+		return NewOpenAIProvider(cfg.Model), true
+	case "google":
+		return NewGoogleProvider(cfg.Model), true
+	case "groq":
+		return NewGroqProvider(cfg.Model), true
+	case "deepseek":
+		return NewDeepSeekProvider(cfg.Model), true
+	case "mistral":
+		return NewMistralProvider(cfg.Model), true
+	case "xai":
+		return NewXAIProvider(cfg.Model), true
+	case "ollama":
+		return NewOllamaProvider(cfg.Model, cfg.Endpoint), true
+	case "lmstudio":
+		return NewLMStudioProvider(cfg.Model, cfg.Endpoint), true
+	case GenericProviderName:
+		return NewGenericProvider(GenericConfig{Endpoint: cfg.Endpoint, Model: cfg.Model}), true
+	}
+	return nil, false
+}
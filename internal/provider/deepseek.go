@@ -16,11 +16,13 @@ func NewDeepSeekProvider(model string) *DeepSeekProvider {
 
 	return &DeepSeekProvider{
 		OpenAICompatProvider: NewOpenAICompatProvider(OpenAICompatConfig{
-			Name:      "deepseek",
-			APIKeyEnv: "DEEPSEEK_API_KEY",
-			Endpoint:  deepseekAPIEndpoint,
-			Model:     model,
-			MaxTokens: 8192,
+			Name:        "deepseek",
+			APIKeyEnv:   "DEEPSEEK_API_KEY",
+			Endpoint:    deepseekAPIEndpoint,
+			Model:       model,
+			MaxTokens:   8192,
+			StreamUsage: true,
+			JSONMode:    "json_object",
 		}),
 	}
 }
@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Embedder is implemented by providers that turn text into vectors, for
+// comparing arguments, detecting consensus and finding related sessions.
+type Embedder interface {
+	Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error)
+}
+
+// EmbedRequest holds texts to embed in one call.
+type EmbedRequest struct {
+	Texts      []string
+	Model      string // Embedding model; empty uses the provider's default
+	Dimensions int    // Truncate vectors to this size where supported (0 = model default)
+}
+
+// EmbedResponse holds one vector per requested text, in order.
+type EmbedResponse struct {
+	Vectors [][]float32
+	Model   string
+	Usage   Usage
+}
+
+// Validate checks that the request has something to embed.
+func (r *EmbedRequest) Validate() error {
+	if len(r.Texts) == 0 {
+		return fmt.Errorf("no texts to embed")
+	}
+	for i, t := range r.Texts {
+		if t == "" {
+			return fmt.Errorf("text %d is empty", i+1)
+		}
+	}
+	return nil
+}
+
+// Embed embeds texts with the provider behind aiID, using the model's
+// configured embedding_model if it has one. Embeddings go straight to the
+// provider, bypassing fallbacks, rate limits, middleware (and so the audit
+// log), the response cache and cassettes.
+func (r *Registry) Embed(ctx context.Context, aiID string, texts ...string) (*EmbedResponse, error) {
+	p, modelCfg, err := r.lookup(aiID)
+	if err != nil {
+		return nil, err
+	}
+	embedder, ok := p.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support embeddings", aiID)
+	}
+
+	req := EmbedRequest{Texts: texts, Model: modelCfg.EmbeddingModel}
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid embed request: %w", err)
+	}
+
+	resp, err := embedder.Embed(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding with %s: %w", aiID, err)
+	}
+	if len(resp.Vectors) != len(texts) {
+		return nil, fmt.Errorf("embedding with %s: got %d vectors for %d texts", aiID, len(resp.Vectors), len(texts))
+	}
+	return resp, nil
+}
+
+// openaiEmbeddingRequest is the body of an OpenAI-style /embeddings call.
+type openaiEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// openaiEmbeddingResponse is the reply to an /embeddings call.
+type openaiEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Model string      `json:"model"`
+	Usage openaiUsage `json:"usage"`
+}
+
+// embedOpenAI calls an OpenAI-style embeddings endpoint, shared by OpenAI
+// and the compatible servers.
+func (b *BaseProvider) embedOpenAI(ctx context.Context, url string, headers map[string]string, model string, req EmbedRequest) (*EmbedResponse, error) {
+	apiReq := openaiEmbeddingRequest{Model: model, Input: req.Texts, Dimensions: req.Dimensions}
+	resp, err := b.DoRequest(ctx, http.MethodPost, url, apiReq, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp openaiErrorResponse
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+			return nil, NewAPIError(resp, errResp.Error.Message)
+		}
+		return nil, NewAPIError(resp, string(body))
+	}
+
+	var apiResp openaiEmbeddingResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	// Vectors may arrive out of order; index says which text each belongs to
+	vectors := make([][]float32, len(req.Texts))
+	for _, d := range apiResp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return &EmbedResponse{Vectors: vectors, Model: apiResp.Model, Usage: apiResp.Usage.toUsage()}, nil
+}
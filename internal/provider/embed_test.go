@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+func TestRegistryEmbed(t *testing.T) {
+	// One server stands in for both Ollama and an OpenAI-compatible endpoint
+	var models []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/embed", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string
+			Input []string
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		models = append(models, req.Model)
+		vectors := make([][]float32, len(req.Input))
+		for i := range vectors {
+			vectors[i] = []float32{float32(i), 1}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"model": req.Model, "embeddings": vectors, "prompt_eval_count": 6})
+	})
+	mux.HandleFunc("POST /v1/embeddings", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		models = append(models, req.Model)
+		// Out of order, as the API allows
+		w.Write([]byte(`{"model":"` + req.Model + `","data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}],"usage":{"prompt_tokens":4}}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	r := NewRegistry()
+	r.Register(NewOllamaProvider("", srv.URL))
+	r.Register(NewGenericProvider(GenericConfig{Name: "local", Endpoint: srv.URL + "/v1"}))
+	r.Register(NewAnthropicProvider(""))
+	r.RegisterModels(map[string]config.ModelConfig{
+		"ollama":   {Provider: "ollama"},
+		"local":    {Provider: "local", EmbeddingModel: "bge-m3"},
+		"no-model": {Provider: "local"},
+		"claude":   {Provider: "anthropic"},
+	})
+	ctx := context.Background()
+
+	resp, err := r.Embed(ctx, "ollama", "tabs", "spaces")
+	if err != nil {
+		t.Fatalf("Embed(ollama) error = %v", err)
+	}
+	if len(resp.Vectors) != 2 || resp.Vectors[1][0] != 1 || resp.Usage.InputTokens != 6 {
+		t.Errorf("Embed(ollama) = %+v", resp)
+	}
+
+	resp, err = r.Embed(ctx, "local", "tabs", "spaces")
+	if err != nil {
+		t.Fatalf("Embed(local) error = %v", err)
+	}
+	if resp.Vectors[0][0] != 1 || resp.Vectors[1][1] != 1 || resp.Usage.InputTokens != 4 {
+		t.Errorf("Embed(local) = %+v, want vectors in input order", resp)
+	}
+	if strings.Join(models, ",") != defaultOllamaEmbeddingModel+",bge-m3" {
+		t.Errorf("embedding models used = %v", models)
+	}
+	if caps, _ := r.Capabilities("local"); !caps.Embeddings {
+		t.Error("configured embedding model not reported in capabilities")
+	}
+
+	if _, err := r.Embed(ctx, "no-model", "tabs"); err == nil {
+		t.Error("expected error for a compatible server without an embedding model")
+	}
+	if _, err := r.Embed(ctx, "claude", "tabs"); err == nil || !strings.Contains(err.Error(), "does not support embeddings") {
+		t.Errorf("Embed(claude) error = %v", err)
+	}
+	if _, err := r.Embed(ctx, "ollama"); err == nil {
+		t.Error("expected error for nothing to embed")
+	}
+}
@@ -34,6 +34,7 @@ func NewGenericProvider(cfg GenericConfig) *GenericProvider {
 			Endpoint:  cfg.Endpoint,
 			Model:     cfg.Model,
 			MaxTokens: maxTokens,
+			JSONMode:  "prompt",
 		}),
 	}
 }
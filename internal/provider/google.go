@@ -10,7 +10,11 @@ import (
 	"strings"
 )
 
-const googleAPIBaseURL = "https://generativelanguage.googleapis.com/v1beta/models"
+const (
+	googleAPIBaseURL = "https://generativelanguage.googleapis.com/v1beta/models"
+
+	defaultGoogleEmbeddingModel = "gemini-embedding-001"
+)
 
 // GoogleProvider implements the Provider interface for Google's Generative AI API (Gemini).
 type GoogleProvider struct {
@@ -36,9 +40,10 @@ func NewGoogleProvider(model string) *GoogleProvider {
 
 // googleRequest represents the request body for the Google Generative AI API.
 type googleRequest struct {
-	Contents         []googleContent        `json:"contents"`
-	SystemInstruction *googleContent        `json:"systemInstruction,omitempty"`
-	GenerationConfig *googleGenerationConfig `json:"generationConfig,omitempty"`
+	Contents          []googleContent         `json:"contents"`
+	SystemInstruction *googleContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *googleGenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []googleTool            `json:"tools,omitempty"`
 }
 
 type googleContent struct {
@@ -47,12 +52,54 @@ type googleContent struct {
 }
 
 type googlePart struct {
-	Text string `json:"text"`
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	FunctionCall     *googleFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *googleFunctionResponse `json:"functionResponse,omitempty"`
+	ThoughtSignature string                  `json:"thoughtSignature,omitempty"`
+	InlineData       *googleBlob             `json:"inline_data,omitempty"`
+}
+
+// googleBlob carries an image or document inline.
+type googleBlob struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"`
+}
+
+// googleTool groups the function declarations offered to the model.
+type googleTool struct {
+	FunctionDeclarations []googleFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type googleFunctionDeclaration struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+type googleFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type googleFunctionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
 }
 
 type googleGenerationConfig struct {
-	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
-	Temperature     float64 `json:"temperature,omitempty"`
+	MaxOutputTokens    int                   `json:"maxOutputTokens,omitempty"`
+	Temperature        float64               `json:"temperature,omitempty"`
+	ResponseMimeType   string                `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema map[string]any        `json:"responseJsonSchema,omitempty"`
+	ThinkingConfig     *googleThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type googleThinkingConfig struct {
+	ThinkingBudget  int  `json:"thinkingBudget"`
+	IncludeThoughts bool `json:"includeThoughts"`
 }
 
 // googleResponse represents the response from the Google Generative AI API.
@@ -68,11 +115,27 @@ type googleResponse struct {
 			Probability string `json:"probability"`
 		} `json:"safetyRatings"`
 	} `json:"candidates"`
-	UsageMetadata struct {
-		PromptTokenCount     int `json:"promptTokenCount"`
-		CandidatesTokenCount int `json:"candidatesTokenCount"`
-		TotalTokenCount      int `json:"totalTokenCount"`
-	} `json:"usageMetadata"`
+	UsageMetadata googleUsageMetadata `json:"usageMetadata"`
+}
+
+// googleUsageMetadata represents token usage reported by the Gemini API.
+type googleUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+}
+
+// toUsage converts Gemini usage metadata into provider usage. Gemini counts
+// thinking tokens separately from candidates, so they are folded into output.
+func (u googleUsageMetadata) toUsage() Usage {
+	return Usage{
+		InputTokens:     u.PromptTokenCount,
+		OutputTokens:    u.CandidatesTokenCount + u.ThoughtsTokenCount,
+		CachedTokens:    u.CachedContentTokenCount,
+		ReasoningTokens: u.ThoughtsTokenCount,
+	}
 }
 
 // googleStreamResponse represents a streaming response chunk from the Google API.
@@ -84,6 +147,7 @@ type googleStreamResponse struct {
 		} `json:"content"`
 		FinishReason string `json:"finishReason,omitempty"`
 	} `json:"candidates"`
+	UsageMetadata *googleUsageMetadata `json:"usageMetadata,omitempty"`
 }
 
 // googleErrorResponse represents an error response from the API.
@@ -102,12 +166,12 @@ func (p *GoogleProvider) Invoke(ctx context.Context, req Request) (*Response, er
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return nil, err
 	}
 
 	apiReq := p.buildRequest(req)
-	url := fmt.Sprintf("%s/%s:generateContent?key=%s", googleAPIBaseURL, p.model, p.GetAPIKey())
+	url := fmt.Sprintf("%s/%s:generateContent?key=%s", googleAPIBaseURL, p.model, p.GetAPIKey(ctx))
 
 	resp, err := p.DoRequest(ctx, http.MethodPost, url, apiReq, nil)
 	if err != nil {
@@ -123,9 +187,9 @@ func (p *GoogleProvider) Invoke(ctx context.Context, req Request) (*Response, er
 	if resp.StatusCode != http.StatusOK {
 		var errResp googleErrorResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error.Message)
+			return nil, NewAPIError(resp, errResp.Error.Message)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(resp, string(body))
 	}
 
 	var apiResp googleResponse
@@ -137,17 +201,27 @@ func (p *GoogleProvider) Invoke(ctx context.Context, req Request) (*Response, er
 		return nil, fmt.Errorf("no candidates in response")
 	}
 
-	// Extract text content
-	var content strings.Builder
-	for _, part := range apiResp.Candidates[0].Content.Parts {
-		content.WriteString(part.Text)
+	// Extract text content, keeping thought summaries apart from the answer
+	parts := apiResp.Candidates[0].Content.Parts
+	var content, reasoning strings.Builder
+	for _, part := range parts {
+		if part.Thought {
+			reasoning.WriteString(part.Text)
+		} else {
+			content.WriteString(part.Text)
+		}
 	}
 
+	usage := apiResp.UsageMetadata.toUsage()
+
 	return &Response{
 		Content:      content.String(),
+		Reasoning:    reasoning.String(),
 		Model:        p.model,
 		FinishReason: apiResp.Candidates[0].FinishReason,
-		TokensUsed:   apiResp.UsageMetadata.TotalTokenCount,
+		TokensUsed:   usage.Total(),
+		Usage:        usage,
+		ToolCalls:    googleToolCalls(parts),
 	}, nil
 }
 
@@ -158,12 +232,12 @@ func (p *GoogleProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return nil, err
 	}
 
 	apiReq := p.buildRequest(req)
-	url := fmt.Sprintf("%s/%s:streamGenerateContent?alt=sse&key=%s", googleAPIBaseURL, p.model, p.GetAPIKey())
+	url := fmt.Sprintf("%s/%s:streamGenerateContent?alt=sse&key=%s", googleAPIBaseURL, p.model, p.GetAPIKey(ctx))
 
 	resp, err := p.DoRequest(ctx, http.MethodPost, url, apiReq, nil)
 	if err != nil {
@@ -175,18 +249,22 @@ func (p *GoogleProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 		body, _ := io.ReadAll(resp.Body)
 		var errResp googleErrorResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error.Message)
+			return nil, NewAPIError(resp, errResp.Error.Message)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(resp, string(body))
 	}
 
 	out := make(chan StreamChunk, 100)
 
 	go func() {
-		defer resp.Body.Close()
+		body := p.streamBody(resp)
+		defer body.Close()
 		defer close(out)
 
-		scanner := bufio.NewScanner(resp.Body)
+		// Each chunk carries cumulative usage; the last one seen is final
+		var usage *Usage
+
+		scanner := bufio.NewScanner(body)
 		for scanner.Scan() {
 			line := scanner.Text()
 
@@ -206,15 +284,29 @@ func (p *GoogleProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 				return
 			}
 
-			if len(chunk.Candidates) > 0 && len(chunk.Candidates[0].Content.Parts) > 0 {
-				for _, part := range chunk.Candidates[0].Content.Parts {
-					if part.Text != "" {
+			if chunk.UsageMetadata != nil {
+				u := chunk.UsageMetadata.toUsage()
+				usage = &u
+			}
+
+			if len(chunk.Candidates) > 0 {
+				parts := chunk.Candidates[0].Content.Parts
+				for _, part := range parts {
+					switch {
+					case part.Text == "":
+					case part.Thought:
+						out <- StreamChunk{Reasoning: part.Text}
+					default:
 						out <- StreamChunk{Content: part.Text}
 					}
 				}
+				// Gemini sends each function call whole rather than in fragments
+				if calls := googleToolCalls(parts); len(calls) > 0 {
+					out <- StreamChunk{ToolCalls: calls}
+				}
 
 				if chunk.Candidates[0].FinishReason != "" {
-					out <- StreamChunk{Done: true}
+					out <- StreamChunk{Done: true, Usage: usage}
 					return
 				}
 			}
@@ -222,7 +314,10 @@ func (p *GoogleProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 
 		if err := scanner.Err(); err != nil {
 			out <- StreamChunk{Error: fmt.Errorf("reading stream: %w", err)}
+			return
 		}
+
+		out <- StreamChunk{Done: true, Usage: usage}
 	}()
 
 	return out, nil
@@ -230,13 +325,64 @@ func (p *GoogleProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 
 // buildRequest constructs a Google API request from a provider.Request.
 func (p *GoogleProvider) buildRequest(req Request) googleRequest {
+	conversation := req.Conversation()
 	apiReq := googleRequest{
-		Contents: []googleContent{
-			{
-				Role:  "user",
-				Parts: []googlePart{{Text: req.Prompt}},
-			},
-		},
+		Contents: make([]googleContent, 0, len(conversation)),
+	}
+
+	for i, m := range conversation {
+		// Tool results are sent back as user function responses; consecutive
+		// results share a turn, matching the calls that produced them
+		if m.Role == RoleTool {
+			part := googlePart{FunctionResponse: &googleFunctionResponse{
+				ID:       m.ToolCallID,
+				Name:     m.ToolName,
+				Response: map[string]any{"content": m.Content},
+			}}
+			if n := len(apiReq.Contents); n > 0 && i > 0 && conversation[i-1].Role == RoleTool {
+				apiReq.Contents[n-1].Parts = append(apiReq.Contents[n-1].Parts, part)
+			} else {
+				apiReq.Contents = append(apiReq.Contents, googleContent{Role: RoleUser, Parts: []googlePart{part}})
+			}
+			continue
+		}
+
+		// Gemini names the assistant role "model"
+		role := m.Role
+		if role == RoleAssistant {
+			role = "model"
+		}
+
+		var parts []googlePart
+		for _, a := range mediaAttachments(m) {
+			parts = append(parts, googlePart{InlineData: &googleBlob{MimeType: a.MIMEType, Data: a.base64()}})
+		}
+		if text := messageText(m); text != "" || len(m.ToolCalls) == 0 {
+			parts = append(parts, googlePart{Text: text})
+		}
+		for _, call := range m.ToolCalls {
+			parts = append(parts, googlePart{
+				FunctionCall:     &googleFunctionCall{ID: call.ID, Name: call.Name, Args: call.argumentsJSON()},
+				ThoughtSignature: call.Signature,
+			})
+		}
+
+		apiReq.Contents = append(apiReq.Contents, googleContent{
+			Role:  role,
+			Parts: parts,
+		})
+	}
+
+	if len(req.Tools) > 0 {
+		decls := make([]googleFunctionDeclaration, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			decls = append(decls, googleFunctionDeclaration{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			})
+		}
+		apiReq.Tools = []googleTool{{FunctionDeclarations: decls}}
 	}
 
 	if req.SystemPrompt != "" {
@@ -258,17 +404,118 @@ func (p *GoogleProvider) buildRequest(req Request) googleRequest {
 		apiReq.GenerationConfig.Temperature = req.Temperature
 	}
 
+	if req.ResponseSchema != nil {
+		apiReq.GenerationConfig.ResponseMimeType = "application/json"
+		apiReq.GenerationConfig.ResponseJSONSchema = req.ResponseSchema.Schema
+	}
+
+	if req.ThinkingBudget > 0 {
+		apiReq.GenerationConfig.ThinkingConfig = &googleThinkingConfig{
+			ThinkingBudget:  req.ThinkingBudget,
+			IncludeThoughts: true,
+		}
+	}
+
 	return apiReq
 }
 
+// googleToolCalls extracts function calls from response parts. Gemini may omit
+// call IDs, so positional ones are generated to pair results with calls.
+func googleToolCalls(parts []googlePart) []ToolCall {
+	var calls []ToolCall
+	for _, part := range parts {
+		if part.FunctionCall == nil {
+			continue
+		}
+		id := part.FunctionCall.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", len(calls)+1)
+		}
+		calls = append(calls, ToolCall{
+			ID:        id,
+			Name:      part.FunctionCall.Name,
+			Arguments: string(part.FunctionCall.Args),
+			Signature: part.ThoughtSignature,
+		})
+	}
+	return calls
+}
+
+// googleEmbedRequest is one text in a batchEmbedContents call.
+type googleEmbedRequest struct {
+	Model                string        `json:"model"`
+	Content              googleContent `json:"content"`
+	OutputDimensionality int           `json:"outputDimensionality,omitempty"`
+}
+
+// Embed turns texts into vectors with Gemini's batchEmbedContents API.
+func (p *GoogleProvider) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
+		return nil, err
+	}
+	model := req.Model
+	if model == "" {
+		model = defaultGoogleEmbeddingModel
+	}
+
+	requests := make([]googleEmbedRequest, len(req.Texts))
+	for i, text := range req.Texts {
+		requests[i] = googleEmbedRequest{
+			Model:                "models/" + model,
+			Content:              googleContent{Parts: []googlePart{{Text: text}}},
+			OutputDimensionality: req.Dimensions,
+		}
+	}
+
+	url := fmt.Sprintf("%s/%s:batchEmbedContents?key=%s", googleAPIBaseURL, model, p.GetAPIKey(ctx))
+	resp, err := p.DoRequest(ctx, http.MethodPost, url, map[string]any{"requests": requests}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp googleErrorResponse
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+			return nil, NewAPIError(resp, errResp.Error.Message)
+		}
+		return nil, NewAPIError(resp, string(body))
+	}
+
+	var apiResp struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	vectors := make([][]float32, len(apiResp.Embeddings))
+	for i, e := range apiResp.Embeddings {
+		vectors[i] = e.Values
+	}
+	return &EmbedResponse{Vectors: vectors, Model: model}, nil
+}
+
+// Capabilities reports the features of the Gemini API.
+func (p *GoogleProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, SystemPrompt: true, Tools: true, Images: true, Documents: true, JSONMode: true, Embeddings: true}.withModelLimits(p.model)
+}
+
 // HealthCheck verifies the Google API is accessible.
 func (p *GoogleProvider) HealthCheck(ctx context.Context) error {
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return err
 	}
 
 	// List models to verify connectivity
-	url := fmt.Sprintf("%s?key=%s", googleAPIBaseURL, p.GetAPIKey())
+	url := fmt.Sprintf("%s?key=%s", googleAPIBaseURL, p.GetAPIKey(ctx))
 	resp, err := p.DoRequest(ctx, http.MethodGet, url, nil, nil)
 	if err != nil {
 		return err
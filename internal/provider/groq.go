@@ -16,11 +16,12 @@ func NewGroqProvider(model string) *GroqProvider {
 
 	return &GroqProvider{
 		OpenAICompatProvider: NewOpenAICompatProvider(OpenAICompatConfig{
-			Name:      "groq",
-			APIKeyEnv: "GROQ_API_KEY",
-			Endpoint:  groqAPIEndpoint,
-			Model:     model,
-			MaxTokens: 8192,
+			Name:        "groq",
+			APIKeyEnv:   "GROQ_API_KEY",
+			Endpoint:    groqAPIEndpoint,
+			Model:       model,
+			MaxTokens:   8192,
+			StreamUsage: true,
 		}),
 	}
 }
@@ -0,0 +1,331 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/budget"
+)
+
+// Middleware wraps a provider to add behaviour around every call, such as
+// logging or metering. The returned provider must handle both Invoke and
+// Stream.
+type Middleware func(next Provider) Provider
+
+// CallInfo identifies the call a middleware is handling. The Registry puts it
+// in the context passed to Invoke and Stream.
+type CallInfo struct {
+	AIID  string // AI ID being called
+	Model string // Configured model, empty for providers that choose their own
+}
+
+type callInfoKey struct{}
+
+// CallInfoFrom returns the call a middleware's context belongs to.
+func CallInfoFrom(ctx context.Context) (CallInfo, bool) {
+	info, ok := ctx.Value(callInfoKey{}).(CallInfo)
+	return info, ok
+}
+
+type (
+	sessionIDKey struct{}
+	taskIDKey    struct{}
+)
+
+// WithSessionID tags calls made with ctx as part of a session, for
+// middleware such as the audit log.
+func WithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, id)
+}
+
+// WithTaskID tags calls made with ctx as work on a task.
+func WithTaskID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, taskIDKey{}, id)
+}
+
+type debugLogKey struct{}
+
+// debugLogFrom returns where a provider may write diagnostics, such as a CLI
+// agent's stderr, for the call ctx belongs to. It is set by DebugLog.
+func debugLogFrom(ctx context.Context) (func(message string), bool) {
+	logf, ok := ctx.Value(debugLogKey{}).(func(string))
+	return logf, ok
+}
+
+type middlewareKey struct{}
+
+// WithMiddleware adds middleware to the calls made with ctx, inside any the
+// registry uses, the first added outermost. It suits observers that belong
+// to one run rather than to every user of a shared registry.
+func WithMiddleware(ctx context.Context, mw ...Middleware) context.Context {
+	existing, _ := ctx.Value(middlewareKey{}).([]Middleware)
+	return context.WithValue(ctx, middlewareKey{}, append(slices.Clip(existing), mw...))
+}
+
+// Use adds middleware to every call made through the registry, the first
+// added outermost. Middleware sees each call once, outside the rate limits,
+// retries, response cache and cassette, so cached and replayed responses pass
+// through it too.
+func (r *Registry) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// applyMiddleware wraps p with the registered middleware, and any added to
+// the call's context, for a call to aiID.
+func (r *Registry) applyMiddleware(aiID, model string, p Provider) Provider {
+	p = &contextMiddlewareProvider{Provider: p}
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		p = r.middleware[i](p)
+	}
+	return &callProvider{Provider: p, info: CallInfo{AIID: aiID, Model: model}}
+}
+
+// contextMiddlewareProvider wraps each call in the middleware added to its
+// context with WithMiddleware.
+type contextMiddlewareProvider struct {
+	Provider
+}
+
+func (p *contextMiddlewareProvider) wrapped(ctx context.Context) Provider {
+	mw, _ := ctx.Value(middlewareKey{}).([]Middleware)
+	next := p.Provider
+	for i := len(mw) - 1; i >= 0; i-- {
+		next = mw[i](next)
+	}
+	return next
+}
+
+func (p *contextMiddlewareProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	return p.wrapped(ctx).Invoke(ctx, req)
+}
+
+func (p *contextMiddlewareProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	return p.wrapped(ctx).Stream(ctx, req)
+}
+
+// callProvider puts the CallInfo in the context of each call.
+type callProvider struct {
+	Provider
+	info CallInfo
+}
+
+func (p *callProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	return p.Provider.Invoke(context.WithValue(ctx, callInfoKey{}, p.info), req)
+}
+
+func (p *callProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	return p.Provider.Stream(context.WithValue(ctx, callInfoKey{}, p.info), req)
+}
+
+// CallReport describes a finished call.
+type CallReport struct {
+	CallInfo
+	SessionID  string // Set with WithSessionID, if the caller did
+	TaskID     string // Set with WithTaskID, if the caller did
+	Provider   string
+	Stream     bool
+	Request    Request
+	Content    string // Answer text, gathered from every chunk of a stream
+	Reasoning  string
+	Duration   time.Duration // Until the response arrived or the stream ended
+	FirstChunk time.Duration // Until a stream's first chunk (0 for Invoke)
+	Usage      Usage
+	Cached     bool // Served from the response cache
+	Err        error
+}
+
+// Timing returns middleware that reports each call once it finishes, with
+// how long it took, the usage reported and any error.
+func Timing(fn func(CallReport)) Middleware {
+	return func(next Provider) Provider {
+		return &observingProvider{Provider: next, onEnd: fn}
+	}
+}
+
+// DebugLog returns middleware that describes each call as it starts and
+// finishes, for display in a debug log. Providers may add their own
+// diagnostics to the log.
+func DebugLog(logf func(aiID, message string)) Middleware {
+	return func(next Provider) Provider {
+		return &debugLogProvider{
+			Provider: &observingProvider{
+				Provider: next,
+				onStart: func(c CallReport) {
+					logf(c.AIID, fmt.Sprintf("%s via %s", callKind(c), describeModel(c)))
+				},
+				onEnd: func(c CallReport) {
+					logf(c.AIID, describeCall(c))
+				},
+			},
+			logf: logf,
+		}
+	}
+}
+
+// debugLogProvider puts the debug log in the context of each call, for
+// debugLogFrom.
+type debugLogProvider struct {
+	Provider
+	logf func(aiID, message string)
+}
+
+func (p *debugLogProvider) withLog(ctx context.Context) context.Context {
+	info, _ := CallInfoFrom(ctx)
+	return context.WithValue(ctx, debugLogKey{}, func(message string) {
+		p.logf(info.AIID, message)
+	})
+}
+
+func (p *debugLogProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	return p.Provider.Invoke(p.withLog(ctx), req)
+}
+
+func (p *debugLogProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	return p.Provider.Stream(p.withLog(ctx), req)
+}
+
+// RecordUsage returns middleware that records the usage of each successful
+// call in t. Responses served from the cache cost nothing and are skipped.
+func RecordUsage(t *budget.Tracker) Middleware {
+	return Timing(func(c CallReport) {
+		if c.Err != nil || c.Cached || c.Usage.Total() == 0 {
+			return
+		}
+		t.RecordUsage(c.Provider, c.Model, c.AIID, c.Usage.InputTokens, c.Usage.OutputTokens, "")
+	})
+}
+
+// observingProvider reports the start and end of each call. Either callback
+// may be nil.
+type observingProvider struct {
+	Provider
+	onStart func(CallReport)
+	onEnd   func(CallReport)
+}
+
+// report starts the CallReport for a call.
+func (p *observingProvider) report(ctx context.Context, req Request, stream bool) CallReport {
+	info, _ := CallInfoFrom(ctx)
+	c := CallReport{CallInfo: info, Provider: p.Name(), Stream: stream, Request: req}
+	c.SessionID, _ = ctx.Value(sessionIDKey{}).(string)
+	c.TaskID, _ = ctx.Value(taskIDKey{}).(string)
+	if p.onStart != nil {
+		p.onStart(c)
+	}
+	return c
+}
+
+func (p *observingProvider) end(c CallReport) {
+	if p.onEnd != nil {
+		p.onEnd(c)
+	}
+}
+
+// Invoke calls the wrapped provider, reporting the call.
+func (p *observingProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	c := p.report(ctx, req, false)
+	start := time.Now()
+
+	resp, err := p.Provider.Invoke(ctx, req)
+	c.Duration = time.Since(start)
+	c.Err = err
+	if resp != nil {
+		c.Content = resp.Content
+		c.Reasoning = resp.Reasoning
+		c.Usage = resp.Usage
+		c.Cached = resp.Cached
+		if resp.Model != "" {
+			c.Model = resp.Model
+		}
+	}
+	p.end(c)
+	return resp, err
+}
+
+// Stream streams from the wrapped provider, reporting the call once the
+// stream ends.
+func (p *observingProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	c := p.report(ctx, req, true)
+	start := time.Now()
+
+	ch, err := p.Provider.Stream(ctx, req)
+	if err != nil {
+		c.Duration = time.Since(start)
+		c.Err = err
+		p.end(c)
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 100)
+	go func() {
+		defer close(out)
+
+		var content, reasoning strings.Builder
+		done := false
+		for chunk := range ch {
+			if c.FirstChunk == 0 {
+				c.FirstChunk = time.Since(start)
+			}
+			content.WriteString(chunk.Content)
+			reasoning.WriteString(chunk.Reasoning)
+			if chunk.Usage != nil {
+				c.Usage = *chunk.Usage
+			}
+			if chunk.Error != nil {
+				c.Err = chunk.Error
+			}
+			c.Cached = c.Cached || chunk.Cached
+			done = done || chunk.Done
+			out <- chunk
+		}
+		if !done && c.Err == nil {
+			c.Err = ctx.Err()
+		}
+		c.Content = content.String()
+		c.Reasoning = reasoning.String()
+
+		// Report before closing, so a caller that drained the stream sees it
+		c.Duration = time.Since(start)
+		p.end(c)
+	}()
+	return out, nil
+}
+
+// callKind names the kind of call, for log messages.
+func callKind(c CallReport) string {
+	if c.Stream {
+		return "stream"
+	}
+	return "invoke"
+}
+
+// describeModel names the provider and model of a call.
+func describeModel(c CallReport) string {
+	if c.Model == "" {
+		return c.Provider
+	}
+	return fmt.Sprintf("%s (%s)", c.Provider, c.Model)
+}
+
+// describeCall summarizes a finished call in one line.
+func describeCall(c CallReport) string {
+	d := c.Duration.Round(10 * time.Millisecond)
+	if c.Err != nil {
+		return fmt.Sprintf("%s failed after %s: %v", callKind(c), d, c.Err)
+	}
+
+	msg := fmt.Sprintf("%s done in %s", callKind(c), d)
+	if c.Stream && c.FirstChunk > 0 {
+		msg += fmt.Sprintf(" (first chunk %s)", c.FirstChunk.Round(10*time.Millisecond))
+	}
+	if c.Cached {
+		msg += ", cached"
+	}
+	if c.Usage.Total() > 0 {
+		msg += fmt.Sprintf(", %d in / %d out tokens", c.Usage.InputTokens, c.Usage.OutputTokens)
+	}
+	return msg
+}
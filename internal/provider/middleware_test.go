@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/budget"
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+// meteredProvider answers every call and reports fixed usage.
+type meteredProvider struct {
+	flakyProvider
+}
+
+func (m *meteredProvider) Name() string { return "anthropic" }
+
+func (m *meteredProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	return &Response{Content: "ok", Usage: Usage{InputTokens: 1000, OutputTokens: 2000}}, nil
+}
+
+func (m *meteredProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	ch := make(chan StreamChunk, 2)
+	ch <- StreamChunk{Content: "ok"}
+	ch <- StreamChunk{Done: true, Usage: &Usage{InputTokens: 1000, OutputTokens: 2000}}
+	close(ch)
+	return ch, nil
+}
+
+// tagging returns middleware that appends name to order on each Invoke.
+func tagging(name string, order *[]string) Middleware {
+	return func(next Provider) Provider {
+		return &tagProvider{Provider: next, name: name, order: order}
+	}
+}
+
+type tagProvider struct {
+	Provider
+	name  string
+	order *[]string
+}
+
+func (p *tagProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	info, _ := CallInfoFrom(ctx)
+	*p.order = append(*p.order, p.name+" "+info.AIID)
+	return p.Provider.Invoke(ctx, req)
+}
+
+func TestRegistryMiddleware(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&meteredProvider{})
+	r.RegisterModel("claude", config.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5-20250929"})
+
+	var order []string
+	var reports []CallReport
+	var logged []string
+	tracker := budget.NewTracker()
+	r.Use(tagging("outer", &order), tagging("inner", &order))
+	r.Use(
+		Timing(func(c CallReport) { reports = append(reports, c) }),
+		DebugLog(func(aiID, message string) { logged = append(logged, aiID+": "+message) }),
+		RecordUsage(tracker),
+	)
+
+	ctx := context.Background()
+	if _, err := r.Invoke(ctx, "claude", Request{Prompt: "hi"}); err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	ch, err := r.Stream(ctx, "claude", Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	for range ch {
+	}
+
+	if strings.Join(order, ", ") != "outer claude, inner claude" {
+		t.Errorf("middleware order = %v", order)
+	}
+	if len(reports) != 2 || reports[0].Stream || !reports[1].Stream || reports[1].Usage.OutputTokens != 2000 {
+		t.Errorf("timing reports = %+v", reports)
+	}
+	if len(logged) != 4 || !strings.Contains(logged[0], "invoke via anthropic (claude-sonnet-4-5-20250929)") ||
+		!strings.Contains(logged[3], "1000 in / 2000 out tokens") {
+		t.Errorf("debug log = %q", logged)
+	}
+
+	// Two calls at $0.003/1K in and $0.015/1K out
+	if usages := tracker.GetUsages(); len(usages) != 2 || usages[0].AIID != "claude" {
+		t.Errorf("recorded usage = %+v", usages)
+	}
+	if cost := tracker.GetTotalCost(); cost < 0.0659 || cost > 0.0661 {
+		t.Errorf("total cost = %v, want 0.066", cost)
+	}
+}
+
+func TestWithMiddleware(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&meteredProvider{})
+	r.RegisterModel("claude", config.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5-20250929"})
+
+	var order []string
+	r.Use(tagging("registry", &order))
+	ctx := WithMiddleware(context.Background(), tagging("run", &order))
+	if _, err := r.Invoke(ctx, "claude", Request{Prompt: "hi"}); err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	// Calls made without the context see only the registry's middleware
+	if _, err := r.Invoke(context.Background(), "claude", Request{Prompt: "hi"}); err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if strings.Join(order, ", ") != "registry claude, run claude, registry claude" {
+		t.Errorf("middleware order = %v", order)
+	}
+}
@@ -16,11 +16,12 @@ func NewMistralProvider(model string) *MistralProvider {
 
 	return &MistralProvider{
 		OpenAICompatProvider: NewOpenAICompatProvider(OpenAICompatConfig{
-			Name:      "mistral",
-			APIKeyEnv: "MISTRAL_API_KEY",
-			Endpoint:  mistralAPIEndpoint,
-			Model:     model,
-			MaxTokens: 8192,
+			Name:           "mistral",
+			APIKeyEnv:      "MISTRAL_API_KEY",
+			Endpoint:       mistralAPIEndpoint,
+			Model:          model,
+			EmbeddingModel: "mistral-embed",
+			MaxTokens:      8192,
 		}),
 	}
 }
@@ -7,13 +7,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 )
 
-const defaultOllamaURL = "http://localhost:11434"
+const (
+	defaultOllamaURL            = "http://localhost:11434"
+	defaultOllamaEmbeddingModel = "nomic-embed-text"
+)
 
 // OllamaProvider implements the Provider interface for Ollama's REST API.
 type OllamaProvider struct {
 	*BaseProvider
+	modelCaps []string // As reported by /api/show; nil if not looked up
 }
 
 // NewOllamaProvider creates a new Ollama provider.
@@ -41,11 +46,27 @@ type ollamaRequest struct {
 	Messages []ollamaMessage `json:"messages"`
 	Stream   bool            `json:"stream"`
 	Options  *ollamaOptions  `json:"options,omitempty"`
+	Tools    []openaiTool    `json:"tools,omitempty"`
+	Format   map[string]any  `json:"format,omitempty"` // JSON Schema constraining the reply
+	Think    bool            `json:"think,omitempty"`  // Return thinking separately from content
 }
 
 type ollamaMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+	Images    []string         `json:"images,omitempty"` // Base64 data, for vision models
+}
+
+// ollamaToolCall represents a function call. Ollama sends arguments as a JSON
+// object rather than an encoded string, and does not assign call IDs.
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
 }
 
 type ollamaOptions struct {
@@ -69,11 +90,14 @@ type ollamaResponse struct {
 }
 
 // ollamaStreamResponse represents a streaming response chunk.
+// The final chunk (Done set) carries the evaluation counts.
 type ollamaStreamResponse struct {
-	Model     string        `json:"model"`
-	CreatedAt string        `json:"created_at"`
-	Message   ollamaMessage `json:"message"`
-	Done      bool          `json:"done"`
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
 }
 
 // ollamaErrorResponse represents an error response from the API.
@@ -88,16 +112,18 @@ func (p *OllamaProvider) Invoke(ctx context.Context, req Request) (*Response, er
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	messages := []ollamaMessage{}
-	if req.SystemPrompt != "" {
-		messages = append(messages, ollamaMessage{Role: "system", Content: req.SystemPrompt})
-	}
-	messages = append(messages, ollamaMessage{Role: "user", Content: req.Prompt})
+	messages := buildOllamaMessages(req)
 
 	apiReq := ollamaRequest{
 		Model:    p.model,
 		Messages: messages,
 		Stream:   false,
+		Tools:    buildOpenAITools(req.Tools),
+		Think:    req.ThinkingBudget > 0,
+	}
+
+	if req.ResponseSchema != nil {
+		apiReq.Format = req.ResponseSchema.Schema
 	}
 
 	// Add options if specified
@@ -126,9 +152,9 @@ func (p *OllamaProvider) Invoke(ctx context.Context, req Request) (*Response, er
 	if resp.StatusCode != http.StatusOK {
 		var errResp ollamaErrorResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error)
+			return nil, NewAPIError(resp, errResp.Error)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(resp, string(body))
 	}
 
 	var apiResp ollamaResponse
@@ -136,11 +162,19 @@ func (p *OllamaProvider) Invoke(ctx context.Context, req Request) (*Response, er
 		return nil, fmt.Errorf("parsing response: %w", err)
 	}
 
+	usage := Usage{
+		InputTokens:  apiResp.PromptEvalCount,
+		OutputTokens: apiResp.EvalCount,
+	}
+
 	return &Response{
 		Content:      apiResp.Message.Content,
+		Reasoning:    apiResp.Message.Thinking,
 		Model:        apiResp.Model,
 		FinishReason: apiResp.DoneReason,
-		TokensUsed:   apiResp.PromptEvalCount + apiResp.EvalCount,
+		TokensUsed:   usage.Total(),
+		Usage:        usage,
+		ToolCalls:    fromOllamaToolCalls(apiResp.Message.ToolCalls),
 	}, nil
 }
 
@@ -151,16 +185,18 @@ func (p *OllamaProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	messages := []ollamaMessage{}
-	if req.SystemPrompt != "" {
-		messages = append(messages, ollamaMessage{Role: "system", Content: req.SystemPrompt})
-	}
-	messages = append(messages, ollamaMessage{Role: "user", Content: req.Prompt})
+	messages := buildOllamaMessages(req)
 
 	apiReq := ollamaRequest{
 		Model:    p.model,
 		Messages: messages,
 		Stream:   true,
+		Tools:    buildOpenAITools(req.Tools),
+		Think:    req.ThinkingBudget > 0,
+	}
+
+	if req.ResponseSchema != nil {
+		apiReq.Format = req.ResponseSchema.Schema
 	}
 
 	// Add options if specified
@@ -185,19 +221,20 @@ func (p *OllamaProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 		body, _ := io.ReadAll(resp.Body)
 		var errResp ollamaErrorResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error)
+			return nil, NewAPIError(resp, errResp.Error)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(resp, string(body))
 	}
 
 	out := make(chan StreamChunk, 100)
 
 	// Ollama uses newline-delimited JSON, not SSE
 	go func() {
-		defer resp.Body.Close()
+		body := p.streamBody(resp)
+		defer body.Close()
 		defer close(out)
 
-		scanner := bufio.NewScanner(resp.Body)
+		scanner := bufio.NewScanner(body)
 		for scanner.Scan() {
 			line := scanner.Bytes()
 			if len(line) == 0 {
@@ -210,12 +247,21 @@ func (p *OllamaProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 				return
 			}
 
+			if chunk.Message.Thinking != "" {
+				out <- StreamChunk{Reasoning: chunk.Message.Thinking}
+			}
 			if chunk.Message.Content != "" {
 				out <- StreamChunk{Content: chunk.Message.Content}
 			}
+			if calls := fromOllamaToolCalls(chunk.Message.ToolCalls); len(calls) > 0 {
+				out <- StreamChunk{ToolCalls: calls}
+			}
 
 			if chunk.Done {
-				out <- StreamChunk{Done: true}
+				out <- StreamChunk{Done: true, Usage: &Usage{
+					InputTokens:  chunk.PromptEvalCount,
+					OutputTokens: chunk.EvalCount,
+				}}
 				return
 			}
 		}
@@ -228,6 +274,92 @@ func (p *OllamaProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 	return out, nil
 }
 
+// buildOllamaMessages converts the request into Ollama chat messages.
+func buildOllamaMessages(req Request) []ollamaMessage {
+	conversation := req.Conversation()
+	messages := make([]ollamaMessage, 0, len(conversation)+1)
+	if req.SystemPrompt != "" {
+		messages = append(messages, ollamaMessage{Role: "system", Content: req.SystemPrompt})
+	}
+	for _, m := range conversation {
+		msg := ollamaMessage{Role: m.Role, Content: messageText(m), ToolName: m.ToolName}
+		for _, a := range mediaAttachments(m) {
+			msg.Images = append(msg.Images, a.base64())
+		}
+		for _, call := range m.ToolCalls {
+			var tc ollamaToolCall
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = call.argumentsJSON()
+			msg.ToolCalls = append(msg.ToolCalls, tc)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// fromOllamaToolCalls converts API tool calls, generating positional IDs.
+func fromOllamaToolCalls(calls []ollamaToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]ToolCall, 0, len(calls))
+	for i, c := range calls {
+		out = append(out, ToolCall{
+			ID:        fmt.Sprintf("call_%d", i+1),
+			Name:      c.Function.Name,
+			Arguments: string(c.Function.Arguments),
+		})
+	}
+	return out
+}
+
+// Embed turns texts into vectors with Ollama's /api/embed. The embedding
+// model must already be pulled.
+func (p *OllamaProvider) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = defaultOllamaEmbeddingModel
+	}
+
+	apiReq := map[string]any{"model": model, "input": req.Texts}
+	if req.Dimensions > 0 {
+		apiReq["dimensions"] = req.Dimensions
+	}
+	var apiResp struct {
+		Model           string      `json:"model"`
+		Embeddings      [][]float32 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	if err := p.manage(ctx, http.MethodPost, "/api/embed", apiReq, &apiResp); err != nil {
+		return nil, err
+	}
+	return &EmbedResponse{
+		Vectors: apiResp.Embeddings,
+		Model:   apiResp.Model,
+		Usage:   Usage{InputTokens: apiResp.PromptEvalCount},
+	}, nil
+}
+
+// Capabilities reports the features of Ollama's chat API, narrowed to what
+// the model supports once SetModelCapabilities has been called. Only vision
+// models accept images, so none are assumed until the server says so or the
+// model's config sets vision. Context windows depend on how each local model
+// is loaded, so none is assumed.
+func (p *OllamaProvider) Capabilities() Capabilities {
+	caps := Capabilities{Streaming: true, SystemPrompt: true, Tools: true, JSONMode: true, Embeddings: true}
+	if p.modelCaps != nil {
+		caps.Tools = slices.Contains(p.modelCaps, "tools")
+		caps.Images = slices.Contains(p.modelCaps, "vision")
+	}
+	return caps
+}
+
+// SetModelCapabilities records the capabilities the Ollama server reports for
+// the model, such as "tools" and "vision".
+func (p *OllamaProvider) SetModelCapabilities(caps []string) {
+	p.modelCaps = caps
+}
+
 // HealthCheck verifies the Ollama API is accessible.
 func (p *OllamaProvider) HealthCheck(ctx context.Context) error {
 	// Check if the server is running by hitting the version endpoint
@@ -244,34 +376,3 @@ func (p *OllamaProvider) HealthCheck(ctx context.Context) error {
 
 	return nil
 }
-
-// ListModels returns the list of available models from Ollama.
-func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
-	url := p.baseURL + "/api/tags"
-	resp, err := p.DoRequest(ctx, http.MethodGet, url, nil, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
-	}
-
-	var result struct {
-		Models []struct {
-			Name string `json:"name"`
-		} `json:"models"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
-	}
-
-	models := make([]string, len(result.Models))
-	for i, m := range result.Models {
-		models[i] = m.Name
-	}
-
-	return models, nil
-}
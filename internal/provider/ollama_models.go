@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+// OllamaModelPrefix starts the AI IDs of models discovered on an Ollama
+// server, as in "ollama/qwen3:8b".
+const OllamaModelPrefix = "ollama/"
+
+// OllamaModel is a model pulled into an Ollama server.
+type OllamaModel struct {
+	Name              string
+	Size              int64 // Bytes on disk
+	ModifiedAt        time.Time
+	Family            string
+	ParameterSize     string // As reported, e.g. "8.0B"
+	QuantizationLevel string // e.g. "Q4_K_M"
+}
+
+// OllamaModelInfo describes a local model in detail.
+type OllamaModelInfo struct {
+	OllamaModel
+	ContextLength int      // Tokens the model was trained for (0 = unknown)
+	Capabilities  []string // e.g. "completion", "tools", "vision"
+	Parameters    string   // Modelfile PARAMETER lines
+	License       string
+}
+
+// OllamaPullProgress reports the state of a model download.
+type OllamaPullProgress struct {
+	Status    string `json:"status"`    // e.g. "pulling manifest", "success"
+	Digest    string `json:"digest"`    // Layer being downloaded, if any
+	Total     int64  `json:"total"`     // Bytes in the layer (0 = unknown)
+	Completed int64  `json:"completed"` // Bytes of the layer downloaded so far
+}
+
+// ollamaModelDetails is the details object shared by /api/tags and /api/show.
+type ollamaModelDetails struct {
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+// ListModels returns the models pulled into the Ollama server, by name.
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]OllamaModel, error) {
+	var result struct {
+		Models []struct {
+			Name       string             `json:"name"`
+			Size       int64              `json:"size"`
+			ModifiedAt time.Time          `json:"modified_at"`
+			Details    ollamaModelDetails `json:"details"`
+		} `json:"models"`
+	}
+	if err := p.manage(ctx, http.MethodGet, "/api/tags", nil, &result); err != nil {
+		return nil, fmt.Errorf("listing Ollama models: %w", err)
+	}
+
+	models := make([]OllamaModel, len(result.Models))
+	for i, m := range result.Models {
+		models[i] = OllamaModel{
+			Name:              m.Name,
+			Size:              m.Size,
+			ModifiedAt:        m.ModifiedAt,
+			Family:            m.Details.Family,
+			ParameterSize:     m.Details.ParameterSize,
+			QuantizationLevel: m.Details.QuantizationLevel,
+		}
+	}
+	slices.SortFunc(models, func(a, b OllamaModel) int { return strings.Compare(a.Name, b.Name) })
+	return models, nil
+}
+
+// ShowModel returns the details of a local model.
+func (p *OllamaProvider) ShowModel(ctx context.Context, name string) (*OllamaModelInfo, error) {
+	var result struct {
+		License      string             `json:"license"`
+		Parameters   string             `json:"parameters"`
+		ModifiedAt   time.Time          `json:"modified_at"`
+		Details      ollamaModelDetails `json:"details"`
+		ModelInfo    map[string]any     `json:"model_info"`
+		Capabilities []string           `json:"capabilities"`
+	}
+	if err := p.manage(ctx, http.MethodPost, "/api/show", map[string]string{"model": name}, &result); err != nil {
+		return nil, fmt.Errorf("showing Ollama model %s: %w", name, err)
+	}
+
+	info := &OllamaModelInfo{
+		OllamaModel: OllamaModel{
+			Name:              name,
+			ModifiedAt:        result.ModifiedAt,
+			Family:            result.Details.Family,
+			ParameterSize:     result.Details.ParameterSize,
+			QuantizationLevel: result.Details.QuantizationLevel,
+		},
+		Capabilities: result.Capabilities,
+		Parameters:   strings.TrimSpace(result.Parameters),
+		License:      strings.TrimSpace(result.License),
+	}
+	// The context length is keyed by architecture, as in "llama.context_length"
+	for key, v := range result.ModelInfo {
+		if n, ok := v.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			info.ContextLength = int(n)
+		}
+	}
+	return info, nil
+}
+
+// DeleteModel removes a local model.
+func (p *OllamaProvider) DeleteModel(ctx context.Context, name string) error {
+	if err := p.manage(ctx, http.MethodDelete, "/api/delete", map[string]string{"model": name}, nil); err != nil {
+		return fmt.Errorf("deleting Ollama model %s: %w", name, err)
+	}
+	return nil
+}
+
+// PullModel downloads a model into the Ollama server, calling progress with
+// each update until the pull succeeds or fails. progress may be nil. Large
+// models take a long time to download, so only ctx limits how long it runs.
+func (p *OllamaProvider) PullModel(ctx context.Context, name string, progress func(OllamaPullProgress)) error {
+	resp, err := p.doRequestUntimed(ctx, http.MethodPost, p.baseURL+"/api/pull", map[string]any{"model": name, "stream": true}, nil)
+	if err != nil {
+		return fmt.Errorf("pulling Ollama model %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("pulling Ollama model %s: %w", name, ollamaError(resp))
+	}
+
+	// Progress arrives as newline-delimited JSON; failures mid-pull arrive as
+	// a line with an error rather than as a status code
+	scanner := bufio.NewScanner(resp.Body)
+	status := ""
+	for scanner.Scan() {
+		var update struct {
+			OllamaPullProgress
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &update); err != nil {
+			continue
+		}
+		if update.Error != "" {
+			return fmt.Errorf("pulling Ollama model %s: %s", name, update.Error)
+		}
+		status = update.Status
+		if progress != nil {
+			progress(update.OllamaPullProgress)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("pulling Ollama model %s: %w", name, err)
+	}
+	if status != "success" {
+		return fmt.Errorf("pulling Ollama model %s: stream ended before the pull finished", name)
+	}
+	return nil
+}
+
+// manage calls a model management endpoint, decoding the reply into out
+// unless it is nil.
+func (p *OllamaProvider) manage(ctx context.Context, method, path string, body, out any) error {
+	resp, err := p.DoRequest(ctx, method, p.baseURL+path, body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ollamaError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+// ollamaError reads an error reply from the Ollama API.
+func ollamaError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	var errResp ollamaErrorResponse
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+		return NewAPIError(resp, errResp.Error)
+	}
+	return NewAPIError(resp, string(body))
+}
+
+// OllamaEndpoint returns the Ollama server of the first configured model
+// that uses one, by AI ID, or the default local server.
+func OllamaEndpoint(models map[string]config.ModelConfig) string {
+	ids := make([]string, 0, len(models))
+	for id, m := range models {
+		if m.Provider == "ollama" && m.Endpoint != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return defaultOllamaURL
+	}
+	slices.Sort(ids)
+	return models[ids[0]].Endpoint
+}
+
+// ollamaDiscoveryTimeout bounds DiscoverOllamaModels, so a missing Ollama
+// server barely delays startup.
+const ollamaDiscoveryTimeout = 2 * time.Second
+
+// DiscoverOllamaModels registers the models pulled into the Ollama server
+// the configured models use, as RegisterOllamaModels does, giving up quickly
+// if no server answers.
+func (r *Registry) DiscoverOllamaModels(models map[string]config.ModelConfig) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ollamaDiscoveryTimeout)
+	defer cancel()
+	return r.RegisterOllamaModels(ctx, OllamaEndpoint(models))
+}
+
+// RegisterOllamaModels registers each model on the Ollama server at
+// endpoint that no configured model already uses, under OllamaModelPrefix
+// and its name, with the context window and capabilities the server reports
+// for it. It returns the AI IDs added.
+func (r *Registry) RegisterOllamaModels(ctx context.Context, endpoint string) ([]string, error) {
+	server := NewOllamaProvider("", endpoint)
+	local, err := server.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	configured := make(map[string]bool)
+	for _, m := range r.models {
+		if m.Provider == "ollama" {
+			configured[m.Model] = true
+			configured[strings.TrimSuffix(m.Model, ":latest")] = true
+		}
+	}
+
+	var added []string
+	for _, m := range local {
+		name := strings.TrimSuffix(m.Name, ":latest")
+		if configured[name] || configured[m.Name] {
+			continue
+		}
+		aiID := OllamaModelPrefix + name
+		cfg := config.ModelConfig{
+			Provider:    "ollama",
+			Model:       m.Name,
+			Endpoint:    endpoint,
+			DisplayName: name + " (local)",
+		}
+		p := NewOllamaProvider(m.Name, endpoint)
+		// A model that can't be described is still usable, with the
+		// capabilities of the chat API in general
+		if info, err := server.ShowModel(ctx, m.Name); err == nil {
+			cfg.ContextWindow = info.ContextLength
+			p.SetModelCapabilities(info.Capabilities)
+		}
+		r.RegisterModel(aiID, cfg)
+		r.RegisterModelProvider(aiID, p)
+		added = append(added, aiID)
+	}
+	return added, nil
+}
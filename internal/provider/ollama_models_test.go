@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+// newOllamaServer stands in for an Ollama server holding the named models.
+func newOllamaServer(t *testing.T, models ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		var tags []map[string]any
+		for _, name := range models {
+			tags = append(tags, map[string]any{
+				"name":    name,
+				"size":    4_920_753_328,
+				"details": map[string]string{"family": "qwen3", "parameter_size": "8.2B", "quantization_level": "Q4_K_M"},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"models": tags})
+	})
+	mux.HandleFunc("POST /api/show", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model string }
+		json.NewDecoder(r.Body).Decode(&req)
+		if !slices.Contains(models, req.Model) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"error":"model '%s' not found"}`, req.Model)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"details":      map[string]string{"family": "qwen3", "parameter_size": "8.2B"},
+			"model_info":   map[string]any{"general.architecture": "qwen3", "qwen3.context_length": 40960},
+			"capabilities": []string{"completion", "tools"},
+		})
+	})
+	mux.HandleFunc("POST /api/pull", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model string }
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		if req.Model == "missing" {
+			fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"downloading","digest":"sha256:abc","total":100,"completed":40}`)
+		fmt.Fprintln(w, `{"status":"downloading","digest":"sha256:abc","total":100,"completed":100}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+		models = append(models, req.Model)
+	})
+	mux.HandleFunc("DELETE /api/delete", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model string }
+		json.NewDecoder(r.Body).Decode(&req)
+		i := slices.Index(models, req.Model)
+		if i < 0 {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"error":"model '%s' not found"}`, req.Model)
+			return
+		}
+		models = slices.Delete(models, i, i+1)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOllamaModelManagement(t *testing.T) {
+	srv := newOllamaServer(t, "qwen3:8b")
+	p := NewOllamaProvider("", srv.URL)
+	ctx := context.Background()
+
+	var progress []OllamaPullProgress
+	if err := p.PullModel(ctx, "llama3.2:latest", func(pp OllamaPullProgress) { progress = append(progress, pp) }); err != nil {
+		t.Fatalf("PullModel() error = %v", err)
+	}
+	if len(progress) != 4 || progress[1].Completed != 40 || progress[1].Total != 100 {
+		t.Errorf("pull progress = %+v", progress)
+	}
+	if err := p.PullModel(ctx, "missing", nil); err == nil {
+		t.Error("expected error for a pull that fails mid-stream")
+	}
+
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0].Name != "llama3.2:latest" || models[1].ParameterSize != "8.2B" {
+		t.Errorf("ListModels() = %+v", models)
+	}
+
+	info, err := p.ShowModel(ctx, "qwen3:8b")
+	if err != nil {
+		t.Fatalf("ShowModel() error = %v", err)
+	}
+	if info.ContextLength != 40960 || !slices.Contains(info.Capabilities, "tools") {
+		t.Errorf("ShowModel() = %+v", info)
+	}
+
+	if err := p.DeleteModel(ctx, "qwen3:8b"); err != nil {
+		t.Fatalf("DeleteModel() error = %v", err)
+	}
+	if _, err := p.ShowModel(ctx, "qwen3:8b"); err == nil {
+		t.Error("expected error showing a deleted model")
+	}
+}
+
+func TestPullModelIgnoresClientTimeout(t *testing.T) {
+	srv := newOllamaServer(t)
+	p := NewOllamaProvider("", srv.URL)
+	p.client.Timeout = time.Nanosecond
+
+	if err := p.PullModel(context.Background(), "llama3.2", nil); err != nil {
+		t.Errorf("PullModel() error = %v, want the pull bounded only by its context", err)
+	}
+}
+
+func TestRegisterOllamaModels(t *testing.T) {
+	srv := newOllamaServer(t, "llama3.2:latest", "qwen3:8b")
+	r := NewRegistry()
+	r.RegisterModels(map[string]config.ModelConfig{
+		"ollama": {Provider: "ollama", Model: "llama3.2", Endpoint: srv.URL},
+	})
+
+	added, err := r.RegisterOllamaModels(context.Background(), OllamaEndpoint(r.models))
+	if err != nil {
+		t.Fatalf("RegisterOllamaModels() error = %v", err)
+	}
+	if !slices.Equal(added, []string{"ollama/qwen3:8b"}) {
+		t.Errorf("added %v, want only the unconfigured model", added)
+	}
+
+	p, cfg, err := r.GetForModel("ollama/qwen3:8b")
+	if err != nil {
+		t.Fatalf("GetForModel() error = %v", err)
+	}
+	if p.(*OllamaProvider).GetModel() != "qwen3:8b" || cfg.Provider != "ollama" {
+		t.Errorf("discovered model served by %s with %+v", p.(*OllamaProvider).GetModel(), cfg)
+	}
+	caps, err := r.Capabilities("ollama/qwen3:8b")
+	if err != nil {
+		t.Fatalf("Capabilities() error = %v", err)
+	}
+	if !caps.Tools || caps.Images || caps.ContextWindow != 40960 {
+		t.Errorf("discovered model capabilities = %+v, want tools without images and a 40960 token window", caps)
+	}
+}
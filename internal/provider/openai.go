@@ -8,7 +8,12 @@ import (
 	"net/http"
 )
 
-const openaiAPIURL = "https://api.openai.com/v1/chat/completions"
+const (
+	openaiAPIURL        = "https://api.openai.com/v1/chat/completions"
+	openaiEmbeddingsURL = "https://api.openai.com/v1/embeddings"
+
+	defaultOpenAIEmbeddingModel = "text-embedding-3-small"
+)
 
 // OpenAIProvider implements the Provider interface for OpenAI's Chat Completions API.
 type OpenAIProvider struct {
@@ -34,16 +39,109 @@ func NewOpenAIProvider(model string) *OpenAIProvider {
 
 // openaiRequest represents the request body for the OpenAI API.
 type openaiRequest struct {
-	Model       string          `json:"model"`
-	Messages    []openaiMessage `json:"messages"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Temperature float64         `json:"temperature,omitempty"`
-	Stream      bool            `json:"stream,omitempty"`
+	Model          string                `json:"model"`
+	Messages       []openaiMessage       `json:"messages"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Temperature    float64               `json:"temperature,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openaiStreamOptions  `json:"stream_options,omitempty"`
+	Tools          []openaiTool          `json:"tools,omitempty"`
+	ResponseFormat *openaiResponseFormat `json:"response_format,omitempty"`
+	// ReasoningEffort is "low", "medium" or "high" for OpenAI reasoning models
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+}
+
+// openaiResponseFormat requests JSON output, constrained to a schema when
+// Type is "json_schema".
+type openaiResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openaiJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openaiJSONSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
 }
 
+// openaiStreamOptions asks the API to append a usage chunk to the stream.
+type openaiStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openaiMessage is a chat message. Servers hosting reasoning models return
+// the model's thinking in reasoning_content (DeepSeek) or reasoning (Groq,
+// LM Studio, OpenRouter); OpenAI itself does not return it.
 type openaiMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role             string              `json:"role"`
+	Content          string              `json:"content"`
+	ReasoningContent string              `json:"reasoning_content,omitempty"`
+	Reasoning        string              `json:"reasoning,omitempty"`
+	ToolCalls        []openaiToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID       string              `json:"tool_call_id,omitempty"`
+	Parts            []openaiContentPart `json:"-"` // Sent as content in place of Content when set
+}
+
+// openaiContentPart is one part of a multimodal user message.
+type openaiContentPart struct {
+	Type     string          `json:"type"` // text, image_url or file
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openaiImageURL `json:"image_url,omitempty"`
+	File     *openaiFile     `json:"file,omitempty"`
+}
+
+// openaiImageURL points at an image, here always a data: URL.
+type openaiImageURL struct {
+	URL string `json:"url"`
+}
+
+// openaiFile carries an inline file, such as a PDF.
+type openaiFile struct {
+	Filename string `json:"filename"`
+	FileData string `json:"file_data"`
+}
+
+// MarshalJSON sends Parts as the content array when the message has any.
+func (m openaiMessage) MarshalJSON() ([]byte, error) {
+	type plain openaiMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []openaiContentPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+// reasoning returns the thinking text, whichever field the server used.
+func (m openaiMessage) reasoning() string {
+	if m.ReasoningContent != "" {
+		return m.ReasoningContent
+	}
+	return m.Reasoning
+}
+
+// openaiTool represents a function tool definition, also used by Ollama.
+type openaiTool struct {
+	Type     string         `json:"type"`
+	Function openaiFunction `json:"function"`
+}
+
+type openaiFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// openaiToolCall represents a function call made by the model. In streams,
+// calls arrive in fragments keyed by Index.
+type openaiToolCall struct {
+	Index    int    `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
 }
 
 // openaiResponse represents the response from the OpenAI API.
@@ -57,11 +155,30 @@ type openaiResponse struct {
 		Message      openaiMessage `json:"message"`
 		FinishReason string        `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage openaiUsage `json:"usage"`
+}
+
+// openaiUsage represents token usage reported by OpenAI-style APIs.
+type openaiUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+// toUsage converts OpenAI usage into provider usage.
+func (u openaiUsage) toUsage() Usage {
+	return Usage{
+		InputTokens:     u.PromptTokens,
+		OutputTokens:    u.CompletionTokens,
+		CachedTokens:    u.PromptTokensDetails.CachedTokens,
+		ReasoningTokens: u.CompletionTokensDetails.ReasoningTokens,
+	}
 }
 
 // openaiStreamChunk represents a streaming chunk from the OpenAI API.
@@ -71,13 +188,11 @@ type openaiStreamChunk struct {
 	Created int64  `json:"created"`
 	Model   string `json:"model"`
 	Choices []struct {
-		Index int `json:"index"`
-		Delta struct {
-			Role    string `json:"role,omitempty"`
-			Content string `json:"content,omitempty"`
-		} `json:"delta"`
-		FinishReason string `json:"finish_reason"`
+		Index        int           `json:"index"`
+		Delta        openaiMessage `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
 	} `json:"choices"`
+	Usage *openaiUsage `json:"usage,omitempty"`
 }
 
 // openaiErrorResponse represents an error response from the API.
@@ -96,7 +211,7 @@ func (p *OpenAIProvider) Invoke(ctx context.Context, req Request) (*Response, er
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return nil, err
 	}
 
@@ -105,24 +220,26 @@ func (p *OpenAIProvider) Invoke(ctx context.Context, req Request) (*Response, er
 		maxTokens = p.maxTokens
 	}
 
-	messages := []openaiMessage{}
-	if req.SystemPrompt != "" {
-		messages = append(messages, openaiMessage{Role: "system", Content: req.SystemPrompt})
-	}
-	messages = append(messages, openaiMessage{Role: "user", Content: req.Prompt})
+	messages := buildOpenAIMessages(req)
 
 	apiReq := openaiRequest{
 		Model:     p.model,
 		Messages:  messages,
 		MaxTokens: maxTokens,
+		Tools:     buildOpenAITools(req.Tools),
 	}
 
+	if req.ResponseSchema != nil {
+		apiReq.ResponseFormat = openaiSchemaFormat(req.ResponseSchema)
+	}
+	apiReq.ReasoningEffort = reasoningEffort(req.ThinkingBudget)
+
 	if req.Temperature > 0 {
 		apiReq.Temperature = req.Temperature
 	}
 
 	headers := map[string]string{
-		"Authorization": "Bearer " + p.GetAPIKey(),
+		"Authorization": "Bearer " + p.GetAPIKey(ctx),
 	}
 
 	resp, err := p.DoRequest(ctx, http.MethodPost, openaiAPIURL, apiReq, headers)
@@ -139,9 +256,9 @@ func (p *OpenAIProvider) Invoke(ctx context.Context, req Request) (*Response, er
 	if resp.StatusCode != http.StatusOK {
 		var errResp openaiErrorResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error.Message)
+			return nil, NewAPIError(resp, errResp.Error.Message)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(resp, string(body))
 	}
 
 	var apiResp openaiResponse
@@ -153,11 +270,16 @@ func (p *OpenAIProvider) Invoke(ctx context.Context, req Request) (*Response, er
 		return nil, fmt.Errorf("no choices in response")
 	}
 
+	usage := apiResp.Usage.toUsage()
+
 	return &Response{
 		Content:      apiResp.Choices[0].Message.Content,
+		Reasoning:    apiResp.Choices[0].Message.reasoning(),
 		Model:        apiResp.Model,
 		FinishReason: apiResp.Choices[0].FinishReason,
-		TokensUsed:   apiResp.Usage.TotalTokens,
+		TokensUsed:   usage.Total(),
+		Usage:        usage,
+		ToolCalls:    fromOpenAIToolCalls(apiResp.Choices[0].Message.ToolCalls),
 	}, nil
 }
 
@@ -168,7 +290,7 @@ func (p *OpenAIProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return nil, err
 	}
 
@@ -177,25 +299,30 @@ func (p *OpenAIProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 		maxTokens = p.maxTokens
 	}
 
-	messages := []openaiMessage{}
-	if req.SystemPrompt != "" {
-		messages = append(messages, openaiMessage{Role: "system", Content: req.SystemPrompt})
-	}
-	messages = append(messages, openaiMessage{Role: "user", Content: req.Prompt})
+	messages := buildOpenAIMessages(req)
 
 	apiReq := openaiRequest{
 		Model:     p.model,
 		Messages:  messages,
 		MaxTokens: maxTokens,
 		Stream:    true,
+		StreamOptions: &openaiStreamOptions{
+			IncludeUsage: true,
+		},
+		Tools: buildOpenAITools(req.Tools),
+	}
+
+	if req.ResponseSchema != nil {
+		apiReq.ResponseFormat = openaiSchemaFormat(req.ResponseSchema)
 	}
+	apiReq.ReasoningEffort = reasoningEffort(req.ThinkingBudget)
 
 	if req.Temperature > 0 {
 		apiReq.Temperature = req.Temperature
 	}
 
 	headers := map[string]string{
-		"Authorization": "Bearer " + p.GetAPIKey(),
+		"Authorization": "Bearer " + p.GetAPIKey(ctx),
 	}
 
 	resp, err := p.DoRequest(ctx, http.MethodPost, openaiAPIURL, apiReq, headers)
@@ -208,37 +335,191 @@ func (p *OpenAIProvider) Stream(ctx context.Context, req Request) (<-chan Stream
 		body, _ := io.ReadAll(resp.Body)
 		var errResp openaiErrorResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error.Message)
+			return nil, NewAPIError(resp, errResp.Error.Message)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(resp, string(body))
 	}
 
 	out := make(chan StreamChunk, 100)
 
-	go p.ReadSSEStream(resp, out, func(data []byte) (string, bool, error) {
+	go p.ReadSSEStream(resp, out, newOpenAIStreamParser())
+
+	return out, nil
+}
+
+// newOpenAIStreamParser returns a parser converting Chat Completions stream
+// events into chunks. The stream is not ended on finish_reason because the
+// usage chunk, when requested, arrives afterwards; the [DONE] marker ends it
+// instead. Tool call fragments are accumulated and emitted on finish_reason.
+func newOpenAIStreamParser() func(data []byte) (StreamChunk, error) {
+	var calls []openaiToolCall
+
+	return func(data []byte) (StreamChunk, error) {
 		var chunk openaiStreamChunk
 		if err := json.Unmarshal(data, &chunk); err != nil {
-			return "", false, fmt.Errorf("parsing stream chunk: %w", err)
+			return StreamChunk{}, fmt.Errorf("parsing stream chunk: %w", err)
 		}
 
-		if len(chunk.Choices) == 0 {
-			return "", false, nil
+		var out StreamChunk
+		if chunk.Usage != nil {
+			usage := chunk.Usage.toUsage()
+			out.Usage = &usage
 		}
 
-		choice := chunk.Choices[0]
-		if choice.FinishReason != "" {
-			return "", true, nil
+		if len(chunk.Choices) > 0 {
+			choice := chunk.Choices[0]
+			out.Content = choice.Delta.Content
+			out.Reasoning = choice.Delta.reasoning()
+
+			for _, frag := range choice.Delta.ToolCalls {
+				for len(calls) <= frag.Index {
+					calls = append(calls, openaiToolCall{})
+				}
+				call := &calls[frag.Index]
+				if frag.ID != "" {
+					call.ID = frag.ID
+				}
+				if frag.Function.Name != "" {
+					call.Function.Name = frag.Function.Name
+				}
+				call.Function.Arguments += frag.Function.Arguments
+			}
+
+			if choice.FinishReason != "" && len(calls) > 0 {
+				out.ToolCalls = fromOpenAIToolCalls(calls)
+				calls = nil
+			}
 		}
 
-		return choice.Delta.Content, false, nil
-	})
+		return out, nil
+	}
+}
 
-	return out, nil
+// buildOpenAIMessages converts the request into Chat Completions messages,
+// leading with the system prompt when one is set.
+func buildOpenAIMessages(req Request) []openaiMessage {
+	conversation := req.Conversation()
+	messages := make([]openaiMessage, 0, len(conversation)+1)
+	if req.SystemPrompt != "" {
+		messages = append(messages, openaiMessage{Role: "system", Content: req.SystemPrompt})
+	}
+	for _, m := range conversation {
+		msg := openaiMessage{Role: m.Role, Content: messageText(m), ToolCallID: m.ToolCallID}
+		if media := mediaAttachments(m); len(media) > 0 {
+			msg.Parts = openaiContentParts(msg.Content, media)
+		}
+		for _, call := range m.ToolCalls {
+			tc := openaiToolCall{ID: call.ID, Type: "function"}
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = string(call.argumentsJSON())
+			msg.ToolCalls = append(msg.ToolCalls, tc)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// openaiContentParts renders a message's text and media attachments as
+// content parts: images as data URLs and PDFs as inline files.
+func openaiContentParts(text string, media []Attachment) []openaiContentPart {
+	parts := make([]openaiContentPart, 0, len(media)+1)
+	if text != "" {
+		parts = append(parts, openaiContentPart{Type: "text", Text: text})
+	}
+	for _, a := range media {
+		if a.Kind() == AttachmentDocument {
+			parts = append(parts, openaiContentPart{Type: "file", File: &openaiFile{Filename: a.Name, FileData: a.dataURL()}})
+		} else {
+			parts = append(parts, openaiContentPart{Type: "image_url", ImageURL: &openaiImageURL{URL: a.dataURL()}})
+		}
+	}
+	return parts
+}
+
+// openaiSchemaFormat builds a json_schema response format.
+func openaiSchemaFormat(s *ResponseSchema) *openaiResponseFormat {
+	return &openaiResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: &openaiJSONSchema{Name: s.Name, Schema: s.Schema},
+	}
+}
+
+// reasoningEffort maps a thinking budget onto OpenAI's effort levels, since
+// the API takes a level rather than a token count.
+func reasoningEffort(budget int) string {
+	switch {
+	case budget <= 0:
+		return ""
+	case budget <= 2048:
+		return "low"
+	case budget <= 16384:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// buildOpenAITools converts tool definitions into function tools.
+func buildOpenAITools(tools []Tool) []openaiTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]openaiTool, 0, len(tools))
+	for _, t := range tools {
+		out = append(out, openaiTool{
+			Type: "function",
+			Function: openaiFunction{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			},
+		})
+	}
+	return out
+}
+
+// fromOpenAIToolCalls converts API tool calls into provider tool calls.
+func fromOpenAIToolCalls(calls []openaiToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]ToolCall, 0, len(calls))
+	for _, c := range calls {
+		out = append(out, ToolCall{ID: c.ID, Name: c.Function.Name, Arguments: c.Function.Arguments})
+	}
+	return out
+}
+
+// Embed turns texts into vectors with OpenAI's embeddings API.
+func (p *OpenAIProvider) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
+		return nil, err
+	}
+	model := req.Model
+	if model == "" {
+		model = defaultOpenAIEmbeddingModel
+	}
+	headers := map[string]string{"Authorization": "Bearer " + p.GetAPIKey(ctx)}
+	return p.embedOpenAI(ctx, openaiEmbeddingsURL, headers, model, req)
+}
+
+// Capabilities reports the features of the Chat Completions API for the
+// provider's default model.
+func (p *OpenAIProvider) Capabilities() Capabilities {
+	return p.capabilitiesFor(p.model)
+}
+
+// capabilitiesFor reports the features of the Chat Completions API for model.
+// Only some models accept images.
+func (p *OpenAIProvider) capabilitiesFor(model string) Capabilities {
+	caps := Capabilities{Streaming: true, SystemPrompt: true, Tools: true, Documents: true, JSONMode: true, Embeddings: true}
+	caps.Images = openaiAcceptsImages(model)
+	return caps.withModelLimits(model)
 }
 
 // HealthCheck verifies the OpenAI API is accessible.
 func (p *OpenAIProvider) HealthCheck(ctx context.Context) error {
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return err
 	}
 
@@ -12,7 +12,11 @@ import (
 // This includes Groq, DeepSeek, Mistral, xAI, LM Studio, and generic endpoints.
 type OpenAICompatProvider struct {
 	*BaseProvider
-	endpoint string
+	endpoint       string
+	streamUsage    bool
+	jsonMode       string
+	images         bool
+	embeddingModel string
 }
 
 // OpenAICompatConfig holds configuration for creating an OpenAI-compatible provider.
@@ -22,6 +26,18 @@ type OpenAICompatConfig struct {
 	Endpoint  string
 	Model     string
 	MaxTokens int
+	// StreamUsage requests a trailing usage chunk via stream_options.
+	// Leave unset for servers that reject unknown request fields.
+	StreamUsage bool
+	// JSONMode selects how a ResponseSchema is sent: "" uses a json_schema
+	// response format, "json_object" sets only the JSON flag and describes the
+	// schema in the system prompt, and "prompt" only describes it.
+	JSONMode string
+	// Images marks models that accept image_url content parts.
+	Images bool
+	// EmbeddingModel is the default model for Embed; leave unset for
+	// servers without an /embeddings endpoint.
+	EmbeddingModel string
 }
 
 // NewOpenAICompatProvider creates a new OpenAI-compatible provider.
@@ -39,7 +55,11 @@ func NewOpenAICompatProvider(cfg OpenAICompatConfig) *OpenAICompatProvider {
 			Model:     cfg.Model,
 			MaxTokens: maxTokens,
 		}),
-		endpoint: cfg.Endpoint,
+		endpoint:       cfg.Endpoint,
+		streamUsage:    cfg.StreamUsage,
+		jsonMode:       cfg.JSONMode,
+		images:         cfg.Images,
+		embeddingModel: cfg.EmbeddingModel,
 	}
 }
 
@@ -50,7 +70,7 @@ func (p *OpenAICompatProvider) Invoke(ctx context.Context, req Request) (*Respon
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return nil, err
 	}
 
@@ -59,25 +79,23 @@ func (p *OpenAICompatProvider) Invoke(ctx context.Context, req Request) (*Respon
 		maxTokens = p.maxTokens
 	}
 
-	messages := []openaiMessage{}
-	if req.SystemPrompt != "" {
-		messages = append(messages, openaiMessage{Role: "system", Content: req.SystemPrompt})
-	}
-	messages = append(messages, openaiMessage{Role: "user", Content: req.Prompt})
+	messages := buildOpenAIMessages(req)
 
 	apiReq := openaiRequest{
 		Model:     p.model,
 		Messages:  messages,
 		MaxTokens: maxTokens,
+		Tools:     buildOpenAITools(req.Tools),
 	}
 
 	if req.Temperature > 0 {
 		apiReq.Temperature = req.Temperature
 	}
+	p.applySchema(&apiReq, req)
 
 	url := p.endpoint + "/chat/completions"
 	headers := map[string]string{}
-	if apiKey := p.GetAPIKey(); apiKey != "" {
+	if apiKey := p.GetAPIKey(ctx); apiKey != "" {
 		headers["Authorization"] = "Bearer " + apiKey
 	}
 
@@ -95,9 +113,9 @@ func (p *OpenAICompatProvider) Invoke(ctx context.Context, req Request) (*Respon
 	if resp.StatusCode != http.StatusOK {
 		var errResp openaiErrorResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error.Message)
+			return nil, NewAPIError(resp, errResp.Error.Message)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(resp, string(body))
 	}
 
 	var apiResp openaiResponse
@@ -109,11 +127,16 @@ func (p *OpenAICompatProvider) Invoke(ctx context.Context, req Request) (*Respon
 		return nil, fmt.Errorf("no choices in response")
 	}
 
+	usage := apiResp.Usage.toUsage()
+
 	return &Response{
 		Content:      apiResp.Choices[0].Message.Content,
+		Reasoning:    apiResp.Choices[0].Message.reasoning(),
 		Model:        apiResp.Model,
 		FinishReason: apiResp.Choices[0].FinishReason,
-		TokensUsed:   apiResp.Usage.TotalTokens,
+		TokensUsed:   usage.Total(),
+		Usage:        usage,
+		ToolCalls:    fromOpenAIToolCalls(apiResp.Choices[0].Message.ToolCalls),
 	}, nil
 }
 
@@ -124,7 +147,7 @@ func (p *OpenAICompatProvider) Stream(ctx context.Context, req Request) (<-chan
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return nil, err
 	}
 
@@ -133,26 +156,28 @@ func (p *OpenAICompatProvider) Stream(ctx context.Context, req Request) (<-chan
 		maxTokens = p.maxTokens
 	}
 
-	messages := []openaiMessage{}
-	if req.SystemPrompt != "" {
-		messages = append(messages, openaiMessage{Role: "system", Content: req.SystemPrompt})
-	}
-	messages = append(messages, openaiMessage{Role: "user", Content: req.Prompt})
+	messages := buildOpenAIMessages(req)
 
 	apiReq := openaiRequest{
 		Model:     p.model,
 		Messages:  messages,
 		MaxTokens: maxTokens,
 		Stream:    true,
+		Tools:     buildOpenAITools(req.Tools),
+	}
+
+	if p.streamUsage {
+		apiReq.StreamOptions = &openaiStreamOptions{IncludeUsage: true}
 	}
 
 	if req.Temperature > 0 {
 		apiReq.Temperature = req.Temperature
 	}
+	p.applySchema(&apiReq, req)
 
 	url := p.endpoint + "/chat/completions"
 	headers := map[string]string{}
-	if apiKey := p.GetAPIKey(); apiKey != "" {
+	if apiKey := p.GetAPIKey(ctx); apiKey != "" {
 		headers["Authorization"] = "Bearer " + apiKey
 	}
 
@@ -166,44 +191,62 @@ func (p *OpenAICompatProvider) Stream(ctx context.Context, req Request) (<-chan
 		body, _ := io.ReadAll(resp.Body)
 		var errResp openaiErrorResponse
 		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error.Message)
+			return nil, NewAPIError(resp, errResp.Error.Message)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(resp, string(body))
 	}
 
 	out := make(chan StreamChunk, 100)
 
-	go p.ReadSSEStream(resp, out, func(data []byte) (string, bool, error) {
-		var chunk openaiStreamChunk
-		if err := json.Unmarshal(data, &chunk); err != nil {
-			return "", false, fmt.Errorf("parsing stream chunk: %w", err)
-		}
+	go p.ReadSSEStream(resp, out, newOpenAIStreamParser())
 
-		if len(chunk.Choices) == 0 {
-			return "", false, nil
-		}
+	return out, nil
+}
 
-		choice := chunk.Choices[0]
-		if choice.FinishReason != "" {
-			return "", true, nil
-		}
+// Embed turns texts into vectors with the server's /embeddings endpoint.
+func (p *OpenAICompatProvider) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
+		return nil, err
+	}
+	model := req.Model
+	if model == "" {
+		model = p.embeddingModel
+	}
+	if model == "" {
+		return nil, fmt.Errorf("%s has no embedding model; set embedding_model in its model config", p.name)
+	}
 
-		return choice.Delta.Content, false, nil
-	})
+	headers := map[string]string{}
+	if apiKey := p.GetAPIKey(ctx); apiKey != "" {
+		headers["Authorization"] = "Bearer " + apiKey
+	}
+	return p.embedOpenAI(ctx, p.endpoint+"/embeddings", headers, model, req)
+}
 
-	return out, nil
+// Capabilities reports the features of an OpenAI-compatible server. JSON
+// mode is only claimed when the server is sent a response format.
+func (p *OpenAICompatProvider) Capabilities() Capabilities {
+	caps := Capabilities{
+		Streaming:    true,
+		SystemPrompt: true,
+		Tools:        true,
+		Images:       p.images,
+		JSONMode:     p.jsonMode != "prompt",
+		Embeddings:   p.embeddingModel != "",
+	}
+	return caps.withModelLimits(p.model)
 }
 
 // HealthCheck verifies the API is accessible.
 func (p *OpenAICompatProvider) HealthCheck(ctx context.Context) error {
-	if err := p.CheckAPIKeyRequired(); err != nil {
+	if err := p.CheckAPIKeyRequired(ctx); err != nil {
 		return err
 	}
 
 	// Try to list models
 	url := p.endpoint + "/models"
 	headers := map[string]string{}
-	if apiKey := p.GetAPIKey(); apiKey != "" {
+	if apiKey := p.GetAPIKey(ctx); apiKey != "" {
 		headers["Authorization"] = "Bearer " + apiKey
 	}
 
@@ -224,3 +267,22 @@ func (p *OpenAICompatProvider) HealthCheck(ctx context.Context) error {
 func (p *OpenAICompatProvider) SetEndpoint(endpoint string) {
 	p.endpoint = endpoint
 }
+
+// applySchema requests structured output according to the server's JSON mode.
+func (p *OpenAICompatProvider) applySchema(apiReq *openaiRequest, req Request) {
+	if req.ResponseSchema == nil {
+		return
+	}
+
+	switch p.jsonMode {
+	case "":
+		apiReq.ResponseFormat = openaiSchemaFormat(req.ResponseSchema)
+		return
+	case "json_object":
+		apiReq.ResponseFormat = &openaiResponseFormat{Type: "json_object"}
+	}
+
+	// The schema itself is only described in the system prompt
+	req.SystemPrompt = schemaSystemPrompt(req)
+	apiReq.Messages = buildOpenAIMessages(req)
+}
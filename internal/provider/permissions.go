@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+)
+
+// PermissionProfile names how much a CLI agent may do on its own. Each CLI
+// provider maps the profiles onto its own allowlist and sandbox flags.
+type PermissionProfile string
+
+const (
+	// ProfileReadOnly lets an agent read files but not change them or run commands.
+	ProfileReadOnly PermissionProfile = "read-only"
+	// ProfileWorkspaceWrite also lets an agent edit files in its working directory.
+	ProfileWorkspaceWrite PermissionProfile = "workspace-write"
+	// ProfileFull skips the CLI's permission checks entirely.
+	ProfileFull PermissionProfile = "full"
+)
+
+// DefaultPermissionProfile is the profile used unless another is chosen.
+const DefaultPermissionProfile = ProfileReadOnly
+
+// PermissionProfiles lists the profiles from most to least restricted.
+var PermissionProfiles = []PermissionProfile{ProfileReadOnly, ProfileWorkspaceWrite, ProfileFull}
+
+// ParsePermissionProfile validates a profile name.
+func ParsePermissionProfile(name string) (PermissionProfile, error) {
+	profile := PermissionProfile(name)
+	if !slices.Contains(PermissionProfiles, profile) {
+		return "", fmt.Errorf("unknown permission profile %q (want read-only, workspace-write or full)", name)
+	}
+	return profile, nil
+}
+
+// WithPermissions returns a copy of p restricted to profile, running in dir
+// unless it has a working directory of its own. Nothing is known about what a
+// CLI that declares no permission flags may do, so it only runs under the
+// full profile.
+func (p *CLIProvider) WithPermissions(profile PermissionProfile, dir string) (*CLIProvider, error) {
+	restricted := *p
+	restricted.profile = profile
+	if restricted.dir == "" {
+		restricted.dir = dir
+	}
+
+	args, ok := p.permissions[profile]
+	switch {
+	case ok:
+		restricted.profileArgs = args
+	case len(p.permissions) == 0 && profile != ProfileFull:
+		return nil, fmt.Errorf("%s declares no permission flags, so it can only run with the full profile", p.name)
+	case len(p.permissions) > 0:
+		return nil, fmt.Errorf("%s has no %q permission profile", p.name, profile)
+	}
+	return &restricted, nil
+}
+
+// ApplyPermissionProfile restricts every CLI provider in providers to profile
+// and pins it to dir. Other providers are returned unchanged.
+func ApplyPermissionProfile(providers []Provider, profile PermissionProfile, dir string) ([]Provider, error) {
+	out := make([]Provider, 0, len(providers))
+	for _, p := range providers {
+		if cli, ok := p.(*CLIProvider); ok {
+			restricted, err := cli.WithPermissions(profile, dir)
+			if err != nil {
+				return nil, err
+			}
+			p = restricted
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package provider
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setProcessGroup does nothing where process groups are unavailable.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// stopProcessGroup kills cmd's process. Without process groups, the tools it
+// spawned are left to exit on their own.
+func stopProcessGroup(cmd *exec.Cmd, grace time.Duration) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package provider
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts cmd in a process group of its own, so the tools an
+// agent spawns can be stopped along with it.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// stopProcessGroup sends SIGTERM to cmd's process group, then SIGKILL after
+// grace to whatever is left of it. The SIGKILL goes ahead even once cmd has
+// exited, since tools it started may ignore SIGTERM and outlive it; the
+// group's ID is not reused while any of them is alive.
+func stopProcessGroup(cmd *exec.Cmd, grace time.Duration) error {
+	pgid := -cmd.Process.Pid
+	if err := syscall.Kill(pgid, syscall.SIGTERM); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+	time.AfterFunc(grace, func() {
+		syscall.Kill(pgid, syscall.SIGKILL)
+	})
+	return nil
+}
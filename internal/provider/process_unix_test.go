@@ -0,0 +1,127 @@
+//go:build unix
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCLIProviderCancelStopsProcessGroup(t *testing.T) {
+	stopped := filepath.Join(t.TempDir(), "stopped")
+	// The CLI starts a helper of its own, as agents do for tools. The helper
+	// announces itself once its trap is set, so the cancel can't beat it
+	script := `(trap 'echo > "$1"; exit 0' TERM; echo started; while :; do sleep 0.1; done) &
+wait`
+	p := NewCLIProvider(CLIProviderConfig{
+		Name:    "agent-cli",
+		Command: "sh",
+		Args:    []string{"-c", script, "sh", stopped},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := p.Stream(ctx, Request{Prompt: "x"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if chunk := <-ch; chunk.Content != "started\n" {
+		t.Fatalf("first chunk = %+v", chunk)
+	}
+	cancel()
+	for range ch {
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		if _, err := os.Stat(stopped); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("helper process was not sent SIGTERM")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestCLIProviderCancelKillsStubbornProcessGroup(t *testing.T) {
+	beat := filepath.Join(t.TempDir(), "beat")
+	// The helper ignores SIGTERM and keeps writing until it is killed
+	script := `(trap '' TERM; echo started; while :; do date +%s%N > "$1"; sleep 0.02; done) &
+wait`
+	p := NewCLIProvider(CLIProviderConfig{
+		Name:    "agent-cli",
+		Command: "sh",
+		Args:    []string{"-c", script, "sh", beat},
+	})
+	p.stopGrace = 200 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := p.Stream(ctx, Request{Prompt: "x"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if chunk := <-ch; chunk.Content != "started\n" {
+		t.Fatalf("first chunk = %+v", chunk)
+	}
+	cancel()
+	for range ch {
+	}
+
+	// Once the stream has ended the helper must have stopped writing
+	time.Sleep(100 * time.Millisecond)
+	last, _ := os.ReadFile(beat)
+	time.Sleep(200 * time.Millisecond)
+	if now, _ := os.ReadFile(beat); string(now) != string(last) {
+		t.Fatal("helper ignoring SIGTERM was not sent SIGKILL")
+	}
+}
+
+func TestCLIProviderCancelKillsGroupAfterLeaderExits(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	// The helper ignores SIGTERM and, with its output elsewhere, doesn't keep
+	// the CLI's stream open once the CLI itself exits on SIGTERM
+	script := `echo $$ > "$1"
+(trap '' TERM; exec sleep 30) > /dev/null 2>&1 &
+echo started
+wait`
+	p := NewCLIProvider(CLIProviderConfig{
+		Name:    "agent-cli",
+		Command: "sh",
+		Args:    []string{"-c", script, "sh", pidFile},
+	})
+	p.stopGrace = 200 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := p.Stream(ctx, Request{Prompt: "x"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if chunk := <-ch; chunk.Content != "started\n" {
+		t.Fatalf("first chunk = %+v", chunk)
+	}
+	data, _ := os.ReadFile(pidFile)
+	pgid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("reading the CLI's pid: %v", err)
+	}
+	cancel()
+	for range ch {
+	}
+
+	// The killed helper counts as a member until init reaps it, so allow
+	// for a slow init
+	deadline := time.Now().Add(p.stopGrace + 5*time.Second)
+	for syscall.Kill(-pgid, 0) != syscall.ESRCH {
+		if time.Now().After(deadline) {
+			syscall.Kill(-pgid, syscall.SIGKILL)
+			t.Fatal("helper ignoring SIGTERM outlived the CLI")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
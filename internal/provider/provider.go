@@ -3,24 +3,145 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jxmullins/thekanbansociety/internal/config"
 )
 
-// StreamChunk represents a piece of streaming response.
+// StreamChunk represents a piece of streaming response. A model's thinking
+// arrives in Reasoning and is never mixed into Content, so callers that only
+// read Content see just the answer.
 type StreamChunk struct {
-	Content string
-	Done    bool
-	Error   error
+	Content   string
+	Reasoning string // Thinking produced before or between answer text
+	Done      bool
+	Error     error
+	Usage     *Usage     // Set on the final chunk when the provider reports usage
+	ToolCalls []ToolCall // Complete tool calls, sent once their arguments have arrived
+
+	// AgentToolCalls are tools an agent ran on its own, such as a CLI agent
+	// editing files. They are reported for display and must not be run again.
+	AgentToolCalls []ToolCall
+
+	Cached bool // Replayed from the response cache, so Usage was not spent again
+}
+
+// Usage holds the token counts reported by a provider for one invocation.
+// OutputTokens includes any reasoning tokens; InputTokens includes cached ones.
+type Usage struct {
+	InputTokens     int
+	OutputTokens    int
+	CachedTokens    int     // Input tokens served from the provider's prompt cache
+	ReasoningTokens int     // Output tokens spent on hidden reasoning
+	CostUSD         float64 // Cost reported by the provider itself, if it does (CLI agents)
+}
+
+// Total returns the combined input and output token count.
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// add sums other into u, for totals across several invocations.
+func (u *Usage) add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CachedTokens += other.CachedTokens
+	u.ReasoningTokens += other.ReasoningTokens
+	u.CostUSD += other.CostUSD
+}
+
+// merge overlays the non-zero counts from other, for providers that report
+// input and output usage in separate stream events.
+func (u *Usage) merge(other Usage) {
+	if other.InputTokens != 0 {
+		u.InputTokens = other.InputTokens
+	}
+	if other.OutputTokens != 0 {
+		u.OutputTokens = other.OutputTokens
+	}
+	if other.CachedTokens != 0 {
+		u.CachedTokens = other.CachedTokens
+	}
+	if other.ReasoningTokens != 0 {
+		u.ReasoningTokens = other.ReasoningTokens
+	}
+	if other.CostUSD != 0 {
+		u.CostUSD = other.CostUSD
+	}
+}
+
+// Message roles used in multi-turn conversations.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool" // Result of a tool call, answering an assistant turn
+)
+
+// Message represents a single turn in a multi-turn conversation.
+type Message struct {
+	Role       string // RoleUser, RoleAssistant or RoleTool
+	Content    string
+	ToolCalls  []ToolCall // Calls requested by an assistant turn
+	ToolCallID string     // For RoleTool: the call this result answers
+	ToolName   string     // For RoleTool: the tool that produced the result
+
+	// Attachments are files sent with a user turn. Omitted when empty so
+	// cache and cassette keys of plain requests are unchanged.
+	Attachments []Attachment `json:",omitempty"`
 }
 
 // Request holds the parameters for an AI invocation.
+//
+// Messages carries prior conversation turns. When Prompt is also set it is
+// sent as the final user turn, so single-shot callers can keep using Prompt.
 type Request struct {
-	Prompt       string
-	SystemPrompt string
-	MaxTokens    int
-	Temperature  float64
+	Prompt         string
+	Messages       []Message
+	SystemPrompt   string
+	MaxTokens      int
+	Temperature    float64
+	Tools          []Tool          // Functions the model may call; see Registry.InvokeWithTools
+	ResponseSchema *ResponseSchema // Requests a JSON reply; see Registry.InvokeJSON
+	ThinkingBudget int             // Tokens the model may spend reasoning (0 = provider default)
+	Attachments    []Attachment    // Files sent with Prompt; see LoadAttachment
+}
+
+// Conversation returns the full list of turns to send, with Prompt appended
+// as the final user message when set.
+func (r *Request) Conversation() []Message {
+	messages := make([]Message, 0, len(r.Messages)+1)
+	messages = append(messages, r.Messages...)
+	if r.Prompt != "" {
+		messages = append(messages, Message{Role: RoleUser, Content: r.Prompt, Attachments: r.Attachments})
+	}
+	return messages
+}
+
+// FlattenMessages renders a conversation as a single prompt for backends
+// that only accept one block of text, such as CLI tools.
+func FlattenMessages(messages []Message) string {
+	if len(messages) == 1 && messages[0].Role == RoleUser {
+		return messageText(messages[0])
+	}
+
+	var b strings.Builder
+	for i, m := range messages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		label := "User"
+		switch m.Role {
+		case RoleAssistant:
+			label = "Assistant"
+		case RoleTool:
+			label = "Tool " + m.ToolName
+		}
+		b.WriteString(fmt.Sprintf("[%s]\n%s", label, messageText(m)))
+	}
+	return b.String()
 }
 
 // MaxPromptLength is the maximum allowed length for prompts to prevent DOS attacks
@@ -31,6 +152,19 @@ func (r *Request) Validate() error {
 	if len(r.Prompt) > MaxPromptLength {
 		return fmt.Errorf("prompt too long: %d bytes (max: %d)", len(r.Prompt), MaxPromptLength)
 	}
+	total := len(r.Prompt)
+	for i, m := range r.Messages {
+		if m.Role != RoleUser && m.Role != RoleAssistant && m.Role != RoleTool {
+			return fmt.Errorf("message %d: invalid role %q", i, m.Role)
+		}
+		total += len(m.Content)
+	}
+	if total > MaxPromptLength {
+		return fmt.Errorf("conversation too long: %d bytes (max: %d)", total, MaxPromptLength)
+	}
+	if r.Prompt == "" && len(r.Messages) == 0 {
+		return fmt.Errorf("request has no prompt or messages")
+	}
 	if len(r.SystemPrompt) > MaxPromptLength {
 		return fmt.Errorf("system prompt too long: %d bytes (max: %d)", len(r.SystemPrompt), MaxPromptLength)
 	}
@@ -43,9 +177,32 @@ func (r *Request) Validate() error {
 // Response holds the result of an AI invocation.
 type Response struct {
 	Content      string
+	Reasoning    string // Thinking returned separately from the answer, if any
 	Model        string
 	FinishReason string
-	TokensUsed   int
+	TokensUsed   int // Usage.Total(), kept for existing callers
+	Usage        Usage
+	AIID         string // AI ID that answered, set by Registry (differs from the requested ID after a fallback)
+	Cached       bool   // Served from the response cache
+	ToolCalls    []ToolCall
+	QueueWait    time.Duration // Time held back by rate limits before the call was sent
+	Partial      bool          // Cut off by an error, returned alongside it; see StreamResumable
+}
+
+// Fallback describes the Registry moving from a failed model to the next one
+// in its fallback chain.
+type Fallback struct {
+	From string // AI ID that failed
+	To   string // AI ID tried next
+	Err  error  // Failure that triggered the fallback
+}
+
+type fallbackHandlerKey struct{}
+
+// WithFallbackHandler has fn called whenever a call made with ctx moves down
+// a model's fallback chain.
+func WithFallbackHandler(ctx context.Context, fn func(Fallback)) context.Context {
+	return context.WithValue(ctx, fallbackHandlerKey{}, fn)
 }
 
 // Provider defines the interface that all AI provider adapters must implement.
@@ -67,6 +224,13 @@ type Provider interface {
 type Registry struct {
 	providers map[string]Provider
 	models    map[string]config.ModelConfig
+	perModel  map[string]Provider // Providers bound to a single model, checked first
+	retry     RetryConfig
+	cache     *ResponseCache
+	cassette  *Cassette
+	limiters  map[string]*RateLimiter // Keyed by "provider <name>" or "model <aiID>"
+
+	middleware []Middleware
 }
 
 // NewRegistry creates a new provider registry.
@@ -74,6 +238,8 @@ func NewRegistry() *Registry {
 	return &Registry{
 		providers: make(map[string]Provider),
 		models:    make(map[string]config.ModelConfig),
+		perModel:  make(map[string]Provider),
+		limiters:  make(map[string]*RateLimiter),
 	}
 }
 
@@ -82,9 +248,52 @@ func (r *Registry) Register(p Provider) {
 	r.providers[p.Name()] = p
 }
 
+// SetRetryConfig sets the retry policy applied to calls made through the registry.
+func (r *Registry) SetRetryConfig(cfg RetryConfig) {
+	r.retry = cfg
+}
+
+// SetCache enables response caching for calls made through the registry.
+// Passing nil disables it.
+func (r *Registry) SetCache(cache *ResponseCache) {
+	r.cache = cache
+}
+
+// SetCassette records every call made through the registry to c, or serves
+// calls from it when c is in replay mode. Passing nil disables it.
+func (r *Registry) SetCassette(c *Cassette) {
+	r.cassette = c
+}
+
+// SetRateLimits sets the limits applied to each provider, keyed by provider
+// name. Limits for a single model are taken from its ModelConfig.
+func (r *Registry) SetRateLimits(limits map[string]config.RateLimitConfig) {
+	for name, cfg := range limits {
+		key := "provider " + name
+		if l := NewRateLimiter(key, cfg); l != nil {
+			r.limiters[key] = l
+		} else {
+			delete(r.limiters, key)
+		}
+	}
+}
+
+// RegisterModelProvider binds a provider to a single model ID, taking
+// precedence over the provider registered under the model's provider name.
+func (r *Registry) RegisterModelProvider(aiID string, p Provider) {
+	r.perModel[aiID] = p
+}
+
 // RegisterModel adds a model configuration to the registry.
 func (r *Registry) RegisterModel(aiID string, cfg config.ModelConfig) {
 	r.models[aiID] = cfg
+
+	key := "model " + aiID
+	if l := NewRateLimiter(key, cfg.RateLimit); l != nil {
+		r.limiters[key] = l
+	} else {
+		delete(r.limiters, key)
+	}
 }
 
 // RegisterModels adds multiple model configurations from a config.
@@ -107,6 +316,10 @@ func (r *Registry) GetForModel(aiID string) (Provider, config.ModelConfig, error
 		return nil, config.ModelConfig{}, fmt.Errorf("model %q not found in registry", aiID)
 	}
 
+	if provider, ok := r.perModel[aiID]; ok {
+		return provider, modelCfg, nil
+	}
+
 	provider, ok := r.providers[modelCfg.Provider]
 	if !ok {
 		return nil, config.ModelConfig{}, fmt.Errorf("provider %q not registered for model %q", modelCfg.Provider, aiID)
@@ -133,34 +346,204 @@ func (r *Registry) ListModels() []string {
 	return ids
 }
 
-// Invoke is a convenience method to invoke a model by AI ID.
+// Invoke is a convenience method to invoke a model by AI ID. If the model
+// fails, its configured fallbacks are tried in order.
 func (r *Registry) Invoke(ctx context.Context, aiID string, req Request) (*Response, error) {
-	// Try model lookup first
-	provider, _, err := r.GetForModel(aiID)
-	if err != nil {
-		// Fall back to direct provider lookup (for CLI providers)
-		var ok bool
-		provider, ok = r.Get(aiID)
-		if !ok {
-			return nil, fmt.Errorf("no provider found for %q", aiID)
+	chain := r.fallbackChain(aiID)
+	errs := make([]error, 0, len(chain))
+
+	for i, id := range chain {
+		provider, err := r.resolve(id)
+		if err == nil {
+			err = r.checkAttachments(id, req)
+		}
+		if err == nil {
+			var resp *Response
+			resp, err = provider.Invoke(ctx, r.withModelDefaults(id, req))
+			if err == nil {
+				resp.AIID = id
+				return resp, nil
+			}
+		}
+		errs = append(errs, err)
+		if !r.fallBack(ctx, chain, i, err) {
+			break
 		}
 	}
 
-	return provider.Invoke(ctx, req)
+	return nil, chainError(aiID, errs)
 }
 
-// Stream is a convenience method to stream a model by AI ID.
+// Stream is a convenience method to stream a model by AI ID. If the model
+// fails before producing its first chunk, its configured fallbacks are tried
+// in order.
 func (r *Registry) Stream(ctx context.Context, aiID string, req Request) (<-chan StreamChunk, error) {
-	// Try model lookup first
-	provider, _, err := r.GetForModel(aiID)
+	chain := r.fallbackChain(aiID)
+	if len(chain) == 1 {
+		provider, err := r.resolve(aiID)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.checkAttachments(aiID, req); err != nil {
+			return nil, err
+		}
+		return provider.Stream(ctx, r.withModelDefaults(aiID, req))
+	}
+
+	errs := make([]error, 0, len(chain))
+	for i, id := range chain {
+		provider, err := r.resolve(id)
+		if err == nil {
+			err = r.checkAttachments(id, req)
+		}
+		if err == nil {
+			var ch <-chan StreamChunk
+			ch, err = startStream(ctx, provider, r.withModelDefaults(id, req))
+			if err == nil {
+				return ch, nil
+			}
+		}
+		errs = append(errs, err)
+		if !r.fallBack(ctx, chain, i, err) {
+			break
+		}
+	}
+
+	return nil, chainError(aiID, errs)
+}
+
+// withModelDefaults fills request settings the caller left unset from the
+// model's configuration.
+func (r *Registry) withModelDefaults(aiID string, req Request) Request {
+	if req.ThinkingBudget == 0 {
+		req.ThinkingBudget = r.models[aiID].ThinkingBudget
+	}
+	return req
+}
+
+// fallbackChain returns aiID followed by its configured fallbacks, without
+// duplicates. Fallbacks of fallbacks are not followed.
+func (r *Registry) fallbackChain(aiID string) []string {
+	chain := []string{aiID}
+	seen := map[string]bool{aiID: true}
+	for _, id := range r.models[aiID].Fallbacks {
+		if !seen[id] {
+			seen[id] = true
+			chain = append(chain, id)
+		}
+	}
+	return chain
+}
+
+// fallBack reports whether the call should move on from chain[i] after err,
+// notifying the context's fallback handler if it does.
+func (r *Registry) fallBack(ctx context.Context, chain []string, i int, err error) bool {
+	if i+1 >= len(chain) || ctx.Err() != nil {
+		return false
+	}
+	if fn, ok := ctx.Value(fallbackHandlerKey{}).(func(Fallback)); ok {
+		fn(Fallback{From: chain[i], To: chain[i+1], Err: err})
+	}
+	return true
+}
+
+// chainError builds the error returned when every model in a chain failed.
+func chainError(aiID string, errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return fmt.Errorf("%q and its fallbacks failed: %w", aiID, errors.Join(errs...))
+}
+
+// resolve finds the provider for an AI ID and wraps it with the rate limits,
+// retry policy, response cache, cassette and middleware.
+func (r *Registry) resolve(aiID string) (Provider, error) {
+	// Replays never reach a live provider, so the AI need not be registered
+	if r.cassette != nil && r.cassette.Mode() == CassetteReplay {
+		return r.applyMiddleware(aiID, r.models[aiID].Model, r.cassette.Wrap(aiID, nil)), nil
+	}
+
+	provider, modelCfg, err := r.lookup(aiID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Limit each attempt rather than the whole retried call, so retries queue too
+	if limiters := r.limitersFor(aiID, provider.Name()); len(limiters) > 0 {
+		provider = NewRateLimitedProvider(provider, aiID, limiters)
+	}
+	if r.retry.MaxRetries > 0 {
+		provider = NewRetryingProvider(provider, r.retry)
+	}
+
+	// CLI agents may act on the workspace, so replaying them would skip their side effects
+	if _, isCLI := provider.(*CLIProvider); r.cache != nil && !isCLI {
+		provider = NewCachingProvider(provider, r.cache, aiID, modelCfg.Model)
+	}
+	if r.cassette != nil {
+		provider = r.cassette.Wrap(aiID, provider)
+	}
+	return r.applyMiddleware(aiID, modelCfg.Model, provider), nil
+}
+
+// lookup finds the unwrapped provider for an AI ID: the provider configured
+// for the model, or for CLI providers, the provider registered under the ID.
+func (r *Registry) lookup(aiID string) (Provider, config.ModelConfig, error) {
+	provider, modelCfg, err := r.GetForModel(aiID)
 	if err != nil {
-		// Fall back to direct provider lookup (for CLI providers)
 		var ok bool
 		provider, ok = r.Get(aiID)
 		if !ok {
-			return nil, fmt.Errorf("no provider found for %q", aiID)
+			return nil, config.ModelConfig{}, fmt.Errorf("no provider found for %q", aiID)
 		}
 	}
+	return provider, modelCfg, nil
+}
 
-	return provider.Stream(ctx, req)
+// Capabilities returns what the provider behind an AI ID supports, with
+// limits for the configured model and any limits set in its config. Replayed
+// calls never reach a provider, so during replay every feature is reported.
+func (r *Registry) Capabilities(aiID string) (Capabilities, error) {
+	if r.cassette != nil && r.cassette.Mode() == CassetteReplay {
+		return Capabilities{Streaming: true, SystemPrompt: true, Tools: true, Images: true, Documents: true, JSONMode: true, Embeddings: true}, nil
+	}
+
+	provider, modelCfg, err := r.lookup(aiID)
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	caps := CapabilitiesOf(provider)
+	if modelCfg.Model != "" {
+		if m, ok := provider.(modelCapabilityReporter); ok {
+			caps = m.capabilitiesFor(modelCfg.Model)
+		}
+		caps = caps.withModelLimits(modelCfg.Model)
+	}
+	if modelCfg.Vision {
+		caps.Images = true
+	}
+	if modelCfg.ContextWindow > 0 {
+		caps.ContextWindow = modelCfg.ContextWindow
+	}
+	if modelCfg.MaxOutputTokens > 0 {
+		caps.MaxOutputTokens = modelCfg.MaxOutputTokens
+	}
+	if _, ok := provider.(Embedder); ok && modelCfg.EmbeddingModel != "" {
+		caps.Embeddings = true
+	}
+	return caps, nil
+}
+
+// limitersFor returns the rate limiters that apply to a model: its own, then
+// its provider's.
+func (r *Registry) limitersFor(aiID, providerName string) []*RateLimiter {
+	var limiters []*RateLimiter
+	if l, ok := r.limiters["model "+aiID]; ok {
+		limiters = append(limiters, l)
+	}
+	if l, ok := r.limiters["provider "+providerName]; ok {
+		limiters = append(limiters, l)
+	}
+	return limiters
 }
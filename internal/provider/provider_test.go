@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+func TestRequestConversation(t *testing.T) {
+	req := Request{
+		Messages: []Message{
+			{Role: RoleUser, Content: "first"},
+			{Role: RoleAssistant, Content: "reply"},
+		},
+		Prompt: "second",
+	}
+
+	conv := req.Conversation()
+	if len(conv) != 3 {
+		t.Fatalf("Conversation() length = %d, want 3", len(conv))
+	}
+	if conv[2].Role != RoleUser || conv[2].Content != "second" {
+		t.Errorf("last message = %+v, want user prompt", conv[2])
+	}
+
+	// Conversation must not alias the caller's slice
+	conv[0].Content = "changed"
+	if req.Messages[0].Content != "first" {
+		t.Error("Conversation() modified request messages")
+	}
+}
+
+func TestRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     Request
+		wantErr bool
+	}{
+		{"prompt only", Request{Prompt: "hi"}, false},
+		{"messages only", Request{Messages: []Message{{Role: RoleUser, Content: "hi"}}}, false},
+		{"empty", Request{}, true},
+		{"bad role", Request{Messages: []Message{{Role: "system", Content: "hi"}}}, true},
+		{"bad temperature", Request{Prompt: "hi", Temperature: 3}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFlattenMessages(t *testing.T) {
+	single := FlattenMessages([]Message{{Role: RoleUser, Content: "just this"}})
+	if single != "just this" {
+		t.Errorf("single message flattened to %q", single)
+	}
+
+	multi := FlattenMessages([]Message{
+		{Role: RoleUser, Content: "question"},
+		{Role: RoleAssistant, Content: "answer"},
+		{Role: RoleUser, Content: "follow-up"},
+	})
+	for _, want := range []string{"[User]\nquestion", "[Assistant]\nanswer", "[User]\nfollow-up"} {
+		if !strings.Contains(multi, want) {
+			t.Errorf("flattened conversation missing %q:\n%s", want, multi)
+		}
+	}
+}
+
+func TestReadSSEStreamUsage(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"choices":[{"delta":{"content":"Hel"}}]}`,
+		`data: {"choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+		`data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":5,"prompt_tokens_details":{"cached_tokens":4}}}`,
+		`data: [DONE]`,
+	}, "\n\n")
+
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader(body))}
+	out := make(chan StreamChunk, 10)
+	b := NewBaseProvider(BaseConfig{Name: "test"})
+	go b.ReadSSEStream(resp, out, newOpenAIStreamParser())
+
+	var content strings.Builder
+	var final StreamChunk
+	for chunk := range out {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected error: %v", chunk.Error)
+		}
+		content.WriteString(chunk.Content)
+		if chunk.Done {
+			final = chunk
+		}
+	}
+
+	if content.String() != "Hello" {
+		t.Errorf("content = %q, want Hello", content.String())
+	}
+	if final.Usage == nil {
+		t.Fatal("final chunk has no usage")
+	}
+	want := Usage{InputTokens: 12, OutputTokens: 5, CachedTokens: 4}
+	if *final.Usage != want {
+		t.Errorf("usage = %+v, want %+v", *final.Usage, want)
+	}
+}
+
+func TestOpenAIStreamReasoning(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"choices":[{"delta":{"reasoning_content":"Check the units."}}]}`,
+		`data: {"choices":[{"delta":{"content":"42"},"finish_reason":"stop"}]}`,
+		`data: [DONE]`,
+	}, "\n\n")
+
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader(body))}
+	out := make(chan StreamChunk, 10)
+	b := NewBaseProvider(BaseConfig{Name: "test"})
+	go b.ReadSSEStream(resp, out, newOpenAIStreamParser())
+
+	var content, reasoning strings.Builder
+	for chunk := range out {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected error: %v", chunk.Error)
+		}
+		content.WriteString(chunk.Content)
+		reasoning.WriteString(chunk.Reasoning)
+	}
+
+	if content.String() != "42" {
+		t.Errorf("content = %q, want reasoning kept out of content", content.String())
+	}
+	if reasoning.String() != "Check the units." {
+		t.Errorf("reasoning = %q", reasoning.String())
+	}
+}
+
+func TestApplyAnthropicThinking(t *testing.T) {
+	apiReq := anthropicRequest{MaxTokens: 1000}
+	applyAnthropicThinking(&apiReq, Request{Prompt: "think", ThinkingBudget: 500})
+
+	if apiReq.Thinking == nil || apiReq.Thinking.BudgetTokens != anthropicMinThinkingBudget {
+		t.Fatalf("Thinking = %+v, want the minimum budget", apiReq.Thinking)
+	}
+	if apiReq.MaxTokens <= apiReq.Thinking.BudgetTokens {
+		t.Errorf("MaxTokens = %d leaves no room for the answer", apiReq.MaxTokens)
+	}
+}
+
+// namedProvider is a flakyProvider registered under a custom name.
+type namedProvider struct {
+	flakyProvider
+	name string
+}
+
+func (n *namedProvider) Name() string { return n.name }
+
+func TestRegistryFallback(t *testing.T) {
+	primary := &namedProvider{name: "primary", flakyProvider: flakyProvider{errs: []error{errors.New("quota exceeded")}}}
+	backup := &namedProvider{name: "backup"}
+
+	r := NewRegistry()
+	r.Register(primary)
+	r.Register(backup)
+	r.RegisterModel("main", config.ModelConfig{Provider: "primary", Fallbacks: []string{"missing", "local"}})
+	r.RegisterModel("local", config.ModelConfig{Provider: "backup"})
+
+	var hops []Fallback
+	ctx := WithFallbackHandler(context.Background(), func(fb Fallback) { hops = append(hops, fb) })
+
+	resp, err := r.Invoke(ctx, "main", Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if resp.AIID != "local" {
+		t.Errorf("AIID = %q, want local", resp.AIID)
+	}
+	if len(hops) != 2 || hops[0].To != "missing" || hops[1].From != "missing" {
+		t.Errorf("fallback hops = %+v", hops)
+	}
+}
@@ -0,0 +1,279 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+// QueueWait describes a call held back by a rate limit before it was sent.
+type QueueWait struct {
+	AIID    string        // AI ID being called
+	Limiter string        // Limiter that held the call, e.g. "provider anthropic"
+	Wait    time.Duration // Time spent queued
+}
+
+type queueHandlerKey struct{}
+
+// WithQueueHandler has fn called whenever a rate limit holds back a call
+// made with ctx, with the time it spent queued.
+func WithQueueHandler(ctx context.Context, fn func(QueueWait)) context.Context {
+	return context.WithValue(ctx, queueHandlerKey{}, fn)
+}
+
+// RateLimiter enforces requests-per-minute, tokens-per-minute and
+// concurrency limits for one provider or model. Token use is estimated when a
+// call starts and corrected from the reported usage when it finishes.
+type RateLimiter struct {
+	name     string
+	requests *tokenBucket
+	tokens   *tokenBucket
+	slots    chan struct{}
+}
+
+// NewRateLimiter creates a limiter for cfg. It returns nil when cfg sets no
+// limits.
+func NewRateLimiter(name string, cfg config.RateLimitConfig) *RateLimiter {
+	if cfg.RequestsPerMinute <= 0 && cfg.TokensPerMinute <= 0 && cfg.MaxConcurrent <= 0 {
+		return nil
+	}
+	l := &RateLimiter{
+		name:     name,
+		requests: newTokenBucket(cfg.RequestsPerMinute),
+		tokens:   newTokenBucket(cfg.TokensPerMinute),
+	}
+	if cfg.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return l
+}
+
+// Name returns the limiter's name.
+func (l *RateLimiter) Name() string {
+	return l.name
+}
+
+// Acquire blocks until a call estimated to use tokens may start, and returns
+// how long it was held back. Every successful Acquire must be paired with a
+// Release.
+func (l *RateLimiter) Acquire(ctx context.Context, tokens int) (time.Duration, error) {
+	var waited time.Duration
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			start := time.Now()
+			select {
+			case l.slots <- struct{}{}:
+				waited = time.Since(start)
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			}
+		}
+	}
+
+	delay := max(l.requests.take(1), l.tokens.take(float64(tokens)))
+	if delay > 0 {
+		if err := sleepContext(ctx, delay); err != nil {
+			l.Cancel(tokens)
+			return 0, err
+		}
+		waited += delay
+	}
+	return waited, nil
+}
+
+// Release ends a call started with Acquire, correcting its token estimate
+// with the tokens actually used. A used count of zero keeps the estimate.
+func (l *RateLimiter) Release(estimated, used int) {
+	if used > 0 {
+		l.tokens.put(float64(estimated - used))
+	}
+	l.releaseSlot()
+}
+
+// Cancel ends a call started with Acquire that was never sent, returning its
+// request and estimated tokens as well as its slot.
+func (l *RateLimiter) Cancel(estimated int) {
+	l.requests.put(1)
+	l.tokens.put(float64(estimated))
+	l.releaseSlot()
+}
+
+func (l *RateLimiter) releaseSlot() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// tokenBucket refills continuously up to one minute's allowance. Callers
+// take what they need up front and the level may go negative; the deficit is
+// how long they must wait, which keeps callers served in arrival order.
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	rate     float64 // Refill per second
+	level    float64
+	last     time.Time
+}
+
+// newTokenBucket returns a full bucket, or nil when perMinute is unlimited.
+func newTokenBucket(perMinute int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		level:    float64(perMinute),
+		last:     time.Now(),
+	}
+}
+
+// take removes n from the bucket and returns how long until the bucket is
+// back in credit. Requests larger than the bucket are capped at its capacity
+// so they can still run once it is full.
+func (b *tokenBucket) take(n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.level -= min(n, b.capacity)
+	if b.level >= 0 {
+		return 0
+	}
+	return time.Duration(-b.level / b.rate * float64(time.Second))
+}
+
+// put returns n to the bucket, or removes it when n is negative.
+func (b *tokenBucket) put(n float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.level = min(b.level+n, b.capacity)
+}
+
+func (b *tokenBucket) refill() {
+	now := time.Now()
+	b.level = min(b.level+now.Sub(b.last).Seconds()*b.rate, b.capacity)
+	b.last = now
+}
+
+// RateLimitedProvider wraps a Provider and holds calls until every one of
+// its limiters has room for them.
+type RateLimitedProvider struct {
+	Provider
+	aiID     string
+	limiters []*RateLimiter
+}
+
+// NewRateLimitedProvider wraps p with the given limiters. The context's queue
+// handler, if any, is called for each limiter that holds a call back.
+func NewRateLimitedProvider(p Provider, aiID string, limiters []*RateLimiter) *RateLimitedProvider {
+	return &RateLimitedProvider{
+		Provider: p,
+		aiID:     aiID,
+		limiters: limiters,
+	}
+}
+
+// Invoke waits for the limiters, then calls the wrapped provider. The time
+// spent queued is reported in Response.QueueWait.
+func (p *RateLimitedProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	estimate := estimateRequestTokens(req)
+	wait, err := p.acquire(ctx, estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.Provider.Invoke(ctx, req)
+	used := 0
+	if resp != nil {
+		used = resp.Usage.Total()
+		resp.QueueWait += wait
+	}
+	p.release(estimate, used)
+	return resp, err
+}
+
+// Stream waits for the limiters, then streams from the wrapped provider. The
+// call holds its concurrency slot until the stream ends.
+func (p *RateLimitedProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	estimate := estimateRequestTokens(req)
+	if _, err := p.acquire(ctx, estimate); err != nil {
+		return nil, err
+	}
+
+	ch, err := p.Provider.Stream(ctx, req)
+	if err != nil {
+		p.release(estimate, 0)
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 100)
+	go func() {
+		defer close(out)
+		used := 0
+		defer func() { p.release(estimate, used) }()
+
+		for chunk := range ch {
+			if chunk.Usage != nil {
+				used = chunk.Usage.Total()
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// acquire takes room from every limiter in order, handing back what it
+// already holds if the context ends first.
+func (p *RateLimitedProvider) acquire(ctx context.Context, estimate int) (time.Duration, error) {
+	onWait, _ := ctx.Value(queueHandlerKey{}).(func(QueueWait))
+	var total time.Duration
+	for i, l := range p.limiters {
+		wait, err := l.Acquire(ctx, estimate)
+		if err != nil {
+			for _, held := range p.limiters[:i] {
+				held.Cancel(estimate)
+			}
+			return 0, err
+		}
+		if wait > 0 && onWait != nil {
+			onWait(QueueWait{AIID: p.aiID, Limiter: l.Name(), Wait: wait})
+		}
+		total += wait
+	}
+	return total, nil
+}
+
+func (p *RateLimitedProvider) release(estimate, used int) {
+	for _, l := range p.limiters {
+		l.Release(estimate, used)
+	}
+}
+
+// estimateRequestTokens roughly counts a request's input tokens. It only
+// needs to be close enough to pace calls; the limiters correct it from
+// reported usage.
+func estimateRequestTokens(req Request) int {
+	chars := len(req.SystemPrompt)
+	for _, m := range req.Conversation() {
+		chars += len(messageText(m))
+	}
+	return chars/CharsPerToken + 1
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+// slowProvider holds each call briefly and records the peak concurrency.
+type slowProvider struct {
+	flakyProvider
+	active, peak atomic.Int32
+}
+
+func (p *slowProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	n := p.active.Add(1)
+	defer p.active.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return &Response{Content: "ok"}, nil
+}
+
+func TestRegistryMaxConcurrent(t *testing.T) {
+	p := &slowProvider{}
+	r := NewRegistry()
+	r.Register(p)
+	r.SetRateLimits(map[string]config.RateLimitConfig{"flaky": {MaxConcurrent: 2}})
+
+	var mu sync.Mutex
+	var waits []QueueWait
+	ctx := WithQueueHandler(context.Background(), func(qw QueueWait) {
+		mu.Lock()
+		waits = append(waits, qw)
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Invoke(ctx, "flaky", Request{Prompt: "hi"}); err != nil {
+				t.Errorf("Invoke() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak := p.peak.Load(); peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", peak)
+	}
+	if len(waits) == 0 || waits[0].Limiter != "provider flaky" || waits[0].Wait <= 0 {
+		t.Errorf("queue waits = %+v, want waits reported for the provider limit", waits)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(60) // one per second
+
+	if d := b.take(60); d != 0 {
+		t.Errorf("first take waited %v, want none from a full bucket", d)
+	}
+	if d := b.take(2); d < 1900*time.Millisecond || d > 2*time.Second {
+		t.Errorf("take from an empty bucket waited %v, want about 2s", d)
+	}
+
+	b.put(10)
+	if d := b.take(1); d != 0 {
+		t.Errorf("take after put waited %v, want none", d)
+	}
+}
+
+func TestRateLimitedAcquireCancelled(t *testing.T) {
+	first := NewRateLimiter("first", config.RateLimitConfig{RequestsPerMinute: 1, TokensPerMinute: 100})
+	second := NewRateLimiter("second", config.RateLimitConfig{MaxConcurrent: 1})
+	if _, err := second.Acquire(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	p := NewRateLimitedProvider(&flakyProvider{}, "flaky", []*RateLimiter{first, second})
+
+	// The call queues on second until its context ends, so it is never sent
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Invoke(ctx, Request{Prompt: "hi"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Invoke() error = %v, want DeadlineExceeded", err)
+	}
+	if d := first.requests.take(1); d != 0 {
+		t.Errorf("next request waited %v, want the unsent call's request back", d)
+	}
+	if d := first.tokens.take(100); d != 0 {
+		t.Errorf("next tokens waited %v, want the unsent call's tokens back", d)
+	}
+}
+
+// chattyProvider streams chunks until its context ends.
+type chattyProvider struct {
+	flakyProvider
+}
+
+func (p *chattyProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case ch <- StreamChunk{Content: "x"}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func TestRateLimitedStreamAbandoned(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&chattyProvider{})
+	r.SetRateLimits(map[string]config.RateLimitConfig{"flaky": {MaxConcurrent: 1}})
+
+	// A caller that stops reading and cancels must not hold the slot forever
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := r.Stream(ctx, "flaky", Request{Prompt: "hi"}); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	next, stop := context.WithTimeout(context.Background(), time.Second)
+	defer stop()
+	if _, err := r.Invoke(next, "flaky", Request{Prompt: "hi"}); err != nil {
+		t.Errorf("Invoke() after an abandoned stream error = %v, want the slot released", err)
+	}
+}
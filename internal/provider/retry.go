@@ -0,0 +1,234 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+// maxRetryAfter caps how long a provider's Retry-After header can stall a call.
+const maxRetryAfter = 2 * time.Minute
+
+// defaultMaxRetryDelay caps the backoff delay when RetryConfig sets none.
+const defaultMaxRetryDelay = time.Minute
+
+// RetryConfig controls how failed provider calls are retried.
+type RetryConfig struct {
+	MaxRetries int           // Retries after the first attempt (0 disables retrying)
+	BaseDelay  time.Duration // Delay before the first retry, doubled each attempt
+	MaxDelay   time.Duration // Upper bound for the backoff delay (default one minute)
+
+	// OnRetry, if set, is called before each retry is attempted.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// RetryConfigFromExecution builds a RetryConfig from the execution settings.
+// It returns a zero config (no retries) when retry_on_failure is disabled.
+func RetryConfigFromExecution(cfg config.ExecutionConfig) RetryConfig {
+	if !cfg.RetryOnFailure {
+		return RetryConfig{}
+	}
+	return RetryConfig{
+		MaxRetries: cfg.MaxRetries,
+		BaseDelay:  time.Duration(cfg.RetryDelay) * time.Second,
+		MaxDelay:   time.Duration(cfg.MaxRetryDelay) * time.Second,
+	}
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// rate limits, overload and server errors, timeouts and dropped connections.
+// Cancellation, bad requests and authentication failures are fatal.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout,
+			http.StatusConflict,
+			http.StatusTooEarly,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+			529: // Anthropic: overloaded
+			return true
+		}
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrStreamStalled) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// RetryingProvider wraps a Provider and retries transient failures with
+// exponential backoff and jitter.
+type RetryingProvider struct {
+	Provider
+	cfg   RetryConfig
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryingProvider wraps p with the given retry policy.
+func NewRetryingProvider(p Provider, cfg RetryConfig) *RetryingProvider {
+	return &RetryingProvider{
+		Provider: p,
+		cfg:      cfg,
+		sleep:    sleepContext,
+	}
+}
+
+// Invoke calls the wrapped provider, retrying transient failures.
+func (p *RetryingProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := p.Provider.Invoke(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if waitErr := p.backoff(ctx, attempt, err); waitErr != nil {
+			return nil, waitErr
+		}
+	}
+}
+
+// Stream calls the wrapped provider, retrying when the call fails or the
+// stream errors before its first chunk. Once content has been delivered the
+// stream is passed through unchanged, since a retry would duplicate output.
+func (p *RetryingProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	for attempt := 0; ; attempt++ {
+		ch, err := startStream(ctx, p.Provider, req)
+		if err == nil {
+			return ch, nil
+		}
+		if waitErr := p.backoff(ctx, attempt, err); waitErr != nil {
+			return nil, waitErr
+		}
+	}
+}
+
+// backoff waits before the next attempt, or returns the error to give up with.
+func (p *RetryingProvider) backoff(ctx context.Context, attempt int, err error) error {
+	if attempt >= p.cfg.MaxRetries || !IsRetryable(err) {
+		return err
+	}
+
+	delay := p.delay(attempt, err)
+	if p.cfg.OnRetry != nil {
+		p.cfg.OnRetry(attempt+1, err, delay)
+	}
+	if sleepErr := p.sleep(ctx, delay); sleepErr != nil {
+		return err
+	}
+	return nil
+}
+
+// delay computes the wait before retry number attempt+1. A Retry-After hint
+// from the provider takes precedence over the computed backoff.
+func (p *RetryingProvider) delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		if apiErr.RetryAfter > maxRetryAfter {
+			return maxRetryAfter
+		}
+		return apiErr.RetryAfter
+	}
+
+	base := p.cfg.BaseDelay
+	if base <= 0 {
+		base = time.Second
+	}
+	maxDelay := p.cfg.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxRetryDelay
+	}
+	// Double one step at a time so high attempt counts cannot overflow
+	d := base
+	for i := 0; i < attempt; i++ {
+		if d > maxDelay/2 {
+			d = maxDelay
+			break
+		}
+		d *= 2
+	}
+	if d > maxDelay {
+		d = maxDelay
+	}
+
+	// Equal jitter: half fixed, half random, so concurrent callers spread out
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// startStream opens a stream and waits for its first chunk, so that failures
+// before any content arrives surface as an error rather than a chunk.
+func startStream(ctx context.Context, p Provider, req Request) (<-chan StreamChunk, error) {
+	ch, err := p.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	first, ok := <-ch
+	if ok && first.Error != nil {
+		return nil, first.Error
+	}
+	return forwardStream(ctx, first, ok, ch), nil
+}
+
+// forwardStream replays an already-received first chunk ahead of the rest of
+// ch. Once ctx is done it stops forwarding, since the caller may have stopped
+// reading, and drains ch so the upstream stream can finish.
+func forwardStream(ctx context.Context, first StreamChunk, ok bool, ch <-chan StreamChunk) <-chan StreamChunk {
+	out := make(chan StreamChunk, 100)
+	go func() {
+		defer close(out)
+		if !ok {
+			return
+		}
+		send := func(chunk StreamChunk) bool {
+			select {
+			case out <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if send(first) {
+			for chunk := range ch {
+				if !send(chunk) {
+					break
+				}
+			}
+		}
+		for range ch {
+		}
+	}()
+	return out
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyProvider fails with the queued errors before succeeding.
+type flakyProvider struct {
+	errs  []error
+	calls int
+}
+
+func (f *flakyProvider) Name() string                          { return "flaky" }
+func (f *flakyProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (f *flakyProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &Response{Content: "ok"}, nil
+}
+
+func (f *flakyProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	f.calls++
+	ch := make(chan StreamChunk, 2)
+	if len(f.errs) > 0 {
+		ch <- StreamChunk{Error: f.errs[0], Done: true}
+		f.errs = f.errs[1:]
+	} else {
+		ch <- StreamChunk{Content: "ok"}
+		ch <- StreamChunk{Done: true}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func newTestRetrying(p Provider, maxRetries int) (*RetryingProvider, *[]time.Duration) {
+	var waits []time.Duration
+	rp := NewRetryingProvider(p, RetryConfig{MaxRetries: maxRetries, BaseDelay: time.Second})
+	rp.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return rp, &waits
+}
+
+func TestRetryingProviderInvoke(t *testing.T) {
+	flaky := &flakyProvider{errs: []error{
+		&APIError{StatusCode: 529, Message: "overloaded"},
+		&APIError{StatusCode: 429, Message: "slow down", RetryAfter: 7 * time.Second},
+	}}
+	rp, waits := newTestRetrying(flaky, 2)
+
+	resp, err := rp.Invoke(context.Background(), Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if resp.Content != "ok" || flaky.calls != 3 {
+		t.Errorf("got %q after %d calls, want ok after 3", resp.Content, flaky.calls)
+	}
+	if (*waits)[1] != 7*time.Second {
+		t.Errorf("second wait = %v, want Retry-After of 7s", (*waits)[1])
+	}
+}
+
+func TestRetryingProviderFatal(t *testing.T) {
+	flaky := &flakyProvider{errs: []error{&APIError{StatusCode: 401, Message: "bad key"}}}
+	rp, _ := newTestRetrying(flaky, 3)
+
+	_, err := rp.Invoke(context.Background(), Request{Prompt: "hi"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 401 {
+		t.Fatalf("Invoke() error = %v, want 401", err)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("fatal error retried: %d calls", flaky.calls)
+	}
+}
+
+func TestRetryDelayCapped(t *testing.T) {
+	rp := NewRetryingProvider(&flakyProvider{}, RetryConfig{MaxRetries: 100, BaseDelay: time.Second})
+	for _, attempt := range []int{10, 63, 64, 100} {
+		if d := rp.delay(attempt, errors.New("timeout")); d <= 0 || d > defaultMaxRetryDelay {
+			t.Errorf("delay(%d) = %v, want within (0, %v]", attempt, d, defaultMaxRetryDelay)
+		}
+	}
+}
+
+func TestRetryingProviderStreamBeforeFirstChunk(t *testing.T) {
+	flaky := &flakyProvider{errs: []error{&APIError{StatusCode: 503, Message: "unavailable"}}}
+	rp, _ := newTestRetrying(flaky, 1)
+
+	ch, err := rp.Stream(context.Background(), Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var content string
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected chunk error: %v", chunk.Error)
+		}
+		content += chunk.Content
+	}
+	if content != "ok" || flaky.calls != 2 {
+		t.Errorf("got %q after %d calls, want ok after 2", content, flaky.calls)
+	}
+}
+
+// stubbornProvider streams a fixed number of chunks whether or not anyone is
+// reading, closing finished once they are all sent.
+type stubbornProvider struct {
+	flakyProvider
+	finished chan struct{}
+}
+
+func (p *stubbornProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(p.finished)
+		defer close(ch)
+		for range 500 {
+			ch <- StreamChunk{Content: "x"}
+		}
+	}()
+	return ch, nil
+}
+
+func TestRetryingProviderStreamAbandoned(t *testing.T) {
+	p := &stubbornProvider{finished: make(chan struct{})}
+	rp, _ := newTestRetrying(p, 1)
+
+	// A caller that cancels and stops reading must not strand the stream
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := rp.Stream(ctx, Request{Prompt: "hi"}); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	cancel()
+
+	select {
+	case <-p.finished:
+	case <-time.After(time.Second):
+		t.Fatal("upstream stream was left undrained after its context was cancelled")
+	}
+}
@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// DefaultSchemaRetries is how many times InvokeJSON reprompts a model whose
+// reply does not match the schema.
+const DefaultSchemaRetries = 2
+
+// ResponseSchema asks a model to reply with JSON matching a schema. Providers
+// whose APIs accept a schema send it natively; the rest describe it in the
+// prompt, and Registry.InvokeJSON validates the reply either way.
+type ResponseSchema struct {
+	Name   string         // Identifier for the schema, e.g. "plan"
+	Schema map[string]any // JSON Schema for the reply
+}
+
+// Validator is implemented by structured replies that check their own
+// contents beyond what the schema can express.
+type Validator interface {
+	Validate() error
+}
+
+// instruction describes the schema for providers without native support.
+func (s *ResponseSchema) instruction() string {
+	schema, _ := json.MarshalIndent(s.Schema, "", "  ")
+	return fmt.Sprintf("Respond with only a JSON value matching this JSON Schema, with no prose or code fences:\n%s", schema)
+}
+
+// schemaSystemPrompt returns the request's system prompt with the schema
+// instruction appended, for providers that describe the schema in the prompt.
+func schemaSystemPrompt(req Request) string {
+	if req.ResponseSchema == nil {
+		return req.SystemPrompt
+	}
+	if req.SystemPrompt == "" {
+		return req.ResponseSchema.instruction()
+	}
+	return req.SystemPrompt + "\n\n" + req.ResponseSchema.instruction()
+}
+
+// InvokeJSON invokes a model with req.ResponseSchema set and decodes the reply
+// into out, which must be a pointer. Replies that are not valid JSON, do not
+// match the schema, or fail out's Validate method are sent back to the model
+// with the error, up to DefaultSchemaRetries times.
+func (r *Registry) InvokeJSON(ctx context.Context, aiID string, req Request, out any) (*Response, error) {
+	if req.ResponseSchema == nil {
+		return nil, fmt.Errorf("InvokeJSON requires a response schema")
+	}
+
+	var usage Usage
+	var lastErr error
+	for attempt := 0; attempt <= DefaultSchemaRetries; attempt++ {
+		resp, err := r.Invoke(ctx, aiID, req)
+		if err != nil {
+			return nil, err
+		}
+		usage.add(resp.Usage)
+
+		lastErr = DecodeJSON(resp.Content, req.ResponseSchema, out)
+		if lastErr == nil {
+			resp.Usage = usage
+			resp.TokensUsed = usage.Total()
+			return resp, nil
+		}
+
+		// Show the model its reply and what was wrong with it
+		req.Messages = append(req.Conversation(),
+			Message{Role: RoleAssistant, Content: resp.Content},
+			Message{Role: RoleUser, Content: fmt.Sprintf(
+				"That reply was invalid: %v\nRespond again with only the corrected JSON.", lastErr)},
+		)
+		req.Prompt = ""
+	}
+
+	return nil, fmt.Errorf("%s reply did not match the %q schema: %w", aiID, req.ResponseSchema.Name, lastErr)
+}
+
+// DecodeJSON extracts a JSON value from a model reply, checks it against the
+// schema and decodes it into out, running out's Validate method if it has one.
+func DecodeJSON(content string, schema *ResponseSchema, out any) error {
+	data := extractJSON(content)
+
+	var value any
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if schema != nil {
+		if err := validateSchema(value, normalizeSchema(schema.Schema), "$"); err != nil {
+			return err
+		}
+	}
+	if err := json.Unmarshal([]byte(data), out); err != nil {
+		return fmt.Errorf("decoding JSON: %w", err)
+	}
+	if v, ok := out.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// extractJSON strips code fences and surrounding prose from a reply.
+func extractJSON(content string) string {
+	s := strings.TrimSpace(content)
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```")
+		s = strings.TrimPrefix(s, "json")
+		s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+		s = strings.TrimSpace(s)
+	}
+	if strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") {
+		return s
+	}
+
+	start := strings.IndexAny(s, "{[")
+	end := strings.LastIndexAny(s, "}]")
+	if start >= 0 && end > start {
+		return s[start : end+1]
+	}
+	return s
+}
+
+// normalizeSchema round-trips a Go-built schema through JSON so typed slices
+// and numbers compare like decoded replies.
+func normalizeSchema(schema map[string]any) map[string]any {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return schema
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return schema
+	}
+	return out
+}
+
+// validateSchema checks value against the subset of JSON Schema used for
+// structured replies: type, properties, required, items, enum and minItems.
+func validateSchema(value any, schema map[string]any, path string) error {
+	if want, ok := schema["type"].(string); ok {
+		if err := checkType(value, want, path); err != nil {
+			return err
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		values, _ := enum.([]any)
+		if !slices.Contains(values, value) {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := v[fmt.Sprint(name)]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for name, sub := range props {
+			field, present := v[name]
+			subSchema, ok := sub.(map[string]any)
+			if !present || !ok {
+				continue
+			}
+			if err := validateSchema(field, subSchema, path+"."+name); err != nil {
+				return err
+			}
+		}
+
+	case []any:
+		if minItems, ok := schema["minItems"].(float64); ok && len(v) < int(minItems) {
+			return fmt.Errorf("%s: want at least %d items, got %d", path, int(minItems), len(v))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkType reports whether a decoded JSON value has the given schema type.
+func checkType(value any, want, path string) error {
+	ok := false
+	switch want {
+	case "object":
+		_, ok = value.(map[string]any)
+	case "array":
+		_, ok = value.([]any)
+	case "string":
+		_, ok = value.(string)
+	case "boolean":
+		_, ok = value.(bool)
+	case "number":
+		_, ok = value.(float64)
+	case "integer":
+		f, isNum := value.(float64)
+		ok = isNum && f == math.Trunc(f)
+	case "null":
+		ok = value == nil
+	default:
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("%s: want %s, got %s", path, want, jsonTypeName(value))
+	}
+	return nil
+}
+
+// jsonTypeName names the JSON type of a decoded value for error messages.
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
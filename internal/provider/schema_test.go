@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var testSchema = &ResponseSchema{
+	Name: "verdict",
+	Schema: map[string]any{
+		"type":     "object",
+		"required": []string{"vote", "score"},
+		"properties": map[string]any{
+			"vote":  map[string]any{"type": "string", "enum": []string{"yes", "no"}},
+			"score": map[string]any{"type": "integer"},
+			"notes": map[string]any{"type": "array", "minItems": 1, "items": map[string]any{"type": "string"}},
+		},
+	},
+}
+
+type verdict struct {
+	Vote  string   `json:"vote"`
+	Score int      `json:"score"`
+	Notes []string `json:"notes"`
+}
+
+func (v *verdict) Validate() error {
+	if v.Score > 10 {
+		return fmt.Errorf("score %d is above 10", v.Score)
+	}
+	return nil
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"plain", `{"vote":"yes","score":3}`, ""},
+		{"fenced", "```json\n{\"vote\":\"no\",\"score\":1}\n```", ""},
+		{"prose around", "Here you go:\n{\"vote\":\"yes\",\"score\":2}\nThanks!", ""},
+		{"not json", "**VOTE:** yes", "invalid JSON"},
+		{"missing field", `{"vote":"yes"}`, `missing required field "score"`},
+		{"bad enum", `{"vote":"maybe","score":1}`, "$.vote"},
+		{"wrong type", `{"vote":"yes","score":1.5}`, "want integer"},
+		{"empty array", `{"vote":"yes","score":1,"notes":[]}`, "at least 1"},
+		{"validate method", `{"vote":"yes","score":11}`, "above 10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v verdict
+			err := DecodeJSON(tt.content, testSchema, &v)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("DecodeJSON() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DecodeJSON() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvokeJSONReprompts(t *testing.T) {
+	p := &toolCallingProvider{responses: []*Response{
+		{Content: "VOTE: yes", Usage: Usage{InputTokens: 5}},
+		{Content: `{"vote":"yes","score":4}`, Usage: Usage{InputTokens: 9}},
+	}}
+	r := NewRegistry()
+	r.Register(p)
+
+	var v verdict
+	resp, err := r.InvokeJSON(context.Background(), "flaky", Request{Prompt: "vote", ResponseSchema: testSchema}, &v)
+	if err != nil {
+		t.Fatalf("InvokeJSON() error = %v", err)
+	}
+	if v.Vote != "yes" || v.Score != 4 {
+		t.Errorf("decoded %+v", v)
+	}
+	if resp.Usage.InputTokens != 14 {
+		t.Errorf("InputTokens = %d, want usage summed across attempts", resp.Usage.InputTokens)
+	}
+
+	retry := p.requests[1].Messages
+	if len(retry) != 3 || !strings.Contains(retry[2].Content, "invalid JSON") {
+		t.Errorf("reprompt messages = %+v", retry)
+	}
+}
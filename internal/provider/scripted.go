@@ -0,0 +1,338 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ScriptedProviderName is the provider value that selects a scripted model.
+const ScriptedProviderName = "scripted"
+
+// Script is the YAML definition of a scripted provider's canned responses.
+type Script struct {
+	LatencyMs    int          `yaml:"latency_ms"`     // Delay before the first chunk
+	ChunkSize    int          `yaml:"chunk_size"`     // Characters per stream chunk (0 = whole response)
+	ChunkDelayMs int          `yaml:"chunk_delay_ms"` // Delay between stream chunks
+	Rules        []ScriptRule `yaml:"rules"`
+	Default      string       `yaml:"default"` // Template used when no rule matches
+}
+
+// ScriptRule maps requests matching a pattern to a response. Responses are
+// Go templates; see ScriptData for the fields available to them.
+type ScriptRule struct {
+	Match     string       `yaml:"match"`                // Regex on the system prompt and conversation; empty matches all
+	Response  string       `yaml:"response,omitempty"`   // Template served on every match
+	Sequence  []string     `yaml:"sequence,omitempty"`   // Templates served in turn; the last one repeats
+	Error     *ScriptError `yaml:"error,omitempty"`      // Failure injected before responding
+	LatencyMs int          `yaml:"latency_ms,omitempty"` // Overrides the script latency
+
+	re        *regexp.Regexp
+	templates []*template.Template
+	calls     int
+	served    int
+}
+
+// ScriptError describes an injected failure.
+type ScriptError struct {
+	Status      int    `yaml:"status"` // HTTP status, so retries and fallbacks classify it (0 = plain error)
+	Message     string `yaml:"message"`
+	Times       int    `yaml:"times"`        // Fail this many matching calls, then respond (0 = always fail)
+	AfterChunks int    `yaml:"after_chunks"` // For streams, fail after this many chunks instead of up front
+}
+
+// ScriptData is passed to response templates.
+type ScriptData struct {
+	Model        string // AI ID the script is serving
+	Prompt       string // Last user message
+	SystemPrompt string
+	Conversation string   // Whole conversation, flattened
+	Match        []string // Regex match and capture groups
+	Call         int      // 1-based count of calls that matched this rule
+}
+
+// ScriptedProvider serves canned responses from a YAML script, for demos and
+// offline runs without API keys or CLI tools.
+type ScriptedProvider struct {
+	name   string
+	script *Script
+
+	mu       sync.Mutex
+	fallback *template.Template
+}
+
+// NewScriptedProvider loads the script at path for the model named name.
+func NewScriptedProvider(name, path string) (*ScriptedProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("scripted provider requires a script path")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading script: %w", err)
+	}
+
+	var script Script
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("parsing script %s: %w", path, err)
+	}
+
+	return newScriptedProvider(name, &script)
+}
+
+// newScriptedProvider compiles the patterns and templates of a parsed script.
+func newScriptedProvider(name string, script *Script) (*ScriptedProvider, error) {
+	for i := range script.Rules {
+		rule := &script.Rules[i]
+
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: invalid match pattern: %w", i+1, err)
+		}
+		rule.re = re
+
+		sources := rule.Sequence
+		if len(sources) == 0 {
+			sources = []string{rule.Response}
+		}
+		for j, src := range sources {
+			tmpl, err := template.New(fmt.Sprintf("rule%d_%d", i+1, j+1)).Parse(src)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: invalid template: %w", i+1, err)
+			}
+			rule.templates = append(rule.templates, tmpl)
+		}
+	}
+
+	fallback, err := template.New("default").Parse(script.Default)
+	if err != nil {
+		return nil, fmt.Errorf("invalid default template: %w", err)
+	}
+
+	return &ScriptedProvider{
+		name:     name,
+		script:   script,
+		fallback: fallback,
+	}, nil
+}
+
+// Name returns the AI ID the script serves.
+func (p *ScriptedProvider) Name() string {
+	return p.name
+}
+
+// Capabilities reports that scripted models stream and read system prompts,
+// which rules can match on, but never call tools.
+func (p *ScriptedProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, SystemPrompt: true}
+}
+
+// HealthCheck always succeeds.
+func (p *ScriptedProvider) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// scriptedReply is the outcome chosen for one request.
+type scriptedReply struct {
+	content     string
+	err         error
+	errAfter    int // Stream chunks to send before err (0 = fail up front)
+	latency     time.Duration
+	inputTokens int
+}
+
+// reply picks the matching rule for req and renders its response.
+func (p *ScriptedProvider) reply(req Request) scriptedReply {
+	conversation := req.Conversation()
+	data := ScriptData{
+		Model:        p.name,
+		SystemPrompt: req.SystemPrompt,
+		Conversation: FlattenMessages(conversation),
+	}
+	if n := len(conversation); n > 0 {
+		data.Prompt = conversation[n-1].Content
+	}
+	subject := req.SystemPrompt + "\n" + data.Conversation
+
+	out := scriptedReply{
+		latency:     time.Duration(p.script.LatencyMs) * time.Millisecond,
+		inputTokens: EstimateTokens(subject),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tmpl := p.fallback
+	for i := range p.script.Rules {
+		rule := &p.script.Rules[i]
+		match := rule.re.FindStringSubmatch(subject)
+		if match == nil {
+			continue
+		}
+
+		rule.calls++
+		data.Match = match
+		data.Call = rule.calls
+		if rule.LatencyMs > 0 {
+			out.latency = time.Duration(rule.LatencyMs) * time.Millisecond
+		}
+
+		if e := rule.Error; e != nil && (e.Times == 0 || rule.calls <= e.Times) {
+			out.err = scriptError(e)
+			out.errAfter = e.AfterChunks
+			if out.errAfter == 0 {
+				return out
+			}
+		}
+
+		idx := rule.served
+		if idx >= len(rule.templates) {
+			idx = len(rule.templates) - 1
+		}
+		rule.served++
+		tmpl = rule.templates[idx]
+		break
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		out.err = fmt.Errorf("rendering scripted response: %w", err)
+		out.errAfter = 0
+		return out
+	}
+	out.content = b.String()
+	return out
+}
+
+// scriptError builds the error injected by a rule.
+func scriptError(e *ScriptError) error {
+	message := e.Message
+	if message == "" {
+		message = "scripted failure"
+	}
+	if e.Status != 0 {
+		return &APIError{StatusCode: e.Status, Message: message}
+	}
+	return fmt.Errorf("%s", message)
+}
+
+// Invoke returns the scripted response for req.
+func (p *ScriptedProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	out := p.reply(req)
+	if err := sleepContext(ctx, out.latency); err != nil {
+		return nil, err
+	}
+	if out.err != nil {
+		return nil, out.err
+	}
+
+	usage := Usage{InputTokens: out.inputTokens, OutputTokens: EstimateTokens(out.content)}
+	return &Response{
+		Content:      out.content,
+		Model:        p.name,
+		FinishReason: "stop",
+		TokensUsed:   usage.Total(),
+		Usage:        usage,
+	}, nil
+}
+
+// Stream returns the scripted response for req in chunks.
+func (p *ScriptedProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	out := p.reply(req)
+	chunks := splitChunks(out.content, p.script.ChunkSize)
+	delay := time.Duration(p.script.ChunkDelayMs) * time.Millisecond
+
+	ch := make(chan StreamChunk, 100)
+	go func() {
+		defer close(ch)
+
+		if err := sleepContext(ctx, out.latency); err != nil {
+			ch <- StreamChunk{Error: err, Done: true}
+			return
+		}
+
+		for i, content := range chunks {
+			if out.err != nil && i == out.errAfter {
+				break
+			}
+			if i > 0 {
+				if err := sleepContext(ctx, delay); err != nil {
+					ch <- StreamChunk{Error: err, Done: true}
+					return
+				}
+			}
+			ch <- StreamChunk{Content: content}
+		}
+
+		if out.err != nil {
+			ch <- StreamChunk{Error: out.err, Done: true}
+			return
+		}
+		ch <- StreamChunk{Done: true, Usage: &Usage{
+			InputTokens:  out.inputTokens,
+			OutputTokens: EstimateTokens(out.content),
+		}}
+	}()
+
+	return ch, nil
+}
+
+// splitChunks breaks s into chunks of at most size runes (0 = one chunk).
+func splitChunks(s string, size int) []string {
+	if s == "" {
+		return nil
+	}
+	runes := []rune(s)
+	if size <= 0 || size >= len(runes) {
+		return []string{s}
+	}
+
+	chunks := make([]string, 0, len(runes)/size+1)
+	for start := 0; start < len(runes); start += size {
+		end := start + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+		chunks = append(chunks, string(runes[start:end]))
+	}
+	return chunks
+}
+
+// RegisterScriptedModels creates a scripted provider for every model
+// configured with provider "scripted", loading the model's script file. Every
+// model is tried; the error reports all whose scripts failed to load.
+func (r *Registry) RegisterScriptedModels(models map[string]config.ModelConfig) error {
+	var errs []error
+	for _, aiID := range slices.Sorted(maps.Keys(models)) {
+		cfg := models[aiID]
+		if cfg.Provider != ScriptedProviderName {
+			continue
+		}
+		p, err := NewScriptedProvider(aiID, cfg.Script)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("model %q: %w", aiID, err))
+			continue
+		}
+		r.RegisterModelProvider(aiID, p)
+	}
+	return errors.Join(errs...)
+}
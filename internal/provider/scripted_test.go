@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+func TestScriptedProvider(t *testing.T) {
+	p, err := newScriptedProvider("demo", &Script{
+		ChunkSize: 4,
+		Rules: []ScriptRule{
+			{Match: `subtask (\d+)`, Response: "done {{index .Match 1}} by {{.Model}}"},
+			{Match: "review", Sequence: []string{"first", "second"}},
+			{Match: "flaky", Response: "recovered", Error: &ScriptError{Status: 429, Times: 1}},
+		},
+		Default: "default",
+	})
+	if err != nil {
+		t.Fatalf("newScriptedProvider() error = %v", err)
+	}
+	ctx := context.Background()
+
+	invoke := func(prompt string) (string, error) {
+		resp, err := p.Invoke(ctx, Request{Prompt: prompt})
+		if err != nil {
+			return "", err
+		}
+		return resp.Content, nil
+	}
+
+	if got, _ := invoke("work on subtask 3"); got != "done 3 by demo" {
+		t.Errorf("template response = %q", got)
+	}
+	for _, want := range []string{"first", "second", "second"} {
+		if got, _ := invoke("please review"); got != want {
+			t.Errorf("sequence response = %q, want %q", got, want)
+		}
+	}
+	if got, _ := invoke("unmatched"); got != "default" {
+		t.Errorf("default response = %q", got)
+	}
+
+	_, err = invoke("flaky call")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 429 {
+		t.Errorf("injected error = %v, want 429", err)
+	}
+	if got, _ := invoke("flaky call"); got != "recovered" {
+		t.Errorf("response after injected error = %q", got)
+	}
+
+	ch, err := p.Stream(ctx, Request{Prompt: "subtask 12"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var chunks []string
+	var final StreamChunk
+	for chunk := range ch {
+		if chunk.Content != "" {
+			chunks = append(chunks, chunk.Content)
+		}
+		final = chunk
+	}
+	if len(chunks) != 4 || chunks[0] != "done" {
+		t.Errorf("stream chunks = %q", chunks)
+	}
+	if !final.Done || final.Usage == nil {
+		t.Errorf("final chunk = %+v, want done with usage", final)
+	}
+}
+
+func TestRegisterScriptedModelsReportsAll(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "demo.yaml")
+	os.WriteFile(script, []byte("default: hello\n"), 0600)
+
+	models := map[string]config.ModelConfig{
+		"a-missing": {Provider: ScriptedProviderName, Script: filepath.Join(dir, "missing.yaml")},
+		"b-demo":    {Provider: ScriptedProviderName, Script: script},
+		"c-missing": {Provider: ScriptedProviderName, Script: filepath.Join(dir, "gone.yaml")},
+	}
+	r := NewRegistry()
+	r.RegisterModels(models)
+	err := r.RegisterScriptedModels(models)
+	if err == nil || !strings.Contains(err.Error(), "a-missing") || !strings.Contains(err.Error(), "c-missing") {
+		t.Errorf("RegisterScriptedModels() error = %v, want both missing scripts", err)
+	}
+	if resp, err := r.Invoke(context.Background(), "b-demo", Request{Prompt: "hi"}); err != nil || resp.Content != "hello" {
+		t.Errorf("Invoke(b-demo) = %v, %v", resp, err)
+	}
+}
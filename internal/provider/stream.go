@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultStreamIdleTimeout is how long a stream may send nothing before it
+// counts as stalled. It is generous because reasoning models and local models
+// reading a long prompt can go quiet for a while.
+const defaultStreamIdleTimeout = 120 * time.Second
+
+// ErrStreamStalled is returned when a stream sends nothing for longer than
+// its idle timeout. Content that arrived before the stall has already been
+// delivered as chunks.
+var ErrStreamStalled = errors.New("stream stalled")
+
+// continuePrompt asks a model to carry on from an interrupted answer.
+const continuePrompt = "Your previous answer was cut off. Continue exactly where it stopped, without repeating anything or adding a preamble."
+
+// ContinuationRequest returns req extended with the partial answer an
+// interrupted stream produced, asking the model to continue from where it
+// stopped rather than start over. Append the reply to partial.
+func ContinuationRequest(req Request, partial string) Request {
+	messages := req.Conversation()
+	messages = append(messages,
+		Message{Role: RoleAssistant, Content: partial},
+		Message{Role: RoleUser, Content: continuePrompt},
+	)
+	req.Messages = messages
+	req.Prompt = ""
+	req.Attachments = nil
+	return req
+}
+
+// maxStreamResumes is how many times StreamResumable continues an
+// interrupted stream before giving up.
+const maxStreamResumes = 2
+
+// StreamResumable streams aiID's answer to req, passing each chunk to onChunk.
+// A stream that stalls or breaks off partway with a retryable error is
+// continued from the text that already arrived, up to maxStreamResumes times,
+// calling onResume (which may be nil) first. If the answer still fails after
+// text arrived, the partial answer is returned with Partial set, alongside
+// the error.
+func (r *Registry) StreamResumable(ctx context.Context, aiID string, req Request, onChunk func(StreamChunk), onResume func(partial string, err error)) (*Response, error) {
+	var content, reasoning strings.Builder
+	var usage Usage
+	for resumes := 0; ; resumes++ {
+		err := r.streamOnce(ctx, aiID, req, &content, &reasoning, &usage, onChunk)
+		if err == nil {
+			break
+		}
+		if content.Len() == 0 {
+			return nil, err
+		}
+		if resumes == maxStreamResumes || ctx.Err() != nil || !IsRetryable(err) {
+			resp := assembledResponse(aiID, &content, &reasoning, usage)
+			resp.Partial = true
+			return resp, err
+		}
+		if onResume != nil {
+			onResume(content.String(), err)
+		}
+		req = ContinuationRequest(req, content.String())
+	}
+	return assembledResponse(aiID, &content, &reasoning, usage), nil
+}
+
+// streamOnce runs one stream for StreamResumable, appending its text and
+// adding its usage.
+func (r *Registry) streamOnce(ctx context.Context, aiID string, req Request, content, reasoning *strings.Builder, usage *Usage, onChunk func(StreamChunk)) error {
+	ch, err := r.Stream(ctx, aiID, req)
+	if err != nil {
+		return err
+	}
+
+	var streamUsage Usage
+	defer func() { usage.add(streamUsage) }()
+	for chunk := range ch {
+		if chunk.Error != nil {
+			return chunk.Error
+		}
+		content.WriteString(chunk.Content)
+		reasoning.WriteString(chunk.Reasoning)
+		if chunk.Usage != nil {
+			streamUsage = *chunk.Usage
+		}
+		onChunk(chunk)
+	}
+	return nil
+}
+
+// assembledResponse builds StreamResumable's response.
+func assembledResponse(aiID string, content, reasoning *strings.Builder, usage Usage) *Response {
+	return &Response{
+		Content:    content.String(),
+		Reasoning:  reasoning.String(),
+		TokensUsed: usage.Total(),
+		Usage:      usage,
+		AIID:       aiID,
+	}
+}
+
+// streamBody returns resp's body for reading a stream. A read fails with
+// ErrStreamStalled once nothing has arrived for the provider's idle timeout,
+// and with the request's context error once the request is cancelled.
+func (b *BaseProvider) streamBody(resp *http.Response) io.ReadCloser {
+	r := &streamReader{body: resp.Body, timeout: b.idleTimeout}
+	if resp.Request != nil {
+		r.ctx = resp.Request.Context()
+	}
+	if r.timeout > 0 {
+		r.timer = time.AfterFunc(r.timeout, func() {
+			r.stalled.Store(true)
+			r.body.Close()
+		})
+	}
+	return r
+}
+
+// streamReader is the body returned by streamBody.
+type streamReader struct {
+	body    io.ReadCloser
+	ctx     context.Context
+	timeout time.Duration
+	timer   *time.Timer
+	stalled atomic.Bool
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if err == io.EOF {
+		return n, err
+	}
+	if r.stalled.Load() {
+		return n, fmt.Errorf("%w: nothing received for %s", ErrStreamStalled, r.timeout)
+	}
+	if err != nil && r.ctx != nil && r.ctx.Err() != nil {
+		return n, r.ctx.Err()
+	}
+	if n > 0 && r.timer != nil {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+func (r *streamReader) Close() error {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	return r.body.Close()
+}
@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamStalled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	p := NewOpenAICompatProvider(OpenAICompatConfig{Name: "test", Endpoint: srv.URL, Model: "m"})
+	p.idleTimeout = 200 * time.Millisecond
+
+	ch, err := p.Stream(context.Background(), Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	var content strings.Builder
+	var streamErr error
+	for chunk := range ch {
+		content.WriteString(chunk.Content)
+		if chunk.Error != nil {
+			streamErr = chunk.Error
+		}
+	}
+	if content.String() != "Hel" {
+		t.Errorf("partial content = %q, want Hel", content.String())
+	}
+	if !errors.Is(streamErr, ErrStreamStalled) || !IsRetryable(streamErr) {
+		t.Errorf("stream error = %v, want a retryable ErrStreamStalled", streamErr)
+	}
+
+	req := ContinuationRequest(Request{SystemPrompt: "sys", Prompt: "hi"}, content.String())
+	if req.Prompt != "" || req.SystemPrompt != "sys" || len(req.Messages) != 3 ||
+		req.Messages[1].Role != RoleAssistant || req.Messages[1].Content != "Hel" {
+		t.Errorf("ContinuationRequest() = %+v", req)
+	}
+}
+
+// breakingProvider streams "part" and then fails with the next error in errs,
+// or finishes once errs runs out.
+type breakingProvider struct {
+	flakyProvider
+	requests []Request
+}
+
+func (p *breakingProvider) Stream(ctx context.Context, req Request) (<-chan StreamChunk, error) {
+	p.requests = append(p.requests, req)
+	ch := make(chan StreamChunk, 2)
+	ch <- StreamChunk{Content: "part"}
+	if len(p.errs) > 0 {
+		ch <- StreamChunk{Error: p.errs[0]}
+		p.errs = p.errs[1:]
+	} else {
+		ch <- StreamChunk{Done: true, Usage: &Usage{OutputTokens: 1}}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestStreamResumable(t *testing.T) {
+	p := &breakingProvider{flakyProvider: flakyProvider{errs: []error{ErrStreamStalled}}}
+	r := NewRegistry()
+	r.Register(p)
+
+	var resumed int
+	resp, err := r.StreamResumable(context.Background(), "flaky", Request{Prompt: "hi"}, func(StreamChunk) {}, func(string, error) {
+		resumed++
+	})
+	if err != nil {
+		t.Fatalf("StreamResumable() error = %v", err)
+	}
+	if resp.Content != "partpart" || resp.Partial || resumed != 1 {
+		t.Errorf("resp = %+v after %d resumes", resp, resumed)
+	}
+	last := p.requests[1].Messages
+	if len(last) != 3 || last[1].Content != "part" || last[2].Content != continuePrompt {
+		t.Errorf("continuation messages = %+v", last)
+	}
+
+	// Errors that won't clear up on a retry keep what arrived
+	p.errs = []error{errors.New("bad event")}
+	resp, err = r.StreamResumable(context.Background(), "flaky", Request{Prompt: "hi"}, func(StreamChunk) {}, nil)
+	if err == nil || resp == nil || !resp.Partial || resp.Content != "part" {
+		t.Errorf("StreamResumable() = %+v, %v; want the partial answer and error", resp, err)
+	}
+}
@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// DefaultMaxToolSteps bounds how many model turns a tool loop may take.
+const DefaultMaxToolSteps = 10
+
+// Tool describes a function a model may call.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any // JSON Schema for the arguments object
+}
+
+// ToolCall is a model's request to run a tool.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // JSON-encoded arguments object
+	Signature string // Opaque provider state echoed back with the call (Gemini thought signatures)
+}
+
+// argumentsJSON returns the call's arguments as raw JSON, defaulting to an
+// empty object since APIs reject a missing arguments value.
+func (c ToolCall) argumentsJSON() json.RawMessage {
+	if c.Arguments == "" || !json.Valid([]byte(c.Arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(c.Arguments)
+}
+
+// ToolHandler runs a tool call and returns its result as text.
+type ToolHandler func(ctx context.Context, call ToolCall) (string, error)
+
+// Toolbox is a set of tools offered to a model, with the handlers that run them.
+type Toolbox struct {
+	tools    []Tool
+	handlers map[string]ToolHandler
+
+	// OnCall, if set, is called after each tool call completes.
+	OnCall func(call ToolCall, result string, err error)
+}
+
+// NewToolbox creates an empty toolbox.
+func NewToolbox() *Toolbox {
+	return &Toolbox{
+		handlers: make(map[string]ToolHandler),
+	}
+}
+
+// Add registers a tool and its handler, replacing any tool with the same name.
+func (t *Toolbox) Add(tool Tool, handler ToolHandler) {
+	if _, exists := t.handlers[tool.Name]; !exists {
+		t.tools = append(t.tools, tool)
+	}
+	t.handlers[tool.Name] = handler
+}
+
+// Tools returns the tool definitions to send with a request.
+func (t *Toolbox) Tools() []Tool {
+	return append([]Tool(nil), t.tools...)
+}
+
+// Run executes a tool call. Failures are returned as text so the model can
+// see what went wrong and adjust, rather than aborting the loop.
+func (t *Toolbox) Run(ctx context.Context, call ToolCall) string {
+	handler, ok := t.handlers[call.Name]
+	if !ok {
+		err := fmt.Errorf("unknown tool %q", call.Name)
+		t.notify(call, "", err)
+		return "error: " + err.Error()
+	}
+
+	result, err := handler(ctx, call)
+	t.notify(call, result, err)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return result
+}
+
+// notify reports a completed call to OnCall.
+func (t *Toolbox) notify(call ToolCall, result string, err error) {
+	if t.OnCall != nil {
+		t.OnCall(call, result, err)
+	}
+}
+
+// InvokeWithTools invokes a model with the toolbox's tools, running each tool
+// call the model makes and feeding the results back until it answers without
+// calling a tool. Usage is summed across all turns.
+func (r *Registry) InvokeWithTools(ctx context.Context, aiID string, req Request, tools *Toolbox, maxSteps int) (*Response, error) {
+	if tools == nil || len(tools.tools) == 0 {
+		return r.Invoke(ctx, aiID, req)
+	}
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxToolSteps
+	}
+
+	// The loop grows the conversation, so the prompt becomes a message
+	req.Messages = req.Conversation()
+	req.Prompt = ""
+	req.Tools = tools.Tools()
+
+	var usage Usage
+	for step := 0; step < maxSteps; step++ {
+		resp, err := r.Invoke(ctx, aiID, req)
+		if err != nil {
+			return nil, err
+		}
+		usage.add(resp.Usage)
+
+		if len(resp.ToolCalls) == 0 {
+			resp.Usage = usage
+			resp.TokensUsed = usage.Total()
+			return resp, nil
+		}
+
+		req.Messages = append(req.Messages, Message{
+			Role:      RoleAssistant,
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		for _, call := range resp.ToolCalls {
+			req.Messages = append(req.Messages, Message{
+				Role:       RoleTool,
+				Content:    tools.Run(ctx, call),
+				ToolCallID: call.ID,
+				ToolName:   call.Name,
+			})
+		}
+	}
+
+	return nil, fmt.Errorf("tool loop for %q did not finish within %d steps", aiID, maxSteps)
+}
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// toolCallingProvider returns its queued responses in order, recording each request.
+type toolCallingProvider struct {
+	flakyProvider
+	responses []*Response
+	requests  []Request
+}
+
+func (p *toolCallingProvider) Invoke(ctx context.Context, req Request) (*Response, error) {
+	p.requests = append(p.requests, req)
+	resp := p.responses[0]
+	p.responses = p.responses[1:]
+	return resp, nil
+}
+
+func TestInvokeWithTools(t *testing.T) {
+	p := &toolCallingProvider{responses: []*Response{
+		{
+			ToolCalls: []ToolCall{{ID: "call_1", Name: "lookup", Arguments: `{"key":"a"}`}},
+			Usage:     Usage{InputTokens: 10, OutputTokens: 2},
+		},
+		{Content: "the answer is 42", Usage: Usage{InputTokens: 20, OutputTokens: 5}},
+	}}
+	r := NewRegistry()
+	r.Register(p)
+
+	tools := NewToolbox()
+	var ran []string
+	tools.Add(Tool{Name: "lookup"}, func(ctx context.Context, call ToolCall) (string, error) {
+		ran = append(ran, call.Arguments)
+		return "42", nil
+	})
+
+	resp, err := r.InvokeWithTools(context.Background(), "flaky", Request{Prompt: "what is a?"}, tools, 0)
+	if err != nil {
+		t.Fatalf("InvokeWithTools() error = %v", err)
+	}
+	if resp.Content != "the answer is 42" {
+		t.Errorf("Content = %q", resp.Content)
+	}
+	if resp.Usage != (Usage{InputTokens: 30, OutputTokens: 7}) {
+		t.Errorf("Usage = %+v, want summed usage", resp.Usage)
+	}
+	if len(ran) != 1 || ran[0] != `{"key":"a"}` {
+		t.Errorf("tool runs = %v", ran)
+	}
+
+	second := p.requests[1].Messages
+	if len(second) != 3 || second[1].Role != RoleAssistant || second[2].Role != RoleTool {
+		t.Fatalf("second request messages = %+v", second)
+	}
+	if second[2].ToolCallID != "call_1" || second[2].Content != "42" {
+		t.Errorf("tool result message = %+v", second[2])
+	}
+}
+
+func TestInvokeWithToolsStepLimit(t *testing.T) {
+	loop := &Response{ToolCalls: []ToolCall{{ID: "x", Name: "missing"}}}
+	p := &toolCallingProvider{responses: []*Response{loop, loop}}
+	r := NewRegistry()
+	r.Register(p)
+
+	tools := NewToolbox()
+	tools.Add(Tool{Name: "other"}, func(ctx context.Context, call ToolCall) (string, error) { return "", nil })
+
+	if _, err := r.InvokeWithTools(context.Background(), "flaky", Request{Prompt: "go"}, tools, 2); err == nil {
+		t.Fatal("expected step limit error")
+	}
+	if got := p.requests[1].Messages[2].Content; !strings.HasPrefix(got, "error: unknown tool") {
+		t.Errorf("unknown tool result = %q", got)
+	}
+}
+
+func TestBuildAnthropicMessagesGroupsToolResults(t *testing.T) {
+	req := Request{Messages: []Message{
+		{Role: RoleUser, Content: "compare"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "a", Name: "read"}, {ID: "b", Name: "read"}}},
+		{Role: RoleTool, ToolCallID: "a", Content: "one"},
+		{Role: RoleTool, ToolCallID: "b", Content: "two"},
+	}}
+
+	messages := buildAnthropicMessages(req)
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages, want 3", len(messages))
+	}
+
+	data, _ := json.Marshal(messages[1])
+	if !strings.Contains(string(data), `"type":"tool_use","id":"a","name":"read","input":{}`) {
+		t.Errorf("tool_use block = %s", data)
+	}
+	results, ok := messages[2].Content.([]anthropicContent)
+	if !ok || len(results) != 2 || messages[2].Role != RoleUser || results[1].ToolUseID != "b" {
+		t.Errorf("tool results turn = %+v", messages[2])
+	}
+}
+
+func TestOpenAIStreamToolCalls(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_9","function":{"name":"lookup","arguments":"{\"ke"}}]}}]}`,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"y\":1}"}}]}}]}`,
+		`data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		`data: [DONE]`,
+	}, "\n\n")
+
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader(body))}
+	out := make(chan StreamChunk, 10)
+	b := NewBaseProvider(BaseConfig{Name: "test"})
+	go b.ReadSSEStream(resp, out, newOpenAIStreamParser())
+
+	var calls []ToolCall
+	for chunk := range out {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected error: %v", chunk.Error)
+		}
+		calls = append(calls, chunk.ToolCalls...)
+	}
+
+	want := ToolCall{ID: "call_9", Name: "lookup", Arguments: `{"key":1}`}
+	if len(calls) != 1 || calls[0] != want {
+		t.Errorf("tool calls = %+v, want %+v", calls, want)
+	}
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+// NewHTTPClient builds a client for a model's HTTP settings. timeout bounds
+// each call when the settings leave it unset.
+func NewHTTPClient(cfg config.HTTPConfig, timeout time.Duration) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", cfg.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if cfg.CABundle != "" || cfg.ClientCert != "" || cfg.ClientKey != "" {
+		tlsConfig, err := tlsConfigFor(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if cfg.ConnectTimeout > 0 {
+		connect := time.Duration(cfg.ConnectTimeout) * time.Second
+		transport.DialContext = (&net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = connect
+	}
+	if cfg.FirstByteTimeout > 0 {
+		transport.ResponseHeaderTimeout = time.Duration(cfg.FirstByteTimeout) * time.Second
+	}
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	var rt http.RoundTripper = transport
+	if len(cfg.Headers) > 0 {
+		headers := make(map[string]string, len(cfg.Headers))
+		for name, value := range cfg.Headers {
+			headers[name] = os.ExpandEnv(value)
+		}
+		rt = &headerTransport{base: transport, headers: headers}
+	}
+	return &http.Client{Transport: rt, Timeout: timeout}, nil
+}
+
+// tlsConfigFor loads the CA bundle and client certificate in cfg.
+func tlsConfigFor(cfg config.HTTPConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(expandHome(cfg.CABundle))
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s holds no PEM certificates", cfg.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		if cfg.ClientCert == "" || cfg.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(expandHome(cfg.ClientCert), expandHome(cfg.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// headerTransport adds configured headers to every request, overriding any
+// the provider set, so a gateway's own auth header takes effect.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+)
+
+func TestGenericModelHTTPSettings(t *testing.T) {
+	var gotAuth, gotProject string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotProject = r.Header.Get("X-Project")
+		w.Write([]byte(`{"model":"gw-model","choices":[{"message":{"content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	caBundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caBundle, cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GATEWAY_TOKEN", "gw-token")
+
+	models := map[string]config.ModelConfig{
+		"gateway": {
+			Provider: GenericProviderName,
+			Model:    "gw-model",
+			Endpoint: srv.URL,
+			HTTP: config.HTTPConfig{
+				CABundle: caBundle,
+				Headers: map[string]string{
+					"Authorization": "Bearer ${GATEWAY_TOKEN}",
+					"X-Project":     "kanban",
+				},
+			},
+		},
+	}
+	r := NewRegistry()
+	r.RegisterModels(models)
+	if err := r.RegisterDedicatedProviders(models); err != nil {
+		t.Fatalf("RegisterDedicatedProviders() error = %v", err)
+	}
+
+	resp, err := r.Invoke(context.Background(), "gateway", Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if resp.Content != "ok" {
+		t.Errorf("Content = %q, want ok", resp.Content)
+	}
+	if gotAuth != "Bearer gw-token" || gotProject != "kanban" {
+		t.Errorf("headers = %q, %q", gotAuth, gotProject)
+	}
+
+	err = r.RegisterDedicatedProviders(map[string]config.ModelConfig{
+		"nowhere": {Provider: GenericProviderName, Model: "m"},
+	})
+	if err == nil {
+		t.Error("expected error for a generic model without an endpoint")
+	}
+}
+
+func TestHTTPClientFirstByteTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(3 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewHTTPClient(config.HTTPConfig{FirstByteTimeout: 1}, time.Minute)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	start := time.Now()
+	if _, err := client.Get(srv.URL); err == nil {
+		t.Fatal("expected a timeout waiting for response headers")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %s, want about 1s", elapsed)
+	}
+
+	if _, err := NewHTTPClient(config.HTTPConfig{ClientCert: "cert.pem"}, 0); err == nil {
+		t.Error("expected error for a client cert without a key")
+	}
+}
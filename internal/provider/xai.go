@@ -16,11 +16,13 @@ func NewXAIProvider(model string) *XAIProvider {
 
 	return &XAIProvider{
 		OpenAICompatProvider: NewOpenAICompatProvider(OpenAICompatConfig{
-			Name:      "xai",
-			APIKeyEnv: "XAI_API_KEY",
-			Endpoint:  xaiAPIEndpoint,
-			Model:     model,
-			MaxTokens: 8192,
+			Name:        "xai",
+			APIKeyEnv:   "XAI_API_KEY",
+			Endpoint:    xaiAPIEndpoint,
+			Model:       model,
+			MaxTokens:   8192,
+			StreamUsage: true,
+			Images:      true,
 		}),
 	}
 }
@@ -0,0 +1,102 @@
+// Package redact scrubs secrets from text before it is written to disk: API
+// keys, bearer tokens, private keys, .env-style assignments and any patterns
+// the configuration adds.
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Placeholder replaces each secret found.
+const Placeholder = "[REDACTED]"
+
+// builtin matches secrets common enough to scrub everywhere. Patterns with a
+// group named "secret" replace only that group, keeping the surrounding
+// context (such as the variable name) readable.
+var builtin = []string{
+	// Vendor API keys: OpenAI and Anthropic, Google, Groq, xAI, GitHub, AWS, Slack
+	`\bsk-[A-Za-z0-9_-]{20,}`,
+	`\bAIza[0-9A-Za-z_-]{35}`,
+	`\bgsk_[A-Za-z0-9]{20,}`,
+	`\bxai-[A-Za-z0-9]{20,}`,
+	`\bgh[pousr]_[A-Za-z0-9]{36,}`,
+	`\bAKIA[0-9A-Z]{16}\b`,
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+
+	// Authorization headers
+	`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/=-]{8,})`,
+
+	// .env-style assignments to names ending in KEY, TOKEN, SECRET, PASSWORD...
+	`\b[A-Z][A-Z0-9_]*(?:KEY|TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIALS?)\s*=\s*(?P<secret>"[^"\n]*"|'[^'\n]*'|[^\s"'$#]+)`,
+
+	// PEM private keys
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// Redactor replaces secrets in text with Placeholder. It is safe for
+// concurrent use.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// New creates a redactor for the built-in patterns and any extra ones. If an
+// extra pattern does not compile, New reports it and still returns a
+// redactor using the rest.
+func New(extra []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, p := range builtin {
+		r.patterns = append(r.patterns, regexp.MustCompile(p))
+	}
+
+	var errs []error
+	for _, p := range extra {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid redaction pattern %q: %w", p, err))
+			continue
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, errors.Join(errs...)
+}
+
+// Redact returns s with every secret replaced. A nil Redactor returns s
+// unchanged.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, re := range r.patterns {
+		s = replace(re, s)
+	}
+	return s
+}
+
+// replace substitutes each match of re in s, or just its "secret" group when
+// re has one.
+func replace(re *regexp.Regexp, s string) string {
+	group := re.SubexpIndex("secret")
+	if group < 0 {
+		return re.ReplaceAllLiteralString(s, Placeholder)
+	}
+
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 {
+			continue
+		}
+		out = append(out, s[last:start]...)
+		out = append(out, Placeholder...)
+		last = end
+	}
+	out = append(out, s[last:]...)
+	return string(out)
+}
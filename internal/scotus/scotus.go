@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/jxmullins/thekanbansociety/internal/config"
+	"github.com/jxmullins/thekanbansociety/internal/history"
 	"github.com/jxmullins/thekanbansociety/internal/provider"
 )
 
@@ -56,12 +57,14 @@ type Session struct {
 	MajorityOpinion string
 	DissentOpinion  string
 	StartTime    time.Time
+	Proceedings  []history.Turn // Arguments, questions and deliberation so far
 }
 
 // Runner orchestrates SCOTUS debate sessions.
 type Runner struct {
 	registry *provider.Registry
 	config   *config.Config
+	history  *history.Manager
 }
 
 // NewRunner creates a new SCOTUS runner.
@@ -69,6 +72,7 @@ func NewRunner(registry *provider.Registry, cfg *config.Config) *Runner {
 	return &Runner{
 		registry: registry,
 		config:   cfg,
+		history:  history.NewManager(registry, cfg.Context),
 	}
 }
 
@@ -79,6 +83,7 @@ func (r *Runner) Run(ctx context.Context, opts Options) error {
 		Justices:  opts.Justices,
 		StartTime: time.Now(),
 	}
+	ctx = provider.WithSessionID(ctx, session.ID)
 
 	r.printHeader(opts)
 
@@ -235,6 +240,11 @@ Consider constitutional principles, precedents, and practical implications.`,
 
 		fmt.Println(resp.Content)
 		fmt.Println()
+		session.Proceedings = append(session.Proceedings, history.Turn{
+			Speaker: "Justice " + r.getDisplayName(justice),
+			Heading: "Opening",
+			Content: resp.Content,
+		})
 	}
 
 	return nil
@@ -267,6 +277,11 @@ Pose 2-3 pointed questions to guide the deliberation. Focus on:
 	fmt.Printf("Chief Justice %s:\n", r.getDisplayName(session.ChiefJustice))
 	fmt.Println(resp.Content)
 	fmt.Println()
+	session.Proceedings = append(session.Proceedings, history.Turn{
+		Speaker: "Chief Justice " + r.getDisplayName(session.ChiefJustice),
+		Heading: "Questions",
+		Content: resp.Content,
+	})
 
 	return nil
 }
@@ -277,6 +292,8 @@ func (r *Runner) runDeliberationRound(ctx context.Context, opts Options, session
 	fmt.Println("───────────────────────────────────────────────────────")
 	fmt.Println()
 
+	// Every justice answers the proceedings as they stood when the round began
+	proceedings := session.Proceedings
 	for _, justice := range session.Justices {
 		fmt.Printf("Justice %s:\n", r.getDisplayName(justice))
 
@@ -284,8 +301,12 @@ func (r *Runner) runDeliberationRound(ctx context.Context, opts Options, session
 
 Resolution: %s
 
+Proceedings so far:
+%s
+
 Respond to the Chief Justice's questions and engage with other justices' positions.
-Clarify or refine your position.`, round, session.Resolution.Formal)
+Clarify or refine your position.`, round, session.Resolution.Formal,
+			r.history.Fit(ctx, justice, proceedings, session.Resolution.Formal))
 
 		resp, err := r.registry.Invoke(ctx, justice, provider.Request{
 			Prompt:       prompt,
@@ -298,6 +319,11 @@ Clarify or refine your position.`, round, session.Resolution.Formal)
 
 		fmt.Println(resp.Content)
 		fmt.Println()
+		session.Proceedings = append(session.Proceedings, history.Turn{
+			Speaker: "Justice " + r.getDisplayName(justice),
+			Heading: fmt.Sprintf("Round %d", round),
+			Content: resp.Content,
+		})
 	}
 
 	return nil
@@ -314,30 +340,25 @@ func (r *Runner) collectVotes(ctx context.Context, opts Options, session *Sessio
 
 Resolution: %s
 
-Respond with:
-VOTE: AFFIRM or REJECT
-REASONING: [One sentence explaining your vote]`, session.Resolution.Formal)
+Reply with JSON giving your vote (AFFIRM or REJECT) and one sentence of
+reasoning.`, session.Resolution.Formal)
 
-		resp, err := r.registry.Invoke(ctx, justice, provider.Request{
-			Prompt:       prompt,
-			SystemPrompt: r.getJusticeSystemPrompt(justice),
-		})
+		var reply voteReply
+		_, err := r.registry.InvokeJSON(ctx, justice, provider.Request{
+			Prompt:         prompt,
+			SystemPrompt:   r.getJusticeSystemPrompt(justice),
+			ResponseSchema: voteSchema,
+		}, &reply)
 		if err != nil {
+			fmt.Printf("Justice %s: [no valid vote: %v]\n", r.getDisplayName(justice), err)
 			continue
 		}
 
-		vote := Vote{JusticeID: justice}
-		lines := strings.Split(resp.Content, "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "VOTE:") {
-				voteStr := strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(line, "VOTE:")))
-				vote.Position = strings.Contains(voteStr, "AFFIRM")
-			} else if strings.HasPrefix(line, "REASONING:") {
-				vote.Reasoning = strings.TrimSpace(strings.TrimPrefix(line, "REASONING:"))
-			}
+		vote := Vote{
+			JusticeID: justice,
+			Position:  reply.Vote == "AFFIRM",
+			Reasoning: reply.Reasoning,
 		}
-
 		session.Votes = append(session.Votes, vote)
 
 		position := "REJECT"
@@ -351,6 +372,36 @@ REASONING: [One sentence explaining your vote]`, session.Resolution.Formal)
 	return nil
 }
 
+// voteReply is a justice's structured vote.
+type voteReply struct {
+	Vote      string `json:"vote"`
+	Reasoning string `json:"reasoning"`
+}
+
+// voteSchema is the JSON Schema for a vote.
+var voteSchema = &provider.ResponseSchema{
+	Name: "vote",
+	Schema: map[string]any{
+		"type":     "object",
+		"required": []string{"vote", "reasoning"},
+		"properties": map[string]any{
+			"vote":      map[string]any{"type": "string", "enum": []string{"AFFIRM", "REJECT"}},
+			"reasoning": map[string]any{"type": "string"},
+		},
+	},
+}
+
+// Validate checks the vote is cast and explained.
+func (v *voteReply) Validate() error {
+	if v.Vote != "AFFIRM" && v.Vote != "REJECT" {
+		return fmt.Errorf("vote must be AFFIRM or REJECT, got %q", v.Vote)
+	}
+	if strings.TrimSpace(v.Reasoning) == "" {
+		return fmt.Errorf("vote has no reasoning")
+	}
+	return nil
+}
+
 func (r *Runner) writeOpinions(ctx context.Context, opts Options, session *Session) error {
 	fmt.Println("───────────────────────────────────────────────────────")
 	fmt.Println("  Writing Opinions")
@@ -21,6 +21,10 @@ const (
 	EventUserTaskCompleted
 	EventError
 	EventSessionComplete
+	EventModelFallback
+	EventToolCalled
+	EventRateLimited
+	EventProviderCall
 )
 
 func (e EventType) String() string {
@@ -53,6 +57,14 @@ func (e EventType) String() string {
 		return "Error"
 	case EventSessionComplete:
 		return "SessionComplete"
+	case EventModelFallback:
+		return "ModelFallback"
+	case EventToolCalled:
+		return "ToolCalled"
+	case EventRateLimited:
+		return "RateLimited"
+	case EventProviderCall:
+		return "ProviderCall"
 	default:
 		return "Unknown"
 	}
@@ -78,8 +90,9 @@ type TaskCreatedData struct {
 
 // TaskProgressData contains data for TaskProgress events.
 type TaskProgressData struct {
-	Content  string  // Streaming chunk
-	Progress float64 // 0.0-1.0
+	Content   string  // Streaming chunk
+	Reasoning string  // Thinking text, kept out of Content and artifacts
+	Progress  float64 // 0.0-1.0
 }
 
 // PMDecisionData contains data for PMDecision events.
@@ -108,6 +121,32 @@ type ErrorData struct {
 	Message string
 }
 
+// ModelFallbackData contains data for ModelFallback events.
+type ModelFallbackData struct {
+	From  string // AI ID that failed
+	To    string // AI ID answering instead
+	Error error
+}
+
+// RateLimitedData contains data for RateLimited events.
+type RateLimitedData struct {
+	Limiter string        // Limit that held the call, e.g. "provider openai"
+	Wait    time.Duration // Time the call spent queued
+}
+
+// ProviderCallData contains data for ProviderCall events.
+type ProviderCallData struct {
+	Message string // e.g. "invoke done in 1.2s, 830 in / 212 out tokens"
+}
+
+// ToolCalledData contains data for ToolCalled events.
+type ToolCalledData struct {
+	Tool      string
+	Arguments string // JSON-encoded arguments
+	Result    string
+	Error     error
+}
+
 // UserTaskCompletedData contains data for UserTaskCompleted events.
 type UserTaskCompletedData struct {
 	Notes string // Optional notes from user
@@ -3,10 +3,12 @@ package team
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/jxmullins/thekanbansociety/internal/config"
+	"github.com/jxmullins/thekanbansociety/internal/history"
 	"github.com/jxmullins/thekanbansociety/internal/provider"
 )
 
@@ -15,7 +17,11 @@ type ModeExecutor struct {
 	registry *provider.Registry
 	config   *config.Config
 	session  *Session
-	events   chan Event // Event channel for TUI
+	events   chan Event       // Event channel for TUI
+	history  *history.Manager // Fits accumulated discussion into each prompt
+
+	toolRoot      string // Workspace root for member tools; empty disables tools
+	allowCommands bool
 }
 
 // emit sends an event to the events channel.
@@ -39,6 +45,7 @@ func NewModeExecutor(registry *provider.Registry, cfg *config.Config, session *S
 		registry: registry,
 		config:   cfg,
 		session:  session,
+		history:  history.NewManager(registry, cfg.Context),
 	}
 }
 
@@ -65,6 +72,48 @@ func (e *ModeExecutor) Execute(ctx context.Context, opts Options) ([]Artifact, e
 	}
 }
 
+// enableTools gives members a workspace toolbox rooted at dir.
+func (e *ModeExecutor) enableTools(dir string, allowCommands bool) error {
+	if dir == "" {
+		dir = "."
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolving workspace: %w", err)
+	}
+	e.toolRoot = root
+	e.allowCommands = allowCommands
+	return nil
+}
+
+// invoke sends a member request with the attachments its task needs, running
+// the tool loop when tools are enabled.
+func (e *ModeExecutor) invoke(ctx context.Context, aiID string, req provider.Request) (*provider.Response, error) {
+	req.Attachments = e.attachments(aiID)
+	if e.toolRoot == "" {
+		return e.registry.Invoke(ctx, aiID, req)
+	}
+
+	tools := workspaceTools(e.toolRoot, e.allowCommands)
+	tools.OnCall = func(call provider.ToolCall, result string, err error) {
+		if e.events == nil {
+			fmt.Printf("  [%s] %s %s\n", e.getDisplayName(aiID), call.Name, call.Arguments)
+		}
+		e.emit(NewEvent(EventToolCalled, aiID, ToolCalledData{
+			Tool:      call.Name,
+			Arguments: call.Arguments,
+			Result:    result,
+			Error:     err,
+		}))
+	}
+	return e.registry.InvokeWithTools(ctx, aiID, req, tools, provider.DefaultMaxToolSteps)
+}
+
+// attachments returns the attached files for aiID's task that it can read.
+func (e *ModeExecutor) attachments(aiID string) []provider.Attachment {
+	return readableAttachments(e.registry, aiID, e.session.attachmentsFor(aiID))
+}
+
 // executePairProgramming runs pair programming mode.
 // Two AIs collaborate on the same artifact, taking turns.
 func (e *ModeExecutor) executePairProgramming(ctx context.Context, opts Options) ([]Artifact, error) {
@@ -87,6 +136,11 @@ func (e *ModeExecutor) executePairProgramming(ctx context.Context, opts Options)
 
 	var artifacts []Artifact
 	var currentWork strings.Builder
+	var lastReview string
+
+	// Each member keeps a conversation per role so turns build on real
+	// history, even when the same AI drives and navigates
+	conversations := make(map[string][]provider.Message)
 
 	// Iterate: driver writes, navigator reviews
 	for i := 0; i < 3; i++ {
@@ -100,12 +154,10 @@ Task: %s
 
 Write or continue the implementation. Be specific and produce actual code/content.`,
 			e.session.Task,
-			e.buildContext(currentWork.String(), i))
+			e.buildContext(lastReview, i))
 
-		resp, err := e.registry.Invoke(ctx, driver, provider.Request{
-			Prompt:       prompt,
-			SystemPrompt: "You are a skilled developer working in pair programming mode.",
-		})
+		resp, err := e.invokeTurn(ctx, driver, "driver", conversations, prompt,
+			"You are a skilled developer working in pair programming mode.")
 		if err != nil {
 			return nil, fmt.Errorf("driver failed: %w", err)
 		}
@@ -126,16 +178,16 @@ Driver's output:
 Review and suggest improvements. Point out any issues or optimizations.`,
 			e.session.Task, resp.Content)
 
-		reviewResp, err := e.registry.Invoke(ctx, navigator, provider.Request{
-			Prompt:       reviewPrompt,
-			SystemPrompt: "You are a code reviewer in pair programming mode.",
-		})
+		reviewResp, err := e.invokeTurn(ctx, navigator, "navigator", conversations, reviewPrompt,
+			"You are a code reviewer in pair programming mode.")
 		if err != nil {
 			fmt.Printf("Navigator review failed: %v\n", err)
+			lastReview = ""
 		} else {
 			fmt.Println(truncateOutput(reviewResp.Content, 300))
 			currentWork.WriteString("\n\n### Navigator Review:\n")
 			currentWork.WriteString(reviewResp.Content)
+			lastReview = reviewResp.Content
 		}
 
 		fmt.Println()
@@ -162,7 +214,7 @@ func (e *ModeExecutor) executeConsultation(ctx context.Context, opts Options) ([
 	fmt.Printf("PM %s leads, consulting team as needed\n\n", e.getDisplayName(e.session.PM))
 
 	var artifacts []Artifact
-	var context strings.Builder
+	var turns []history.Turn
 
 	// PM starts with initial approach
 	fmt.Printf("%s (PM) - Initial Approach:\n", e.getDisplayName(e.session.PM))
@@ -176,14 +228,18 @@ Outline your approach and identify what input you need from team members.`,
 	resp, err := e.registry.Invoke(ctx, e.session.PM, provider.Request{
 		Prompt:       initialPrompt,
 		SystemPrompt: "You are a project manager leading a team.",
+		Attachments:  e.attachments(e.session.PM),
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	fmt.Println(truncateOutput(resp.Content, 500))
-	context.WriteString("PM Initial Approach:\n")
-	context.WriteString(resp.Content)
+	turns = append(turns, history.Turn{
+		Speaker: e.getDisplayName(e.session.PM),
+		Heading: "PM Initial Approach",
+		Content: resp.Content,
+	})
 	fmt.Println()
 
 	// Consult each team member
@@ -200,9 +256,9 @@ Outline your approach and identify what input you need from team members.`,
 Task: %s
 
 Provide your expert input, suggestions, or concerns.`,
-			context.String(), e.session.Task)
+			e.history.Fit(ctx, member, turns, e.session.Task), e.session.Task)
 
-		memberResp, err := e.registry.Invoke(ctx, member, provider.Request{
+		memberResp, err := e.invoke(ctx, member, provider.Request{
 			Prompt:       consultPrompt,
 			SystemPrompt: "You are a team member providing consultation.",
 		})
@@ -212,7 +268,11 @@ Provide your expert input, suggestions, or concerns.`,
 		}
 
 		fmt.Println(truncateOutput(memberResp.Content, 400))
-		context.WriteString(fmt.Sprintf("\n\n%s's Input:\n%s", e.getDisplayName(member), memberResp.Content))
+		turns = append(turns, history.Turn{
+			Speaker: e.getDisplayName(member),
+			Heading: "Input",
+			Content: memberResp.Content,
+		})
 		fmt.Println()
 	}
 
@@ -223,7 +283,7 @@ Provide your expert input, suggestions, or concerns.`,
 %s
 
 Produce the final deliverable for: %s`,
-		context.String(), e.session.Task)
+		e.history.Fit(ctx, e.session.PM, turns, e.session.Task), e.session.Task)
 
 	finalResp, err := e.registry.Invoke(ctx, e.session.PM, provider.Request{
 		Prompt:       finalPrompt,
@@ -253,7 +313,7 @@ func (e *ModeExecutor) executeRoundRobin(ctx context.Context, opts Options) ([]A
 	fmt.Println("Sequential contributions from each member")
 
 	var artifacts []Artifact
-	var accumulated strings.Builder
+	var contributions []history.Turn
 
 	for round := 1; round <= 2; round++ {
 		fmt.Printf("--- Round %d ---\n\n", round)
@@ -267,9 +327,9 @@ Previous contributions:
 %s
 
 Add your contribution. Build on what others have done.`,
-				e.session.Task, accumulated.String())
+				e.session.Task, e.history.Fit(ctx, member, contributions, e.session.Task))
 
-			resp, err := e.registry.Invoke(ctx, member, provider.Request{
+			resp, err := e.invoke(ctx, member, provider.Request{
 				Prompt:       prompt,
 				SystemPrompt: "You are contributing to a collaborative project.",
 			})
@@ -279,8 +339,11 @@ Add your contribution. Build on what others have done.`,
 			}
 
 			fmt.Println(truncateOutput(resp.Content, 400))
-			accumulated.WriteString(fmt.Sprintf("\n\n### %s (Round %d):\n%s",
-				e.getDisplayName(member), round, resp.Content))
+			contributions = append(contributions, history.Turn{
+				Speaker: e.getDisplayName(member),
+				Heading: fmt.Sprintf("Round %d", round),
+				Content: resp.Content,
+			})
 			fmt.Println()
 		}
 	}
@@ -288,7 +351,7 @@ Add your contribution. Build on what others have done.`,
 	artifacts = append(artifacts, Artifact{
 		Name:        "round_robin_output.md",
 		Type:        ArtifactDocument,
-		Content:     accumulated.String(),
+		Content:     history.Render(contributions),
 		Description: "Combined round-robin contributions",
 		CreatedBy:   strings.Join(e.session.Members, ", "),
 	})
@@ -312,7 +375,8 @@ List each subtask clearly numbered.`,
 		len(e.session.Members), e.session.Task)
 
 	divideResp, err := e.registry.Invoke(ctx, e.session.PM, provider.Request{
-		Prompt: dividePrompt,
+		Prompt:      dividePrompt,
+		Attachments: e.attachments(e.session.PM),
 	})
 	if err != nil {
 		return nil, err
@@ -323,7 +387,7 @@ List each subtask clearly numbered.`,
 
 	// Execute subtasks in parallel
 	var wg sync.WaitGroup
-	results := make([]string, len(e.session.Members))
+	results := make([]history.Turn, len(e.session.Members))
 	errors := make([]error, len(e.session.Members))
 
 	for i, member := range e.session.Members {
@@ -344,31 +408,27 @@ Your subtask number: %d
 Subtask breakdown: %s`,
 				e.session.Task, idx+1, divideResp.Content)
 
-			// Use streaming to emit progress
-			streamCh, err := e.registry.Stream(ctx, m, provider.Request{
+			content, err := e.streamSubtask(ctx, m, tid, provider.Request{
 				Prompt: subtaskPrompt,
 			})
+			heading := fmt.Sprintf("Subtask %d", idx+1)
 			if err != nil {
 				errors[idx] = err
-				e.emitTask(EventError, tid, m, ErrorData{Error: err, TaskID: tid})
-				return
-			}
-
-			var content strings.Builder
-			for chunk := range streamCh {
-				if chunk.Error != nil {
-					errors[idx] = chunk.Error
+				if content == "" {
 					return
 				}
-				content.WriteString(chunk.Content)
-				e.emitTask(EventTaskProgress, tid, m, TaskProgressData{
-					Content:  chunk.Content,
-					Progress: 0.5, // Could calculate based on expected length
-				})
+				// Keep what arrived so the merge can still use it
+				heading += " (incomplete)"
 			}
 
-			results[idx] = fmt.Sprintf("### %s (Subtask %d):\n%s", e.getDisplayName(m), idx+1, content.String())
-			e.emitTask(EventTaskCompleted, tid, m, nil)
+			results[idx] = history.Turn{
+				Speaker: e.getDisplayName(m),
+				Heading: heading,
+				Content: content,
+			}
+			if err == nil {
+				e.emitTask(EventTaskCompleted, tid, m, nil)
+			}
 		}(i, member, taskID)
 	}
 
@@ -384,11 +444,10 @@ Subtask breakdown: %s`,
 	// Merge results
 	fmt.Printf("\n%s (PM) merging results...\n", e.getDisplayName(e.session.PM))
 
-	var allResults strings.Builder
+	var completed []history.Turn
 	for _, r := range results {
-		if r != "" {
-			allResults.WriteString(r)
-			allResults.WriteString("\n\n")
+		if r.Content != "" {
+			completed = append(completed, r)
 		}
 	}
 
@@ -396,7 +455,7 @@ Subtask breakdown: %s`,
 %s
 
 Original task: %s`,
-		allResults.String(), e.session.Task)
+		e.history.Fit(ctx, e.session.PM, completed, e.session.Task), e.session.Task)
 
 	mergeResp, err := e.registry.Invoke(ctx, e.session.PM, provider.Request{
 		Prompt: mergePrompt,
@@ -416,6 +475,61 @@ Original task: %s`,
 	}}, nil
 }
 
+// streamSubtask runs a subtask, streaming progress to the board. Tool loops
+// and members that cannot stream send the whole result at once. On failure it
+// returns the partial result along with the error.
+func (e *ModeExecutor) streamSubtask(ctx context.Context, aiID, taskID string, req provider.Request) (string, error) {
+	ctx = provider.WithTaskID(ctx, taskID)
+	caps, err := e.registry.Capabilities(aiID)
+	if err != nil {
+		e.emitTask(EventError, taskID, aiID, ErrorData{Error: err, TaskID: taskID})
+		return "", err
+	}
+	if e.toolRoot != "" || !caps.Streaming {
+		resp, err := e.invoke(ctx, aiID, req)
+		if err != nil {
+			e.emitTask(EventError, taskID, aiID, ErrorData{Error: err, TaskID: taskID})
+			return "", err
+		}
+		e.emitTask(EventTaskProgress, taskID, aiID, TaskProgressData{
+			Content:   resp.Content,
+			Reasoning: resp.Reasoning,
+			Progress:  1,
+		})
+		return resp.Content, nil
+	}
+
+	// Use streaming to emit progress. A stream that stalls or breaks off
+	// partway is continued from what arrived rather than started over.
+	req.Attachments = e.attachments(aiID)
+	resp, err := e.registry.StreamResumable(ctx, aiID, req, func(chunk provider.StreamChunk) {
+		// CLI agents run their own tools; show them alongside ours
+		for _, call := range chunk.AgentToolCalls {
+			e.emitTask(EventToolCalled, taskID, aiID, ToolCalledData{Tool: call.Name, Arguments: call.Arguments})
+		}
+		if chunk.Content == "" && chunk.Reasoning == "" {
+			return
+		}
+		e.emitTask(EventTaskProgress, taskID, aiID, TaskProgressData{
+			Content:   chunk.Content,
+			Reasoning: chunk.Reasoning,
+			Progress:  0.5, // Could calculate based on expected length
+		})
+	}, func(partial string, err error) {
+		e.emitTask(EventProviderCall, taskID, aiID, ProviderCallData{
+			Message: fmt.Sprintf("stream interrupted after %d chars (%v); continuing", len(partial), err),
+		})
+	})
+	if err != nil {
+		e.emitTask(EventError, taskID, aiID, ErrorData{Error: err, TaskID: taskID})
+		if resp != nil {
+			return resp.Content, err
+		}
+		return "", err
+	}
+	return resp.Content, nil
+}
+
 // executeFreeForm runs free-form collaboration.
 // Open collaboration with PM moderation.
 func (e *ModeExecutor) executeFreeForm(ctx context.Context, opts Options) ([]Artifact, error) {
@@ -423,7 +537,7 @@ func (e *ModeExecutor) executeFreeForm(ctx context.Context, opts Options) ([]Art
 	fmt.Println("Open collaboration with PM moderation")
 
 	var artifacts []Artifact
-	var discussion strings.Builder
+	var discussion []history.Turn
 
 	// Initial brainstorm from all members
 	fmt.Println("--- Brainstorming Phase ---")
@@ -436,7 +550,7 @@ func (e *ModeExecutor) executeFreeForm(ctx context.Context, opts Options) ([]Art
 Share your initial thoughts, ideas, and approach for this task.`,
 			e.session.Task)
 
-		resp, err := e.registry.Invoke(ctx, member, provider.Request{
+		resp, err := e.invoke(ctx, member, provider.Request{
 			Prompt: prompt,
 		})
 		if err != nil {
@@ -445,7 +559,7 @@ Share your initial thoughts, ideas, and approach for this task.`,
 		}
 
 		fmt.Println(truncateOutput(resp.Content, 300))
-		discussion.WriteString(fmt.Sprintf("### %s:\n%s\n\n", e.getDisplayName(member), resp.Content))
+		discussion = append(discussion, history.Turn{Speaker: e.getDisplayName(member), Content: resp.Content})
 		fmt.Println()
 	}
 
@@ -458,7 +572,7 @@ Share your initial thoughts, ideas, and approach for this task.`,
 
 As PM, synthesize these ideas and provide direction for the final deliverable.
 Task: %s`,
-		discussion.String(), e.session.Task)
+		e.history.Fit(ctx, e.session.PM, discussion, e.session.Task), e.session.Task)
 
 	synthResp, err := e.registry.Invoke(ctx, e.session.PM, provider.Request{
 		Prompt: synthesisPrompt,
@@ -478,7 +592,7 @@ Task: %s`,
 %s
 
 Produce the final deliverable.`,
-		discussion.String(), synthResp.Content)
+		e.history.Fit(ctx, e.session.PM, discussion, synthResp.Content), synthResp.Content)
 
 	finalResp, err := e.registry.Invoke(ctx, e.session.PM, provider.Request{
 		Prompt: finalPrompt,
@@ -507,11 +621,34 @@ func (e *ModeExecutor) getDisplayName(aiID string) string {
 	return aiID
 }
 
-func (e *ModeExecutor) buildContext(current string, iteration int) string {
-	if current == "" {
+func (e *ModeExecutor) buildContext(review string, iteration int) string {
+	if iteration == 0 {
 		return "This is the start. Begin the implementation."
 	}
-	return fmt.Sprintf("Current progress (iteration %d):\n%s\n\nContinue from here.", iteration, current)
+	if review == "" {
+		return fmt.Sprintf("Iteration %d. Continue from the work so far.", iteration+1)
+	}
+	return fmt.Sprintf("Iteration %d. Latest review from your partner:\n%s\n\nContinue from here, addressing the feedback.", iteration+1, review)
+}
+
+// invokeTurn sends prompt as the next user turn in aiID's conversation in
+// role and records the reply, so later turns carry the member's own history.
+func (e *ModeExecutor) invokeTurn(ctx context.Context, aiID, role string, conversations map[string][]provider.Message, prompt, systemPrompt string) (*provider.Response, error) {
+	key := role + "/" + aiID
+	resp, err := e.invoke(ctx, aiID, e.history.FitMessages(ctx, aiID, provider.Request{
+		Prompt:       prompt,
+		Messages:     conversations[key],
+		SystemPrompt: systemPrompt,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	conversations[key] = append(conversations[key],
+		provider.Message{Role: provider.RoleUser, Content: prompt},
+		provider.Message{Role: provider.RoleAssistant, Content: resp.Content},
+	)
+	return resp, nil
 }
 
 func truncateOutput(s string, maxLen int) string {
@@ -0,0 +1,86 @@
+package team
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jxmullins/thekanbansociety/internal/config"
+	"github.com/jxmullins/thekanbansociety/internal/provider"
+)
+
+// recordingProvider answers every call and keeps the requests it was sent.
+type recordingProvider struct {
+	requests []provider.Request
+}
+
+func (p *recordingProvider) Name() string { return "solo" }
+
+func (p *recordingProvider) Invoke(ctx context.Context, req provider.Request) (*provider.Response, error) {
+	p.requests = append(p.requests, req)
+	return &provider.Response{Content: "done"}, nil
+}
+
+func (p *recordingProvider) Stream(ctx context.Context, req provider.Request) (<-chan provider.StreamChunk, error) {
+	resp, _ := p.Invoke(ctx, req)
+	ch := make(chan provider.StreamChunk, 1)
+	ch <- provider.StreamChunk{Content: resp.Content, Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func (p *recordingProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func TestPairProgrammingSameAIKeepsRolesApart(t *testing.T) {
+	cfg := &config.Config{Models: map[string]config.ModelConfig{"solo": {Provider: "solo"}}}
+	p := &recordingProvider{}
+	registry := provider.NewRegistry()
+	registry.Register(p)
+	registry.RegisterModels(cfg.Models)
+
+	session := &Session{Task: "Write a parser", Members: []string{"solo", "solo"}}
+	if _, err := NewModeExecutor(registry, cfg, session).executePairProgramming(context.Background(), Options{}); err != nil {
+		t.Fatalf("executePairProgramming() error = %v", err)
+	}
+
+	if len(p.requests) != 6 {
+		t.Fatalf("made %d calls, want 6", len(p.requests))
+	}
+	for _, req := range p.requests {
+		driving := strings.HasPrefix(req.Prompt, "You are the driver")
+		for _, m := range req.Messages {
+			if m.Role == provider.RoleUser && strings.HasPrefix(m.Content, "You are the driver") != driving {
+				t.Fatalf("a %q turn carried the other role's history: %q", req.SystemPrompt, m.Content)
+			}
+		}
+	}
+	if last := p.requests[5]; len(last.Messages) != 4 {
+		t.Errorf("last navigator turn carried %d messages, want its own 4", len(last.Messages))
+	}
+}
+
+func TestPMPlanningSeesAttachments(t *testing.T) {
+	cfg := &config.Config{Models: map[string]config.ModelConfig{"solo": {Provider: "solo"}}}
+	spec := provider.Attachment{Name: "spec.md", MIMEType: "text/markdown", Data: []byte("# Spec")}
+
+	for _, mode := range []WorkMode{ModeConsultation, ModeDivideConquer} {
+		p := &recordingProvider{}
+		registry := provider.NewRegistry()
+		registry.Register(p)
+		registry.RegisterModels(cfg.Models)
+
+		session := &Session{
+			Task:        "Write a parser",
+			PM:          "solo",
+			Mode:        mode,
+			Members:     []string{"solo"},
+			Attachments: []provider.Attachment{spec},
+		}
+		if _, err := NewModeExecutor(registry, cfg, session).Execute(context.Background(), Options{}); err != nil {
+			t.Fatalf("%s Execute() error = %v", mode, err)
+		}
+		if first := p.requests[0]; len(first.Attachments) != 1 || len(first.Tools) != 0 {
+			t.Errorf("%s PM's first call had attachments %v and %d tools, want the spec and no tools", mode, first.Attachments, len(first.Tools))
+		}
+	}
+}
@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/jxmullins/thekanbansociety/internal/budget"
 	"github.com/jxmullins/thekanbansociety/internal/config"
 	"github.com/jxmullins/thekanbansociety/internal/provider"
+	"github.com/jxmullins/thekanbansociety/internal/redact"
 )
 
 // WorkMode represents the team collaboration mode.
@@ -36,14 +39,20 @@ const (
 // Options configures a team session.
 type Options struct {
 	Task            string
-	PM              string          // Forced PM, or empty for auto-selection
-	Mode            WorkMode        // Forced mode, or empty for PM decision
-	Members         []string        // Team members (excluding PM)
-	IncludeArbiter  bool            // Include 4th AI as arbiter
+	PM              string   // Forced PM, or empty for auto-selection
+	Mode            WorkMode // Forced mode, or empty for PM decision
+	Members         []string // Team members (excluding PM)
+	IncludeArbiter  bool     // Include 4th AI as arbiter
 	CheckpointLevel CheckpointLevel
 	ShowCosts       bool
 	OutputDir       string
 	Verbose         bool
+	Tools           bool                  // Give members workspace tools (read_file, list_files)
+	AllowCommands   bool                  // Also offer run_command; requires Tools
+	Workspace       string                // Root for tools (default: current directory)
+	CLIProfile      string                // Permission profile CLI agents run under, if any
+	CLIDir          string                // Working directory CLI agents are pinned to
+	Attachments     []provider.Attachment // Files for the PM's plan and the members' tasks
 }
 
 // Phase represents a team workflow phase.
@@ -90,6 +99,9 @@ type Session struct {
 	Artifacts   []Artifact
 	Checkpoints []Checkpoint
 	Plan        *Plan
+	CLIProfile  string // Permission profile CLI agents ran under, if any
+	CLIDir      string // Working directory CLI agents were pinned to
+	Attachments []provider.Attachment
 }
 
 // Plan holds the PM's work plan.
@@ -106,6 +118,7 @@ type PlanStep struct {
 	Description string
 	AssignedTo  string
 	DependsOn   []string
+	Attachments []string // Names of the attached files the step needs
 	Status      string
 }
 
@@ -113,16 +126,48 @@ type PlanStep struct {
 type Runner struct {
 	registry *provider.Registry
 	config   *config.Config
-	Events   chan Event // Event channel for TUI
+	usage    *budget.Tracker
+	redactor *redact.Redactor // Scrubs secrets from saved artifacts and the summary
+	Events   chan Event       // Event channel for TUI
 }
 
 // NewRunner creates a new team runner.
 func NewRunner(registry *provider.Registry, cfg *config.Config) *Runner {
-	return &Runner{
+	r := &Runner{
 		registry: registry,
 		config:   cfg,
+		usage:    budget.NewTracker(),
 		Events:   make(chan Event, 100), // Buffered channel
 	}
+	// The config's patterns were checked when it loaded; any that fail here
+	// are skipped and the built-in patterns still apply
+	r.redactor, _ = redact.New(cfg.Redaction.Patterns)
+	return r
+}
+
+// observe adds the runner's own middleware and handlers to the calls made
+// with ctx, leaving the shared registry untouched.
+func (r *Runner) observe(ctx context.Context) context.Context {
+	// Surface fallbacks so the board can show which model actually answered
+	ctx = provider.WithFallbackHandler(ctx, func(fb provider.Fallback) {
+		r.emit(NewEvent(EventModelFallback, fb.From, ModelFallbackData{
+			From:  fb.From,
+			To:    fb.To,
+			Error: fb.Err,
+		}))
+	})
+	ctx = provider.WithQueueHandler(ctx, func(qw provider.QueueWait) {
+		r.emit(NewEvent(EventRateLimited, qw.AIID, RateLimitedData{
+			Limiter: qw.Limiter,
+			Wait:    qw.Wait,
+		}))
+	})
+	return provider.WithMiddleware(ctx,
+		provider.DebugLog(func(aiID, message string) {
+			r.emit(NewEvent(EventProviderCall, aiID, ProviderCallData{Message: message}))
+		}),
+		provider.RecordUsage(r.usage),
+	)
 }
 
 // emit sends an event to the Events channel if it exists and has listeners.
@@ -139,15 +184,93 @@ func (r *Runner) emitTask(eventType EventType, taskID, actor string, data interf
 	r.emit(NewTaskEvent(eventType, taskID, actor, data))
 }
 
+// checkCapabilities rejects sessions that some member cannot take part in,
+// before any tokens are spent.
+func (r *Runner) checkCapabilities(opts Options) error {
+	if opts.PM != "" {
+		if _, err := r.registry.Capabilities(opts.PM); err != nil {
+			return fmt.Errorf("PM %q: %w", opts.PM, err)
+		}
+	}
+	for _, member := range opts.Members {
+		caps, err := r.registry.Capabilities(member)
+		if err != nil {
+			return fmt.Errorf("member %q: %w", member, err)
+		}
+		if opts.Tools && !caps.Tools {
+			return fmt.Errorf("member %q cannot call tools; remove it or run without --tools", member)
+		}
+		for _, a := range opts.Attachments {
+			if !caps.Accepts(a) {
+				fmt.Printf("Note: %s cannot read %s and will work without it\n", r.getDisplayName(member), a.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// readableAttachments returns the attachments aiID can read, leaving out
+// images and PDFs for models that only take text.
+func readableAttachments(registry *provider.Registry, aiID string, attachments []provider.Attachment) []provider.Attachment {
+	caps, err := registry.Capabilities(aiID)
+	if err != nil {
+		return nil
+	}
+	var readable []provider.Attachment
+	for _, a := range attachments {
+		if caps.Accepts(a) {
+			readable = append(readable, a)
+		}
+	}
+	return readable
+}
+
+// attachmentsFor returns the attachments relevant to aiID's work: those named
+// by its plan steps, or all of them when the plan does not say.
+func (s *Session) attachmentsFor(aiID string) []provider.Attachment {
+	if s.Plan == nil {
+		return s.Attachments
+	}
+	named := false
+	needed := make(map[string]bool)
+	for _, step := range s.Plan.Steps {
+		named = named || len(step.Attachments) > 0
+		if step.AssignedTo == aiID {
+			for _, name := range step.Attachments {
+				needed[name] = true
+			}
+		}
+	}
+	if !named {
+		return s.Attachments
+	}
+
+	var relevant []provider.Attachment
+	for _, a := range s.Attachments {
+		if needed[a.Name] {
+			relevant = append(relevant, a)
+		}
+	}
+	return relevant
+}
+
 // Run executes a team collaboration session.
 func (r *Runner) Run(ctx context.Context, opts Options) error {
 	// Create session
 	session := &Session{
-		ID:        fmt.Sprintf("team_%d", time.Now().Unix()),
-		Task:      opts.Task,
-		StartTime: time.Now(),
-		Phase:     PhaseAnalysis,
-		Members:   opts.Members,
+		ID:          fmt.Sprintf("team_%d", time.Now().Unix()),
+		Task:        opts.Task,
+		StartTime:   time.Now(),
+		Phase:       PhaseAnalysis,
+		Members:     opts.Members,
+		CLIProfile:  opts.CLIProfile,
+		CLIDir:      opts.CLIDir,
+		Attachments: opts.Attachments,
+	}
+	ctx = r.observe(provider.WithSessionID(ctx, session.ID))
+
+	if err := r.checkCapabilities(opts); err != nil {
+		return err
 	}
 
 	// Print header
@@ -233,6 +356,11 @@ func (r *Runner) Run(ctx context.Context, opts Options) error {
 
 	executor := NewModeExecutor(r.registry, r.config, session)
 	executor.events = r.Events // Pass events channel to executor
+	if opts.Tools {
+		if err := executor.enableTools(opts.Workspace, opts.AllowCommands); err != nil {
+			return err
+		}
+	}
 	artifacts, err := executor.Execute(ctx, opts)
 	if err != nil {
 		r.emit(NewEvent(EventError, "system", ErrorData{Error: err, Message: "Execution failed"}))
@@ -263,6 +391,9 @@ func (r *Runner) Run(ctx context.Context, opts Options) error {
 	session.Phase = PhaseComplete
 	r.emit(NewEvent(EventSessionComplete, "system", nil))
 	r.printSummary(session)
+	if opts.ShowCosts {
+		fmt.Println(r.usage.Summary())
+	}
 
 	return nil
 }
@@ -308,86 +439,148 @@ Create a plan with:
    - divide_conquer: Split task into parallel subtasks, merge results
    - free_form: Open collaboration
 
-Respond in this format:
-SUMMARY: <brief summary>
-MODE: <work_mode>
-STEPS:
-1. <step description> [ASSIGNED: <ai_id>]
-2. <step description> [ASSIGNED: <ai_id>]
-...`, opts.Task, strings.Join(session.Members, ", "))
+Reply with JSON: a summary, the work mode, and the steps with the team
+member assigned to each.`, opts.Task, strings.Join(session.Members, ", "))
 
-	resp, err := r.registry.Invoke(ctx, session.PM, provider.Request{
-		Prompt:       prompt,
-		SystemPrompt: "You are an expert project manager coordinating a team of AI assistants.",
-	})
-	if err != nil {
-		return nil, "", err
+	names := make([]string, len(session.Attachments))
+	for i, a := range session.Attachments {
+		names[i] = a.Name
+	}
+	if len(names) > 0 {
+		prompt += fmt.Sprintf(`
+
+Attached files: %s
+For each step, list the attached files the assignee needs; only those are
+sent with their task.`, strings.Join(names, ", "))
 	}
 
-	// Parse response
-	plan, mode := r.parsePlanResponse(resp.Content, opts)
+	assignees := append([]string{session.PM}, session.Members...)
+	var reply planReply
+	reply.team = assignees
+	reply.attachments = names
+
+	_, err := r.registry.InvokeJSON(ctx, session.PM, provider.Request{
+		Prompt:         prompt,
+		SystemPrompt:   "You are an expert project manager coordinating a team of AI assistants.",
+		ResponseSchema: planSchema(assignees, names),
+		Attachments:    readableAttachments(r.registry, session.PM, session.Attachments),
+	}, &reply)
+	if err != nil {
+		return nil, "", fmt.Errorf("parsing plan: %w", err)
+	}
 
+	plan, mode := reply.toPlan(opts)
 	return plan, mode, nil
 }
 
-func (r *Runner) parsePlanResponse(content string, opts Options) (*Plan, WorkMode) {
-	plan := &Plan{
-		Summary:     "Task execution plan",
-		Steps:       []PlanStep{},
-		Assignments: make(map[string][]string),
+// planReply is the PM's structured work plan.
+type planReply struct {
+	Summary string          `json:"summary"`
+	Mode    WorkMode        `json:"mode"`
+	Steps   []planStepReply `json:"steps"`
+
+	team        []string // Valid assignees, checked by Validate
+	attachments []string // Names of the attached files
+}
+
+type planStepReply struct {
+	Description string   `json:"description"`
+	AssignedTo  string   `json:"assigned_to"`
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// workModes lists the modes a PM may choose.
+var workModes = []WorkMode{ModePairProgramming, ModeConsultation, ModeRoundRobin, ModeDivideConquer, ModeFreeForm}
+
+// planSchema returns the JSON Schema for a plan assigning steps to team.
+// When files are attached, each step may name the ones it needs.
+func planSchema(team, attachments []string) *provider.ResponseSchema {
+	step := map[string]any{
+		"description": map[string]any{"type": "string"},
+		"assigned_to": map[string]any{"type": "string", "enum": team},
+	}
+	if len(attachments) > 0 {
+		step["attachments"] = map[string]any{
+			"type":  "array",
+			"items": map[string]any{"type": "string", "enum": attachments},
+		}
 	}
 
-	mode := ModeFreeForm
-	if opts.Mode != "" {
-		mode = opts.Mode
+	return &provider.ResponseSchema{
+		Name: "plan",
+		Schema: map[string]any{
+			"type":     "object",
+			"required": []string{"summary", "mode", "steps"},
+			"properties": map[string]any{
+				"summary": map[string]any{"type": "string"},
+				"mode":    map[string]any{"type": "string", "enum": workModes},
+				"steps": map[string]any{
+					"type":     "array",
+					"minItems": 1,
+					"items": map[string]any{
+						"type":       "object",
+						"required":   []string{"description", "assigned_to"},
+						"properties": step,
+					},
+				},
+			},
+		},
 	}
+}
 
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "SUMMARY:") {
-			plan.Summary = strings.TrimPrefix(line, "SUMMARY:")
-			plan.Summary = strings.TrimSpace(plan.Summary)
-		} else if strings.HasPrefix(line, "MODE:") {
-			if opts.Mode == "" { // Only use PM's mode if not forced
-				modeStr := strings.TrimPrefix(line, "MODE:")
-				modeStr = strings.TrimSpace(modeStr)
-				switch modeStr {
-				case "pair_programming":
-					mode = ModePairProgramming
-				case "consultation":
-					mode = ModeConsultation
-				case "round_robin":
-					mode = ModeRoundRobin
-				case "divide_conquer":
-					mode = ModeDivideConquer
-				case "free_form":
-					mode = ModeFreeForm
-				}
-			}
-		} else if len(line) > 2 && line[0] >= '1' && line[0] <= '9' && line[1] == '.' {
-			// Parse step
-			step := PlanStep{
-				ID:          fmt.Sprintf("step_%d", len(plan.Steps)+1),
-				Description: line,
-				Status:      "pending",
+// Validate checks the plan names a known mode and assigns every step to a
+// team member.
+func (p *planReply) Validate() error {
+	if strings.TrimSpace(p.Summary) == "" {
+		return fmt.Errorf("plan has no summary")
+	}
+	if !slices.Contains(workModes, p.Mode) {
+		return fmt.Errorf("unknown work mode %q", p.Mode)
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("plan has no steps")
+	}
+	for i, step := range p.Steps {
+		if strings.TrimSpace(step.Description) == "" {
+			return fmt.Errorf("step %d has no description", i+1)
+		}
+		if !slices.Contains(p.team, step.AssignedTo) {
+			return fmt.Errorf("step %d is assigned to %q, who is not on the team (%s)",
+				i+1, step.AssignedTo, strings.Join(p.team, ", "))
+		}
+		for _, name := range step.Attachments {
+			if !slices.Contains(p.attachments, name) {
+				return fmt.Errorf("step %d needs %q, which is not an attached file", i+1, name)
 			}
+		}
+	}
+	return nil
+}
 
-			// Extract assignment if present
-			if idx := strings.Index(line, "[ASSIGNED:"); idx != -1 {
-				end := strings.Index(line[idx:], "]")
-				if end != -1 {
-					assigned := line[idx+10 : idx+end]
-					step.AssignedTo = strings.TrimSpace(assigned)
-					step.Description = strings.TrimSpace(line[:idx])
-				}
-			}
+// toPlan converts the reply into a Plan, keeping a forced mode over the PM's.
+func (p *planReply) toPlan(opts Options) (*Plan, WorkMode) {
+	plan := &Plan{
+		Summary:     p.Summary,
+		Steps:       make([]PlanStep, 0, len(p.Steps)),
+		Assignments: make(map[string][]string),
+	}
 
-			plan.Steps = append(plan.Steps, step)
+	for i, s := range p.Steps {
+		step := PlanStep{
+			ID:          fmt.Sprintf("step_%d", i+1),
+			Description: s.Description,
+			AssignedTo:  s.AssignedTo,
+			Attachments: s.Attachments,
+			Status:      "pending",
 		}
+		plan.Steps = append(plan.Steps, step)
+		plan.Assignments[step.AssignedTo] = append(plan.Assignments[step.AssignedTo], step.ID)
 	}
 
+	mode := p.Mode
+	if opts.Mode != "" {
+		mode = opts.Mode
+	}
 	return plan, mode
 }
 
@@ -436,6 +629,7 @@ func (r *Runner) deliver(ctx context.Context, session *Session, opts Options) er
 	// Save artifacts if project directory is set
 	if session.ProjectDir != "" {
 		for _, artifact := range session.Artifacts {
+			artifact.Content = r.redactor.Redact(artifact.Content)
 			if err := artifact.Save(session.ProjectDir); err != nil {
 				fmt.Printf("Warning: failed to save %s: %v\n", artifact.Name, err)
 			} else {
@@ -446,7 +640,7 @@ func (r *Runner) deliver(ctx context.Context, session *Session, opts Options) er
 
 	// Save session summary
 	summaryPath := filepath.Join(session.ProjectDir, "SESSION_SUMMARY.md")
-	summary := r.generateSessionSummary(session)
+	summary := r.redactor.Redact(r.generateSessionSummary(session))
 	if err := os.WriteFile(summaryPath, []byte(summary), 0640); err != nil {
 		fmt.Printf("Warning: failed to save summary: %v\n", err)
 	} else {
@@ -464,6 +658,9 @@ func (r *Runner) generateSessionSummary(session *Session) string {
 	b.WriteString(fmt.Sprintf("**Project Manager:** %s\n", session.PM))
 	b.WriteString(fmt.Sprintf("**Work Mode:** %s\n", session.Mode))
 	b.WriteString(fmt.Sprintf("**Team:** %s\n\n", strings.Join(session.Members, ", ")))
+	if session.CLIProfile != "" {
+		b.WriteString(fmt.Sprintf("**CLI Permissions:** %s (working directory: %s)\n\n", session.CLIProfile, session.CLIDir))
+	}
 	b.WriteString(fmt.Sprintf("**Started:** %s\n", session.StartTime.Format(time.RFC3339)))
 	b.WriteString(fmt.Sprintf("**Duration:** %s\n\n", time.Since(session.StartTime).Round(time.Second)))
 
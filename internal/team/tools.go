@@ -0,0 +1,168 @@
+package team
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jxmullins/thekanbansociety/internal/provider"
+)
+
+const (
+	maxToolFileBytes   = 64 * 1024        // read_file output cap
+	maxToolListEntries = 500              // list_files output cap
+	toolCommandTimeout = 60 * time.Second // run_command time limit
+)
+
+// workspaceTools builds the toolbox offered to team members. Tools operate
+// inside root and refuse paths that escape it; run_command is only offered
+// when allowCommands is set.
+func workspaceTools(root string, allowCommands bool) *provider.Toolbox {
+	// Work from the real root, so paths listed relative to it stay relative
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+	tb := provider.NewToolbox()
+
+	tb.Add(provider.Tool{
+		Name:        "read_file",
+		Description: "Read a text file from the workspace.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{"type": "string", "description": "Path relative to the workspace root"},
+			},
+			"required": []string{"path"},
+		},
+	}, func(ctx context.Context, call provider.ToolCall) (string, error) {
+		var args struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+		path, err := workspacePath(root, args.Path)
+		if err != nil {
+			return "", err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		if len(data) > maxToolFileBytes {
+			return string(data[:maxToolFileBytes]) + "\n... (truncated)", nil
+		}
+		return string(data), nil
+	})
+
+	tb.Add(provider.Tool{
+		Name:        "list_files",
+		Description: "List files under a workspace directory, recursively.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{"type": "string", "description": "Directory relative to the workspace root (default: root)"},
+			},
+		},
+	}, func(ctx context.Context, call provider.ToolCall) (string, error) {
+		var args struct {
+			Path string `json:"path"`
+		}
+		if call.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+		}
+		dir, err := workspacePath(root, args.Path)
+		if err != nil {
+			return "", err
+		}
+
+		var files []string
+		err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != dir && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if len(files) >= maxToolListEntries {
+				return filepath.SkipAll
+			}
+			rel, _ := filepath.Rel(root, path)
+			files = append(files, rel)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(files) == 0 {
+			return "(no files)", nil
+		}
+		return strings.Join(files, "\n"), nil
+	})
+
+	if allowCommands {
+		tb.Add(provider.Tool{
+			Name:        "run_command",
+			Description: "Run a shell command in the workspace and return its combined output.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"command": map[string]any{"type": "string"},
+				},
+				"required": []string{"command"},
+			},
+		}, func(ctx context.Context, call provider.ToolCall) (string, error) {
+			var args struct {
+				Command string `json:"command"`
+			}
+			if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, toolCommandTimeout)
+			defer cancel()
+
+			cmd := exec.CommandContext(ctx, "sh", "-c", args.Command)
+			cmd.Dir = root
+			out, err := cmd.CombinedOutput()
+			result := string(out)
+			if len(result) > maxToolFileBytes {
+				result = result[:maxToolFileBytes] + "\n... (truncated)"
+			}
+			if err != nil {
+				return fmt.Sprintf("%s\n(exit: %v)", result, err), nil
+			}
+			return result, nil
+		})
+	}
+
+	return tb
+}
+
+// workspacePath resolves rel inside root, rejecting paths outside it. Symlinks
+// are resolved first, so a link inside the workspace cannot lead out of it.
+func workspacePath(root, rel string) (string, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("resolving workspace: %w", err)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(realRoot, filepath.Clean("/"+rel)))
+	if err != nil {
+		return "", err
+	}
+	if path != realRoot && !strings.HasPrefix(path, realRoot+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside the workspace", rel)
+	}
+	return path, nil
+}
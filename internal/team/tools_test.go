@@ -0,0 +1,29 @@
+package team
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspacePathSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret"), []byte("x"), 0600)
+	os.WriteFile(filepath.Join(root, "notes.md"), []byte("x"), 0600)
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	os.Symlink("notes.md", filepath.Join(root, "alias.md"))
+
+	for _, rel := range []string{"escape/secret", "escape", "../" + filepath.Base(outside) + "/secret"} {
+		if _, err := workspacePath(root, rel); err == nil {
+			t.Errorf("workspacePath(%q) allowed a path outside the workspace", rel)
+		}
+	}
+	for _, rel := range []string{"notes.md", "alias.md", ""} {
+		if _, err := workspacePath(root, rel); err != nil {
+			t.Errorf("workspacePath(%q) error = %v", rel, err)
+		}
+	}
+}
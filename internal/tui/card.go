@@ -38,11 +38,13 @@ type KanbanCard struct {
 	Title       string
 	Description string
 	AssignedTo  string   // AI ID or "user"
+	AnsweredBy  string   // Fallback AI ID, if AssignedTo failed
 	Column      Column
 	IsBlocking  bool     // Only relevant for user tasks
 	IsUserTask  bool     // True if assigned to human
 	StreamBuf   strings.Builder
 	FullHistory strings.Builder
+	Reasoning   strings.Builder // Model thinking, kept out of FullHistory
 	Progress    float64  // 0.0-1.0
 	DependsOn   []string
 	BlockedBy   []string // Computed: tasks that block this one
@@ -107,6 +109,9 @@ func (c *KanbanCard) Render(styles Styles, selected bool, width, maxLines int) s
 		assignee = styles.Warning.Render("You")
 	} else {
 		assignee = styles.AINameStyle(c.AssignedTo).Render(c.AssignedTo)
+		if c.AnsweredBy != "" {
+			assignee += styles.Muted.Render(" → ") + styles.AINameStyle(c.AnsweredBy).Render(c.AnsweredBy)
+		}
 	}
 
 	// Status indicator
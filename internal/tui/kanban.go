@@ -42,9 +42,10 @@ type KanbanModel struct {
 	startTime    time.Time
 
 	// Popup
-	showPopup    bool
-	popupCardID  string
-	popupScroll  int
+	showPopup      bool
+	popupCardID    string
+	popupScroll    int
+	popupReasoning bool // Show the card's reasoning section
 
 	// Help
 	showHelp     bool
@@ -272,6 +273,8 @@ func (m KanbanModel) handlePopupKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.popupScroll > 0 {
 			m.popupScroll--
 		}
+	case "t":
+		m.popupReasoning = !m.popupReasoning
 	}
 	return m, nil
 }
@@ -351,11 +354,15 @@ func (m KanbanModel) handleTeamEventCopy(event team.Event) *KanbanModel {
 			if data, ok := event.Data.(team.TaskProgressData); ok {
 				card.StreamBuf.WriteString(data.Content)
 				card.FullHistory.WriteString(data.Content)
+				card.Reasoning.WriteString(data.Reasoning)
 				card.Progress = data.Progress
 				// Only log significant progress updates
 				if len(data.Content) > 50 {
 					m.addDebugLog("response", event.Actor, truncateString(data.Content, 80))
 				}
+				if len(data.Reasoning) > 50 {
+					m.addDebugLog("thinking", event.Actor, truncateString(data.Reasoning, 80))
+				}
 			}
 		}
 		m.activityStatus = fmt.Sprintf("%s responding...", event.Actor)
@@ -400,6 +407,39 @@ func (m KanbanModel) handleTeamEventCopy(event team.Event) *KanbanModel {
 		m.activityStatus = "Session complete!"
 		m.addDebugLog("complete", "system", "Session finished successfully")
 
+	case team.EventModelFallback:
+		if data, ok := event.Data.(team.ModelFallbackData); ok {
+			for _, card := range m.columns[ColumnInProgress] {
+				if card.AssignedTo == data.From || card.AnsweredBy == data.From {
+					card.AnsweredBy = data.To
+				}
+			}
+			m.activityStatus = fmt.Sprintf("%s failed, falling back to %s", data.From, data.To)
+			m.addDebugLog("fallback", data.From, fmt.Sprintf("Falling back to %s: %v", data.To, data.Error))
+		}
+
+	case team.EventRateLimited:
+		if data, ok := event.Data.(team.RateLimitedData); ok {
+			wait := data.Wait.Round(100 * time.Millisecond)
+			m.activityStatus = fmt.Sprintf("%s queued %s by %s limit", event.Actor, wait, data.Limiter)
+			m.addDebugLog("queued", event.Actor, fmt.Sprintf("Waited %s for %s rate limit", wait, data.Limiter))
+		}
+
+	case team.EventProviderCall:
+		if data, ok := event.Data.(team.ProviderCallData); ok {
+			m.addDebugLog("call", event.Actor, data.Message)
+		}
+
+	case team.EventToolCalled:
+		if data, ok := event.Data.(team.ToolCalledData); ok {
+			m.activityStatus = fmt.Sprintf("%s called %s", event.Actor, data.Tool)
+			message := fmt.Sprintf("%s %s", data.Tool, truncateString(data.Arguments, 60))
+			if data.Error != nil {
+				message += fmt.Sprintf(" failed: %v", data.Error)
+			}
+			m.addDebugLog("tool", event.Actor, message)
+		}
+
 	case team.EventError:
 		if data, ok := event.Data.(team.ErrorData); ok {
 			if card, ok := m.cards[data.TaskID]; ok {
@@ -787,6 +827,9 @@ func (m KanbanModel) renderPopup() string {
 
 	// Metadata
 	assignee := fmt.Sprintf("Assigned: %s", card.AssignedTo)
+	if card.AnsweredBy != "" {
+		assignee += fmt.Sprintf(" (answered by %s)", card.AnsweredBy)
+	}
 	status := fmt.Sprintf("Status: %s", card.Column.String())
 	meta := lipgloss.JoinHorizontal(lipgloss.Left, assignee, "    ", status)
 
@@ -810,8 +853,18 @@ func (m KanbanModel) renderPopup() string {
 	}
 	activity := m.styles.Label.Render("Activity Log:") + "\n" + activityContent
 
+	// Reasoning, collapsed unless toggled open
+	var reasoning string
+	if card.Reasoning.Len() > 0 {
+		if m.popupReasoning {
+			reasoning = m.styles.Label.Render("Reasoning:") + "\n" + m.styles.Muted.Render(card.Reasoning.String())
+		} else {
+			reasoning = m.styles.Label.Render("Reasoning:") + " " + m.styles.Muted.Render("(hidden, press t to show)")
+		}
+	}
+
 	// Help bar
-	helpBar := m.styles.HelpBar.Render("[Esc] Close   [j/k] Scroll")
+	helpBar := m.styles.HelpBar.Render("[Esc] Close   [j/k] Scroll   [t] Reasoning")
 
 	dividerWidth := popupWidth - 2
 	if dividerWidth < 10 {
@@ -828,6 +881,7 @@ func (m KanbanModel) renderPopup() string {
 		desc,
 		divider,
 		activity,
+		reasoning,
 	)
 
 	popupStyle := m.styles.PanelFocused.
@@ -922,12 +976,14 @@ func (m KanbanModel) renderDebugLog() string {
 				typeStyle = m.styles.Success
 			case "pm", "decision":
 				typeStyle = m.styles.AINameStyle(entry.Actor)
-			case "task", "cmd":
+			case "task", "cmd", "tool":
 				typeStyle = m.styles.AINameStyle(entry.Actor)
 			case "response":
 				typeStyle = m.styles.Subtitle
 			case "error":
 				typeStyle = m.styles.Error
+			case "fallback", "queued":
+				typeStyle = m.styles.Warning
 			case "complete":
 				typeStyle = m.styles.Success
 			default:
@@ -3,6 +3,7 @@ package tui
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,6 +16,9 @@ import (
 	"github.com/jxmullins/thekanbansociety/internal/provider"
 )
 
+// statusColumn is the index of the Status column in the model table.
+const statusColumn = 5
+
 // ModelManager provides an interactive model management interface.
 type ModelManager struct {
 	config   *config.Config
@@ -28,6 +32,10 @@ type ModelManager struct {
 	testResult string
 	err      error
 
+	ollama  *provider.OllamaProvider // Server for pulling, showing and deleting local models
+	pulling string                   // Model being pulled, if any
+	pullCh  chan tea.Msg             // Progress of the pull
+
 	width  int
 	height int
 	ready  bool
@@ -41,23 +49,13 @@ func NewModelManager(cfg *config.Config, registry *provider.Registry) *ModelMana
 		{Title: "Name", Width: 20},
 		{Title: "Provider", Width: 12},
 		{Title: "Model", Width: 25},
+		{Title: "Capabilities", Width: 34},
 		{Title: "Status", Width: 10},
 	}
 
-	rows := make([]table.Row, 0, len(cfg.Models))
-	for id, model := range cfg.Models {
-		rows = append(rows, table.Row{
-			id,
-			model.DisplayName,
-			model.Provider,
-			model.Model,
-			"Unknown",
-		})
-	}
-
 	t := table.New(
 		table.WithColumns(columns),
-		table.WithRows(rows),
+		table.WithRows(modelRows(cfg, registry)),
 		table.WithFocused(true),
 		table.WithHeight(10),
 	)
@@ -84,9 +82,47 @@ func NewModelManager(cfg *config.Config, registry *provider.Registry) *ModelMana
 		styles:   DefaultStyles(),
 		table:    t,
 		spinner:  sp,
+		ollama:   provider.NewOllamaProvider("", provider.OllamaEndpoint(cfg.Models)),
 	}
 }
 
+// modelRows lists the configured models, then the Ollama models discovered
+// in the registry.
+func modelRows(cfg *config.Config, registry *provider.Registry) []table.Row {
+	rows := make([]table.Row, 0, len(cfg.Models))
+	for id, model := range cfg.Models {
+		rows = append(rows, table.Row{
+			id,
+			model.DisplayName,
+			model.Provider,
+			model.Model,
+			capabilityBadges(registry, id),
+			"Unknown",
+		})
+	}
+
+	ids := registry.ListModels()
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, ok := cfg.Models[id]; ok || !strings.HasPrefix(id, provider.OllamaModelPrefix) {
+			continue
+		}
+		_, model, err := registry.GetForModel(id)
+		if err != nil {
+			continue
+		}
+		rows = append(rows, table.Row{
+			id,
+			model.DisplayName,
+			model.Provider,
+			model.Model,
+			capabilityBadges(registry, id),
+			"Local",
+		})
+	}
+	return rows
+}
+
 // Init initializes the model.
 func (m ModelManager) Init() tea.Cmd {
 	return tea.Batch(
@@ -103,6 +139,29 @@ type TestCompleteMsg struct {
 	Error   error
 }
 
+// PullStartMsg asks for an Ollama model to be pulled.
+type PullStartMsg struct {
+	Model string
+}
+
+// PullProgressMsg reports the progress of an Ollama model pull.
+type PullProgressMsg struct {
+	Progress provider.OllamaPullProgress
+}
+
+// PullCompleteMsg signals an Ollama model pull has finished.
+type PullCompleteMsg struct {
+	Model string
+	Error error
+}
+
+// ModelsRefreshedMsg carries the model table after local models were
+// rediscovered.
+type ModelsRefreshedMsg struct {
+	Rows    []table.Row
+	Message string
+}
+
 // Update handles messages and updates the model.
 func (m ModelManager) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
@@ -141,8 +200,26 @@ func (m ModelManager) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "r":
-			// Refresh/reload config
+			// Rediscover local models
 			return m, m.refreshModels()
+
+		case "p":
+			// Pull a model into Ollama
+			if m.pulling == "" {
+				return m, askPullModel()
+			}
+
+		case "i":
+			// Show details of the selected local model
+			if row := m.table.SelectedRow(); len(row) > 0 && row[2] == "ollama" {
+				return m, m.showModel(row[3])
+			}
+
+		case "d":
+			// Delete the selected local model
+			if row := m.table.SelectedRow(); len(row) > 0 && row[2] == "ollama" {
+				return m, m.deleteModel(row[3])
+			}
 		}
 
 	case tea.WindowSizeMsg:
@@ -168,6 +245,35 @@ func (m ModelManager) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		// Update table status
 		m.updateModelStatus(msg.ModelID, msg.Success)
+
+	case PullStartMsg:
+		m.pulling = msg.Model
+		m.testResult = "Pulling " + msg.Model
+		m.pullCh = make(chan tea.Msg, 16)
+		cmds = append(cmds, m.pullModel(msg.Model, m.pullCh), waitForPull(m.pullCh))
+
+	case PullProgressMsg:
+		p := msg.Progress
+		m.testResult = fmt.Sprintf("Pulling %s: %s", m.pulling, p.Status)
+		if p.Total > 0 {
+			m.testResult += fmt.Sprintf(" %.0f%%", float64(p.Completed)*100/float64(p.Total))
+		}
+		cmds = append(cmds, waitForPull(m.pullCh))
+
+	case PullCompleteMsg:
+		m.pulling = ""
+		if msg.Error != nil {
+			m.testResult = m.styles.Error.Render(fmt.Sprintf("✗ %v", msg.Error))
+		} else {
+			cmds = append(cmds, m.refreshModels())
+		}
+
+	case ModelsRefreshedMsg:
+		m.table.SetRows(msg.Rows)
+		m.testResult = m.styles.Success.Render(msg.Message)
+
+	case ErrorMsg:
+		m.testResult = m.styles.Error.Render(fmt.Sprintf("✗ %v", msg.Err))
 	}
 
 	// Update table
@@ -201,13 +307,17 @@ func (m ModelManager) View() string {
 		b.WriteString(" Testing ")
 		b.WriteString(m.styles.AINameStyle(m.selected).Render(m.selected))
 		b.WriteString("...")
+	} else if m.pulling != "" {
+		b.WriteString(m.spinner.View())
+		b.WriteString(" ")
+		b.WriteString(m.testResult)
 	} else if m.testResult != "" {
 		b.WriteString(m.testResult)
 	}
 	b.WriteString("\n\n")
 
 	// Help
-	help := "↑/↓: Navigate | t/Enter: Test | a: Add | e: Edit | r: Refresh | q: Quit"
+	help := "↑/↓: Navigate | t/Enter: Test | a: Add | e: Edit | p: Pull | i: Info | d: Delete | r: Refresh | q: Quit"
 	b.WriteString(m.styles.HelpBar.Render(help))
 
 	return b.String()
@@ -240,6 +350,16 @@ func (m *ModelManager) testModel(modelID string) tea.Cmd {
 	}
 }
 
+// capabilityBadges lists what a model supports, or "unavailable" when no
+// registered provider serves it.
+func capabilityBadges(registry *provider.Registry, modelID string) string {
+	caps, err := registry.Capabilities(modelID)
+	if err != nil {
+		return "unavailable"
+	}
+	return strings.Join(caps.Badges(), " ")
+}
+
 func (m *ModelManager) updateModelStatus(modelID string, success bool) {
 	rows := m.table.Rows()
 	for i, row := range rows {
@@ -248,7 +368,7 @@ func (m *ModelManager) updateModelStatus(modelID string, success bool) {
 			if !success {
 				status = "✗ Failed"
 			}
-			rows[i][4] = status
+			rows[i][statusColumn] = status
 			break
 		}
 	}
@@ -350,15 +470,135 @@ func (m *ModelManager) editModel(modelID string) tea.Cmd {
 	}
 }
 
+// refreshModels registers models newly pulled into Ollama and rebuilds the
+// table.
 func (m *ModelManager) refreshModels() tea.Cmd {
 	return func() tea.Msg {
-		// In a full implementation, this would reload config from disk
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		added, err := m.registry.RegisterOllamaModels(ctx, m.ollama.GetBaseURL())
+		if err != nil {
+			return ErrorMsg{Err: err}
+		}
+		return ModelsRefreshedMsg{
+			Rows:    modelRows(m.config, m.registry),
+			Message: fmt.Sprintf("✓ Models refreshed (%d new local)", len(added)),
+		}
+	}
+}
+
+// askPullModel prompts for the name of a model to pull.
+func askPullModel() tea.Cmd {
+	return func() tea.Msg {
+		var name string
+		form := huh.NewForm(
+			huh.NewGroup(
+				huh.NewInput().
+					Title("Model Name").
+					Description("An Ollama library model (e.g., 'llama3.2' or 'qwen3:8b')").
+					Value(&name),
+			).Title("Pull Model"),
+		).WithTheme(councilTheme())
+
+		if err := form.Run(); err != nil {
+			return ErrorMsg{Err: err}
+		}
+		if name = strings.TrimSpace(name); name == "" {
+			return nil
+		}
+		return PullStartMsg{Model: name}
+	}
+}
+
+// pullModel downloads a model into Ollama, reporting progress on ch and
+// closing it when done.
+func (m *ModelManager) pullModel(name string, ch chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		defer close(ch)
+		err := m.ollama.PullModel(context.Background(), name, func(p provider.OllamaPullProgress) {
+			select {
+			case ch <- PullProgressMsg{Progress: p}:
+			default: // Drop updates the view has not caught up with
+			}
+		})
+		ch <- PullCompleteMsg{Model: name, Error: err}
+		return nil
+	}
+}
+
+// waitForPull returns a command that waits for the next pull message.
+func waitForPull(ch chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// showModel displays the details of a local model.
+func (m *ModelManager) showModel(name string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		info, err := m.ollama.ShowModel(ctx, name)
+		if err != nil {
+			return ErrorMsg{Err: err}
+		}
+		details := []string{info.Family, info.ParameterSize, info.QuantizationLevel}
+		if info.ContextLength > 0 {
+			details = append(details, fmt.Sprintf("%d context", info.ContextLength))
+		}
+		details = append(details, info.Capabilities...)
 		return TestCompleteMsg{
-			ModelID: "config",
+			ModelID: name,
 			Success: true,
-			Message: "Models refreshed",
+			Message: strings.Join(details, " · "),
+		}
+	}
+}
+
+// deleteModel removes a local model after confirmation.
+func (m *ModelManager) deleteModel(name string) tea.Cmd {
+	return func() tea.Msg {
+		confirmed := false
+		form := huh.NewForm(
+			huh.NewGroup(
+				huh.NewConfirm().
+					Title(fmt.Sprintf("Delete %s from Ollama?", name)).
+					Value(&confirmed),
+			),
+		).WithTheme(councilTheme())
+		if err := form.Run(); err != nil || !confirmed {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := m.ollama.DeleteModel(ctx, name); err != nil {
+			return ErrorMsg{Err: err}
+		}
+		return ModelsRefreshedMsg{
+			Rows:    removeModelRows(m.table.Rows(), name),
+			Message: fmt.Sprintf("✓ Deleted %s", name),
+		}
+	}
+}
+
+// removeModelRows drops the discovered rows served by a deleted model.
+// Configured models stay, since the config still names them.
+func removeModelRows(rows []table.Row, model string) []table.Row {
+	kept := make([]table.Row, 0, len(rows))
+	for _, row := range rows {
+		if strings.HasPrefix(row[0], provider.OllamaModelPrefix) && row[3] == model {
+			continue
 		}
+		kept = append(kept, row)
 	}
+	return kept
 }
 
 // RunModelManager starts the model manager TUI.
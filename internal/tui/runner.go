@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jxmullins/thekanbansociety/internal/config"
@@ -43,7 +44,7 @@ func (r *Runner) Run(opts Options) error {
 
 // orchestrateDebate manages the debate flow and sends updates to the TUI.
 func (r *Runner) orchestrateDebate(opts Options) {
-	ctx := context.Background()
+	ctx := provider.WithSessionID(context.Background(), fmt.Sprintf("debate_%d", time.Now().Unix()))
 
 	// Wait for TUI to be ready
 	// In a real implementation, we'd use a channel
@@ -17,6 +17,13 @@ type TeamTUIOptions struct {
 	PM      string
 	Mode    team.WorkMode
 	Members []string
+
+	Tools         bool   // Give members workspace tools
+	AllowCommands bool   // Also offer run_command
+	Workspace     string // Root for tools
+	CLIProfile    string // Permission profile CLI agents run under, if any
+	CLIDir        string // Working directory CLI agents are pinned to
+	Attachments   []provider.Attachment
 }
 
 // RunTeamTUI runs the team collaboration with TUI.
@@ -37,6 +44,12 @@ func RunTeamTUI(ctx context.Context, opts TeamTUIOptions, cfg *config.Config, re
 		Mode:            opts.Mode,
 		Members:         opts.Members,
 		CheckpointLevel: team.CheckpointNone, // TUI handles checkpoints
+		Tools:           opts.Tools,
+		AllowCommands:   opts.AllowCommands,
+		Workspace:       opts.Workspace,
+		CLIProfile:      opts.CLIProfile,
+		CLIDir:          opts.CLIDir,
+		Attachments:     opts.Attachments,
 	}
 
 	errChan := make(chan error, 1)
@@ -0,0 +1,216 @@
+// Package vector keeps embeddings in memory and finds the ones nearest a
+// query by cosine similarity. It is sized for a session's worth of
+// arguments or a directory of past transcripts, searched exhaustively.
+package vector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Item is an embedded piece of text.
+type Item struct {
+	ID       string            `json:"id"`
+	Text     string            `json:"text,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Vector   []float32         `json:"vector"`
+}
+
+// Match is a search result, scored by cosine similarity from -1 to 1.
+type Match struct {
+	Item
+	Score float32
+}
+
+// Index is an in-memory vector index. Vectors are stored, and returned,
+// normalized to unit length. It is safe for concurrent use.
+type Index struct {
+	mu    sync.RWMutex
+	dims  int // Vector length, fixed by the first item
+	items []Item
+	byID  map[string]int // Position of each item in items
+}
+
+// NewIndex creates an empty index.
+func NewIndex() *Index {
+	return &Index{byID: make(map[string]int)}
+}
+
+// Add stores an item, replacing any with the same ID. All vectors in an
+// index must have the same length.
+func (ix *Index) Add(item Item) error {
+	if item.ID == "" {
+		return fmt.Errorf("item has no ID")
+	}
+	vec, ok := normalize(item.Vector)
+	if !ok {
+		return fmt.Errorf("item %q has a zero vector", item.ID)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.dims == 0 {
+		ix.dims = len(vec)
+	} else if len(vec) != ix.dims {
+		return fmt.Errorf("item %q has %d dimensions, index has %d", item.ID, len(vec), ix.dims)
+	}
+
+	item.Vector = vec
+	if i, ok := ix.byID[item.ID]; ok {
+		ix.items[i] = item
+		return nil
+	}
+	ix.byID[item.ID] = len(ix.items)
+	ix.items = append(ix.items, item)
+	return nil
+}
+
+// Remove deletes the item with id, reporting whether it was present.
+func (ix *Index) Remove(id string) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	i, ok := ix.byID[id]
+	if !ok {
+		return false
+	}
+
+	// Move the last item into the gap
+	last := len(ix.items) - 1
+	ix.items[i] = ix.items[last]
+	ix.byID[ix.items[i].ID] = i
+	ix.items = ix.items[:last]
+	delete(ix.byID, id)
+	return true
+}
+
+// Get returns the item with id.
+func (ix *Index) Get(id string) (Item, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	i, ok := ix.byID[id]
+	if !ok {
+		return Item{}, false
+	}
+	return ix.items[i], true
+}
+
+// Len returns the number of items.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.items)
+}
+
+// Search returns up to k items most similar to query, best first. Items
+// scoring below minScore are left out; pass -1 to keep them all.
+func (ix *Index) Search(query []float32, k int, minScore float32) ([]Match, error) {
+	q, ok := normalize(query)
+	if !ok {
+		return nil, fmt.Errorf("query is a zero vector")
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if len(ix.items) == 0 {
+		return nil, nil
+	}
+	if len(q) != ix.dims {
+		return nil, fmt.Errorf("query has %d dimensions, index has %d", len(q), ix.dims)
+	}
+
+	var matches []Match
+	for _, item := range ix.items {
+		if score := dot(q, item.Vector); score >= minScore {
+			matches = append(matches, Match{Item: item, Score: score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// Save writes the index to path as JSON.
+func (ix *Index) Save(path string) error {
+	ix.mu.RLock()
+	data, err := json.Marshal(ix.items)
+	ix.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encoding index: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("saving index: %w", err)
+	}
+	return nil
+}
+
+// Load reads an index saved with Save.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading index: %w", err)
+	}
+	var items []Item
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("parsing index %s: %w", path, err)
+	}
+
+	ix := NewIndex()
+	for _, item := range items {
+		if err := ix.Add(item); err != nil {
+			return nil, fmt.Errorf("loading index %s: %w", path, err)
+		}
+	}
+	return ix, nil
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 when either is a
+// zero vector or their lengths differ.
+func Cosine(a, b []float32) float32 {
+	na, okA := normalize(a)
+	nb, okB := normalize(b)
+	if !okA || !okB || len(a) != len(b) {
+		return 0
+	}
+	return dot(na, nb)
+}
+
+// normalize returns v scaled to unit length, or false for a zero vector.
+func normalize(v []float32) ([]float32, bool) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return nil, false
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out, true
+}
+
+// dot returns the dot product of two vectors of the same length.
+func dot(a, b []float32) float32 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return float32(sum)
+}
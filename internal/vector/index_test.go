@@ -0,0 +1,58 @@
+package vector
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexSearch(t *testing.T) {
+	ix := NewIndex()
+	for _, item := range []Item{
+		{ID: "monolith", Vector: []float32{1, 0, 0}},
+		{ID: "microservices", Vector: []float32{0, 1, 0}},
+		{ID: "modular-monolith", Vector: []float32{0.9, 0.3, 0}},
+	} {
+		if err := ix.Add(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ix.Add(Item{ID: "short", Vector: []float32{1, 0}}); err == nil {
+		t.Error("expected error for a vector of the wrong length")
+	}
+
+	matches, err := ix.Search([]float32{2, 0, 0}, 2, -1)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(matches) != 2 || matches[0].ID != "monolith" || matches[0].Score < 0.999 || matches[1].ID != "modular-monolith" {
+		t.Errorf("Search() = %+v", matches)
+	}
+	if matches, _ := ix.Search([]float32{1, 0, 0}, 0, 0.5); len(matches) != 2 {
+		t.Errorf("Search() with min score kept %d items, want 2", len(matches))
+	}
+
+	if !ix.Remove("monolith") || ix.Remove("monolith") || ix.Len() != 2 {
+		t.Error("Remove() did not delete the item exactly once")
+	}
+	if _, ok := ix.Get("modular-monolith"); !ok {
+		t.Error("item lost after removing another")
+	}
+}
+
+func TestIndexSaveLoad(t *testing.T) {
+	ix := NewIndex()
+	ix.Add(Item{ID: "a", Text: "tabs", Metadata: map[string]string{"session": "1"}, Vector: []float32{3, 4}})
+	path := filepath.Join(t.TempDir(), "index.json")
+	if err := ix.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	item, ok := loaded.Get("a")
+	if !ok || item.Text != "tabs" || item.Metadata["session"] != "1" || Cosine(item.Vector, []float32{3, 4}) < 0.999 {
+		t.Errorf("loaded item = %+v", item)
+	}
+}